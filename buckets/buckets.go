@@ -0,0 +1,384 @@
+package buckets
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider names as reported in findings
+const (
+	ProviderS3    = "s3"
+	ProviderGCS   = "gcs"
+	ProviderAzure = "azure"
+)
+
+// Exposure states recorded for a checked bucket
+const (
+	StatusPublicListable = "public-listable"
+	StatusPublicReadable = "public-readable"
+	StatusMissing        = "missing"
+	StatusPrivate        = "private"
+	StatusError          = "error"
+)
+
+// Default public endpoints. AzureEndpoint contains an {account} placeholder.
+const (
+	DefaultS3Endpoint    = "https://s3.amazonaws.com"
+	DefaultGCSEndpoint   = "https://storage.googleapis.com"
+	DefaultAzureEndpoint = "https://{account}.blob.core.windows.net"
+)
+
+// Target identifies a bucket or container parsed from a finding
+type Target struct {
+	Provider string
+	Account  string // Azure storage account, empty for other providers
+	Bucket   string
+}
+
+// Result is the outcome of an anonymous exposure check
+type Result struct {
+	Target
+	Status     string
+	HTTPStatus int
+	SampleKey  string
+	Error      string
+	// Endpoint is the URL of an S3 bucket in another region, which the
+	// global endpoint redirects to
+	Endpoint string
+}
+
+// Checker issues anonymous list and read requests against storage providers
+type Checker struct {
+	S3Endpoint    string
+	GCSEndpoint   string
+	AzureEndpoint string
+	Client        *http.Client
+
+	mu    sync.Mutex
+	cache map[Target]Result
+}
+
+var (
+	s3URIPattern        = regexp.MustCompile(`(?i)s3://([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])`)
+	s3JSONPattern       = regexp.MustCompile(`(?i)"bucket":\s*"([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])"`)
+	s3VirtualPattern    = regexp.MustCompile(`(?i)(?:^|//)([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])\.s3[.-](?:[a-z0-9-]+\.)?amazonaws\.com`)
+	s3PathPattern       = regexp.MustCompile(`(?i)(?:^|//)s3[.-](?:[a-z0-9-]+\.)?amazonaws\.com/([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])`)
+	gcsPattern          = regexp.MustCompile(`(?i)storage\.(?:googleapis|cloud\.google)\.com/([a-z0-9][a-z0-9._-]{1,61}[a-z0-9])`)
+	azurePattern        = regexp.MustCompile(`(?i)([a-z0-9]{3,24})\.blob\.core\.windows\.net/([a-z0-9$][a-z0-9-]{2,62})`)
+	s3RegionHostPattern = regexp.MustCompile(`(?i)^s3[.-]`)
+)
+
+// urlPattern finds absolute URLs in the text around a finding
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>()\\]+`)
+
+// NewChecker creates a Checker using the providers' public endpoints
+func NewChecker() *Checker {
+	return &Checker{
+		S3Endpoint:    DefaultS3Endpoint,
+		GCSEndpoint:   DefaultGCSEndpoint,
+		AzureEndpoint: DefaultAzureEndpoint,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		cache:         make(map[Target]Result),
+	}
+}
+
+// ParseTarget extracts the bucket or container named in a CloudStorage
+// finding value. Values that only name an environment variable or an
+// Azure account without a container cannot be checked.
+func ParseTarget(patternType, value string) (Target, bool) {
+	switch patternType {
+	case "AWS S3 Bucket":
+		for _, re := range []*regexp.Regexp{s3URIPattern, s3JSONPattern, s3PathPattern, s3VirtualPattern} {
+			if m := re.FindStringSubmatch(value); m != nil {
+				bucket := strings.ToLower(m[1])
+				if s3RegionHostPattern.MatchString(bucket) {
+					continue
+				}
+				return Target{Provider: ProviderS3, Bucket: bucket}, true
+			}
+		}
+	case "Google Cloud Storage":
+		if m := gcsPattern.FindStringSubmatch(value); m != nil {
+			return Target{Provider: ProviderGCS, Bucket: strings.ToLower(m[1])}, true
+		}
+	case "Azure Blob Storage":
+		if m := azurePattern.FindStringSubmatch(value); m != nil {
+			return Target{Provider: ProviderAzure, Account: strings.ToLower(m[1]), Bucket: strings.ToLower(m[2])}, true
+		}
+	}
+	return Target{}, false
+}
+
+// ObjectKey finds an object URL for target in the text around a finding and
+// returns its key, so readability can be tested on a bucket that refuses
+// listing
+func ObjectKey(target Target, context string) string {
+	for _, raw := range urlPattern.FindAllString(context, -1) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		path := strings.TrimPrefix(u.Path, "/")
+
+		var key string
+		switch target.Provider {
+		case ProviderS3:
+			if strings.HasPrefix(host, target.Bucket+".s3") {
+				key = path
+			} else if s3RegionHostPattern.MatchString(host) && strings.HasPrefix(path, target.Bucket+"/") {
+				key = strings.TrimPrefix(path, target.Bucket+"/")
+			}
+		case ProviderGCS:
+			if strings.HasPrefix(host, "storage.") && strings.HasPrefix(path, target.Bucket+"/") {
+				key = strings.TrimPrefix(path, target.Bucket+"/")
+			}
+		case ProviderAzure:
+			if host == target.Account+".blob.core.windows.net" && strings.HasPrefix(path, target.Bucket+"/") {
+				key = strings.TrimPrefix(path, target.Bucket+"/")
+			}
+		}
+		if key != "" && !strings.HasSuffix(key, "/") {
+			return key
+		}
+	}
+	return ""
+}
+
+// RiskLevel returns the finding risk level implied by an exposure status,
+// or an empty string to keep the category default
+func RiskLevel(status string) string {
+	switch status {
+	case StatusPublicListable:
+		return "Critical"
+	case StatusPublicReadable, StatusMissing:
+		// A referenced bucket that does not exist can be claimed by anyone
+		return "High"
+	case StatusPrivate:
+		return "Low"
+	}
+	return ""
+}
+
+// Check returns the exposure of a target, caching results per bucket so
+// the same bucket referenced by many pages is only requested once
+func (c *Checker) Check(target Target) Result {
+	c.mu.Lock()
+	if result, ok := c.cache[target]; ok {
+		c.mu.Unlock()
+		return result
+	}
+	c.mu.Unlock()
+
+	var result Result
+	if target.Provider == ProviderAzure {
+		result = c.checkAzure(target)
+	} else {
+		result = c.checkS3Compatible(target)
+	}
+
+	c.mu.Lock()
+	c.cache[target] = result
+	c.mu.Unlock()
+	return result
+}
+
+// listBucketResult is the S3 and GCS XML listing response
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+// storageError is the error body returned by S3, GCS and Azure. S3 names
+// the regional endpoint of a bucket outside us-east-1 in Endpoint.
+type storageError struct {
+	Code     string `xml:"Code"`
+	Endpoint string `xml:"Endpoint"`
+}
+
+// checkS3Compatible checks S3 and GCS buckets, which share the XML API
+func (c *Checker) checkS3Compatible(target Target) Result {
+	base := c.S3Endpoint
+	if target.Provider == ProviderGCS {
+		base = c.GCSEndpoint
+	}
+	bucketURL := strings.TrimRight(base, "/") + "/" + url.PathEscape(target.Bucket)
+	result := Result{Target: target}
+
+	status, header, body, err := c.get(bucketURL + "/")
+	if err == nil && status == http.StatusMovedPermanently {
+		// Path-style requests for buckets in other regions are answered
+		// with PermanentRedirect and no Location
+		if endpoint := regionalEndpoint(base, target.Bucket, header, body); endpoint != "" {
+			result.Endpoint = endpoint
+			status, _, body, err = c.get(endpoint + "/")
+		}
+	}
+	if err != nil {
+		result.Status, result.Error = StatusError, err.Error()
+		return result
+	}
+	result.HTTPStatus = status
+
+	switch {
+	case status == http.StatusOK:
+		var listing listBucketResult
+		if err := xml.Unmarshal(body, &listing); err != nil {
+			result.Status, result.Error = StatusError, err.Error()
+			return result
+		}
+		result.Status = StatusPublicListable
+		if len(listing.Contents) > 0 {
+			result.SampleKey = listing.Contents[0].Key
+		}
+	case errorCode(body) == "NoSuchBucket" || status == http.StatusNotFound:
+		result.Status = StatusMissing
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		result.Status = StatusPrivate
+	default:
+		result.Status = StatusError
+		result.Error = fmt.Sprintf("unexpected status %d", status)
+	}
+	return result
+}
+
+// enumerationResults is the Azure container listing response
+type enumerationResults struct {
+	Blobs []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>Blob"`
+}
+
+// checkAzure checks a blob container for anonymous container-level access
+func (c *Checker) checkAzure(target Target) Result {
+	base := strings.Replace(c.AzureEndpoint, "{account}", target.Account, 1)
+	containerURL := strings.TrimRight(base, "/") + "/" + url.PathEscape(target.Bucket)
+	result := Result{Target: target}
+
+	status, _, body, err := c.get(containerURL + "?restype=container&comp=list&maxresults=1")
+	if err != nil {
+		// A storage account that does not resolve does not exist
+		if strings.Contains(err.Error(), "no such host") {
+			result.Status = StatusMissing
+			return result
+		}
+		result.Status, result.Error = StatusError, err.Error()
+		return result
+	}
+	result.HTTPStatus = status
+
+	switch {
+	case status == http.StatusOK:
+		var listing enumerationResults
+		if err := xml.Unmarshal(body, &listing); err != nil {
+			result.Status, result.Error = StatusError, err.Error()
+			return result
+		}
+		result.Status = StatusPublicListable
+		if len(listing.Blobs) > 0 {
+			result.SampleKey = listing.Blobs[0].Name
+		}
+	case errorCode(body) == "ContainerNotFound":
+		result.Status = StatusMissing
+	case status == http.StatusNotFound || status == http.StatusForbidden || status == http.StatusUnauthorized:
+		// Containers with blob-level public access refuse listing with
+		// ResourceNotFound, so they are indistinguishable from private ones
+		result.Status = StatusPrivate
+	default:
+		result.Status = StatusError
+		result.Error = fmt.Sprintf("unexpected status %d", status)
+	}
+	return result
+}
+
+// CheckRead reports whether an object in a checked bucket can be read
+// anonymously, upgrading a private result to public-readable when it can
+func (c *Checker) CheckRead(result Result, key string) Result {
+	if key == "" || result.Status == StatusMissing || result.Status == StatusError {
+		return result
+	}
+
+	var objectURL string
+	switch result.Provider {
+	case ProviderAzure:
+		base := strings.Replace(c.AzureEndpoint, "{account}", result.Account, 1)
+		objectURL = strings.TrimRight(base, "/") + "/" + url.PathEscape(result.Bucket) + "/" + escapeKey(key)
+	case ProviderGCS:
+		objectURL = strings.TrimRight(c.GCSEndpoint, "/") + "/" + url.PathEscape(result.Bucket) + "/" + escapeKey(key)
+	default:
+		bucketURL := strings.TrimRight(c.S3Endpoint, "/") + "/" + url.PathEscape(result.Bucket)
+		if result.Endpoint != "" {
+			bucketURL = result.Endpoint
+		}
+		objectURL = bucketURL + "/" + escapeKey(key)
+	}
+
+	status, _, _, err := c.get(objectURL)
+	if err == nil && status == http.StatusOK {
+		result.SampleKey = key
+		if result.Status == StatusPrivate {
+			result.Status = StatusPublicReadable
+		}
+	}
+	return result
+}
+
+// get performs an anonymous GET and returns at most 1 MB of the body
+func (c *Checker) get(rawURL string) (int, http.Header, []byte, error) {
+	resp, err := c.Client.Get(rawURL)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, resp.Header, nil, err
+	}
+	return resp.StatusCode, resp.Header, body, nil
+}
+
+// regionalEndpoint returns the bucket URL named by an S3 PermanentRedirect:
+// the virtual-hosted Endpoint of the error body, else the path-style URL of
+// the x-amz-bucket-region header. It keeps the scheme of base.
+func regionalEndpoint(base, bucket string, header http.Header, body []byte) string {
+	scheme := "https"
+	if u, err := url.Parse(base); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	var e storageError
+	if xml.Unmarshal(body, &e) == nil && e.Endpoint != "" {
+		return scheme + "://" + strings.TrimRight(e.Endpoint, "/")
+	}
+	if region := header.Get("X-Amz-Bucket-Region"); region != "" {
+		return scheme + "://s3." + region + ".amazonaws.com/" + url.PathEscape(bucket)
+	}
+	return ""
+}
+
+// errorCode extracts the Code element from a storage error response
+func errorCode(body []byte) string {
+	var e storageError
+	if err := xml.Unmarshal(body, &e); err != nil {
+		return ""
+	}
+	return e.Code
+}
+
+// escapeKey escapes each segment of an object key, preserving slashes
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
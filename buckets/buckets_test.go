@@ -0,0 +1,156 @@
+package buckets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		patternType string
+		value       string
+		want        Target
+		ok          bool
+	}{
+		{"AWS S3 Bucket", "https://my-assets.s3.amazonaws.com", Target{Provider: ProviderS3, Bucket: "my-assets"}, true},
+		{"AWS S3 Bucket", "my-assets.s3.us-west-2.amazonaws.com/img", Target{Provider: ProviderS3, Bucket: "my-assets"}, true},
+		{"AWS S3 Bucket", "https://s3.amazonaws.com/Media-Files/", Target{Provider: ProviderS3, Bucket: "media-files"}, true},
+		{"AWS S3 Bucket", "s3-eu-west-1.amazonaws.com/backups", Target{Provider: ProviderS3, Bucket: "backups"}, true},
+		{"AWS S3 Bucket", "s3://data-lake", Target{Provider: ProviderS3, Bucket: "data-lake"}, true},
+		{"AWS S3 Bucket", `"bucket": "uploads"`, Target{Provider: ProviderS3, Bucket: "uploads"}, true},
+		{"AWS S3 Bucket", "S3_BUCKET", Target{}, false},
+		{"AWS S3 Bucket", "s3.amazonaws.com", Target{}, false},
+		{"Google Cloud Storage", "https://storage.googleapis.com/public-data", Target{Provider: ProviderGCS, Bucket: "public-data"}, true},
+		{"Google Cloud Storage", `"type": "service_account"`, Target{}, false},
+		{"Azure Blob Storage", "https://acct.blob.core.windows.net/media", Target{Provider: ProviderAzure, Account: "acct", Bucket: "media"}, true},
+		{"Azure Blob Storage", "acct.blob.core.windows.net", Target{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseTarget(tt.patternType, tt.value)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseTarget(%q, %q) = %+v, %v; want %+v, %v", tt.patternType, tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	line := `<img src="https://my-assets.s3.amazonaws.com/img/logo.png"><a href="https://other.s3.amazonaws.com/x.js">`
+	if got := ObjectKey(Target{Provider: ProviderS3, Bucket: "my-assets"}, line); got != "img/logo.png" {
+		t.Errorf("Expected img/logo.png, got %q", got)
+	}
+	line = `<script src="https://storage.googleapis.com/public-data/app.js"></script>`
+	if got := ObjectKey(Target{Provider: ProviderGCS, Bucket: "public-data"}, line); got != "app.js" {
+		t.Errorf("Expected app.js, got %q", got)
+	}
+}
+
+// newStandIn serves S3/GCS and Azure style responses for a fixed set of buckets
+func newStandIn() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/open/":
+			w.Write([]byte(`<?xml version="1.0"?><ListBucketResult><Name>open</Name><Contents><Key>index.html</Key></Contents></ListBucketResult>`))
+		case r.URL.Path == "/locked/":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code></Error>`))
+		case r.URL.Path == "/locked/public.js":
+			w.Write([]byte(`console.log("hi")`))
+		case r.URL.Path == "/moved/":
+			// S3 names the regional endpoint of a bucket outside us-east-1
+			w.WriteHeader(http.StatusMovedPermanently)
+			w.Write([]byte(`<Error><Code>PermanentRedirect</Code><Bucket>moved</Bucket><Endpoint>` + r.Host + `/eu-west-1/moved</Endpoint></Error>`))
+		case r.URL.Path == "/eu-west-1/moved/":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code></Error>`))
+		case r.URL.Path == "/eu-west-1/moved/public.js":
+			w.Write([]byte(`console.log("hi")`))
+		case r.URL.Path == "/acct/media" && r.URL.Query().Get("comp") == "list":
+			w.Write([]byte(`<?xml version="1.0"?><EnumerationResults><Blobs><Blob><Name>photo.jpg</Name></Blob></Blobs></EnumerationResults>`))
+		case r.URL.Path == "/acct/gone":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>ContainerNotFound</Code></Error>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchBucket</Code></Error>`))
+		}
+	}))
+}
+
+func TestCheck(t *testing.T) {
+	server := newStandIn()
+	defer server.Close()
+
+	c := NewChecker()
+	c.S3Endpoint = server.URL
+	c.GCSEndpoint = server.URL
+	c.AzureEndpoint = server.URL + "/{account}"
+
+	tests := []struct {
+		target Target
+		status string
+		key    string
+	}{
+		{Target{Provider: ProviderS3, Bucket: "open"}, StatusPublicListable, "index.html"},
+		{Target{Provider: ProviderS3, Bucket: "locked"}, StatusPrivate, ""},
+		{Target{Provider: ProviderS3, Bucket: "moved"}, StatusPrivate, ""},
+		{Target{Provider: ProviderGCS, Bucket: "nothing-here"}, StatusMissing, ""},
+		{Target{Provider: ProviderAzure, Account: "acct", Bucket: "media"}, StatusPublicListable, "photo.jpg"},
+		{Target{Provider: ProviderAzure, Account: "acct", Bucket: "gone"}, StatusMissing, ""},
+	}
+	for _, tt := range tests {
+		got := c.Check(tt.target)
+		if got.Status != tt.status || got.SampleKey != tt.key {
+			t.Errorf("Check(%+v) = %s (key %q), want %s (key %q)", tt.target, got.Status, got.SampleKey, tt.status, tt.key)
+		}
+	}
+
+	locked := c.Check(Target{Provider: ProviderS3, Bucket: "locked"})
+	if got := c.CheckRead(locked, "public.js"); got.Status != StatusPublicReadable {
+		t.Errorf("Expected readable object to upgrade status to %s, got %s", StatusPublicReadable, got.Status)
+	}
+	if got := c.CheckRead(locked, "secret.txt"); got.Status != StatusPrivate {
+		t.Errorf("Expected unreadable object to keep status %s, got %s", StatusPrivate, got.Status)
+	}
+	moved := c.Check(Target{Provider: ProviderS3, Bucket: "moved"})
+	if got := c.CheckRead(moved, "public.js"); got.Status != StatusPublicReadable || got.Endpoint != server.URL+"/eu-west-1/moved" {
+		t.Errorf("Expected the object to be read from the regional endpoint, got %+v", got)
+	}
+}
+
+func TestCheckCachesResults(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	c := NewChecker()
+	c.S3Endpoint = server.URL
+	for i := 0; i < 3; i++ {
+		c.Check(Target{Provider: ProviderS3, Bucket: "cached"})
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request for repeated checks, got %d", requests)
+	}
+	if !strings.HasPrefix(RiskLevel(StatusPublicListable), "Crit") {
+		t.Errorf("Expected Critical risk for listable buckets, got %s", RiskLevel(StatusPublicListable))
+	}
+}
+
+func TestRegionalEndpoint(t *testing.T) {
+	body := []byte(`<Error><Code>PermanentRedirect</Code><Endpoint>assets.s3.eu-west-1.amazonaws.com</Endpoint></Error>`)
+	if got := regionalEndpoint("https://s3.amazonaws.com", "assets", nil, body); got != "https://assets.s3.eu-west-1.amazonaws.com" {
+		t.Errorf("Expected the endpoint of the body, got %q", got)
+	}
+	header := http.Header{"X-Amz-Bucket-Region": {"ap-south-1"}}
+	if got := regionalEndpoint("https://s3.amazonaws.com", "assets", header, nil); got != "https://s3.ap-south-1.amazonaws.com/assets" {
+		t.Errorf("Expected the endpoint of the region header, got %q", got)
+	}
+	if got := regionalEndpoint("https://s3.amazonaws.com", "assets", http.Header{}, nil); got != "" {
+		t.Errorf("Expected no endpoint without a body or header, got %q", got)
+	}
+}
@@ -0,0 +1,350 @@
+package compliance
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gregcmartin/spectre/models"
+	"golang.org/x/net/html"
+)
+
+// Tracker statuses relative to the page's consent management platform
+const (
+	// StatusNoCMP marks a tracker on a page without any CMP
+	StatusNoCMP = "no-cmp"
+	// StatusBeforeCMP marks an ungated tracker placed before the CMP in the
+	// page source, so it is likely to load before the consent banner shows
+	StatusBeforeCMP = "before-cmp"
+	// StatusUngated marks a tracker loaded after the CMP without the
+	// markup the CMP uses to hold it until consent is given
+	StatusUngated = "ungated"
+	// StatusGated marks a tracker held back until consent is given
+	StatusGated = "gated"
+	// StatusFired marks a tracker the rendered page sent a request to
+	// while its consent banner was not accepted
+	StatusFired = "fired-before-consent"
+)
+
+// PatternTypes maps tracker statuses to the pattern types of the
+// ConsentCompliance findings reported for them
+var PatternTypes = map[string]string{
+	StatusNoCMP:     "Tracker Without CMP",
+	StatusBeforeCMP: "Tracker Before CMP",
+	StatusUngated:   "Ungated Tracker",
+	StatusFired:     "Tracker Before Consent",
+}
+
+// TrackerCategories are the categories that require consent before firing
+var TrackerCategories = map[string]bool{
+	"TrackingPixel":    true,
+	"AdNetwork":        true,
+	"SessionRecording": true,
+	"Tracking":         true,
+}
+
+// TrackerResult is the consent status of one tracker finding
+type TrackerResult struct {
+	Category    string
+	PatternType string
+	Location    string
+	Line        int
+	Status      string
+	Request     string // first request a rendered page sent to the tracker
+}
+
+// Report is the consent posture of a single page
+type Report struct {
+	URL      string
+	CMPs     []string
+	CMPLine  int
+	Trackers []TrackerResult
+}
+
+// Violations returns trackers that are not held back until consent
+func (r Report) Violations() []TrackerResult {
+	var violations []TrackerResult
+	for _, t := range r.Trackers {
+		if t.Status != StatusGated {
+			violations = append(violations, t)
+		}
+	}
+	return violations
+}
+
+// lineRange is an inclusive span of source lines
+type lineRange struct {
+	start, end int
+}
+
+var (
+	lineAnchor = regexp.MustCompile(`#L(\d+)$`)
+	// localLine ends the path:line locations of local files
+	localLine = regexp.MustCompile(`:(\d+)$`)
+)
+
+// javaScriptTypes are script types browsers execute
+var javaScriptTypes = map[string]bool{
+	"":                       true,
+	"text/javascript":        true,
+	"application/javascript": true,
+	"module":                 true,
+	"text/ecmascript":        true,
+	"application/ecmascript": true,
+}
+
+// Analyze correlates a page's CMP and tracker findings. Trackers are
+// ordered against the first CMP by source line, and trackers inside
+// elements carrying CMP blocking markup are treated as gated.
+func Analyze(url, content string, findings []models.Finding) Report {
+	var g Gating
+	g.Add(content, 1)
+	return g.Analyze(url, findings)
+}
+
+// Gating collects the elements a CMP holds until consent from a page read
+// in windows, so pages too large to hold whole can still be analyzed
+type Gating struct {
+	ranges []lineRange
+}
+
+// Add records the gated elements of a window that starts on page line
+// firstLine. Elements cut off at the end of a window are not recorded, so
+// windows should overlap.
+func (g *Gating) Add(content string, firstLine int) {
+	for _, r := range gatedRanges(content) {
+		g.ranges = append(g.ranges, lineRange{r.start + firstLine - 1, r.end + firstLine - 1})
+	}
+}
+
+// Analyze correlates a page's CMP and tracker findings against the gated
+// elements added so far, as the package-level Analyze does for a whole page
+func (g *Gating) Analyze(url string, findings []models.Finding) Report {
+	report := Report{URL: url}
+	gated := g.ranges
+
+	seenCMP := make(map[string]bool)
+	for _, f := range findings {
+		if f.Category != "ConsentManagement" {
+			continue
+		}
+		if !seenCMP[f.PatternType] {
+			seenCMP[f.PatternType] = true
+			report.CMPs = append(report.CMPs, f.PatternType)
+		}
+		if line := findingLine(f.Location); line > 0 && (report.CMPLine == 0 || line < report.CMPLine) {
+			report.CMPLine = line
+		}
+	}
+	sort.Strings(report.CMPs)
+
+	for _, f := range findings {
+		if !TrackerCategories[f.Category] {
+			continue
+		}
+		result := TrackerResult{
+			Category:    f.Category,
+			PatternType: f.PatternType,
+			Location:    f.Location,
+			Line:        findingLine(f.Location),
+		}
+		switch {
+		case inRanges(result.Line, gated):
+			result.Status = StatusGated
+		case len(report.CMPs) == 0:
+			result.Status = StatusNoCMP
+		case result.Line > 0 && result.Line < report.CMPLine:
+			result.Status = StatusBeforeCMP
+		default:
+			result.Status = StatusUngated
+		}
+		report.Trackers = append(report.Trackers, result)
+	}
+	return report
+}
+
+// Vendor is a tracker or CMP pattern and the domains it serves from
+type Vendor struct {
+	Category    string
+	PatternType string
+	Domains     []string
+}
+
+// Rendered correlates the requests a rendered page sent before its consent
+// banner was accepted with tracker vendors. The page's CMPs come from its
+// ConsentManagement findings and from requests to CMP vendors. Trackers are
+// only reported on pages with a CMP, as Analyze already reports trackers
+// on pages without one.
+func Rendered(pageURL string, requests []string, vendors []Vendor, findings []models.Finding) Report {
+	report := Report{URL: pageURL}
+	seenCMP := make(map[string]bool)
+	for _, f := range findings {
+		if f.Category == "ConsentManagement" && !seenCMP[f.PatternType] {
+			seenCMP[f.PatternType] = true
+			report.CMPs = append(report.CMPs, f.PatternType)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var fired []TrackerResult
+	for _, request := range requests {
+		u, err := url.Parse(request)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		for _, v := range vendors {
+			key := v.Category + ":" + v.PatternType
+			if seen[key] || !servedFrom(host, v.Domains) {
+				continue
+			}
+			switch {
+			case v.Category == "ConsentManagement":
+				seen[key] = true
+				if !seenCMP[v.PatternType] {
+					seenCMP[v.PatternType] = true
+					report.CMPs = append(report.CMPs, v.PatternType)
+				}
+			case TrackerCategories[v.Category]:
+				seen[key] = true
+				fired = append(fired, TrackerResult{
+					Category:    v.Category,
+					PatternType: v.PatternType,
+					Location:    pageURL,
+					Status:      StatusFired,
+					Request:     request,
+				})
+			}
+		}
+	}
+	sort.Strings(report.CMPs)
+	if len(report.CMPs) > 0 {
+		report.Trackers = fired
+	}
+	return report
+}
+
+// servedFrom reports whether host is one of domains or a subdomain of one
+func servedFrom(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// findingLine extracts the line number from a finding location
+func findingLine(location string) int {
+	m := lineAnchor.FindStringSubmatch(location)
+	if m == nil && !strings.Contains(location, "://") {
+		m = localLine.FindStringSubmatch(location)
+	}
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+func inRanges(line int, ranges []lineRange) bool {
+	for _, r := range ranges {
+		if line >= r.start && line <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// gatedRanges returns the line spans of elements a CMP holds until consent:
+// scripts with a non-executable type, and elements carrying CookieBot,
+// OneTrust, TrustArc or generic consent category attributes
+func gatedRanges(content string) []lineRange {
+	var ranges []lineRange
+	var open []lineRange
+	var openTags []string
+	var openGated []bool
+
+	z := html.NewTokenizer(strings.NewReader(content))
+	line := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		startLine := line
+		line += bytes.Count(raw, []byte("\n"))
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			attrs := make(map[string]string)
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				attrs[string(key)] = string(val)
+			}
+			isGated := isConsentGated(tag, attrs)
+			if tt == html.SelfClosingTagToken || !hasContent(tag) {
+				if isGated {
+					ranges = append(ranges, lineRange{startLine, line})
+				}
+				continue
+			}
+			open = append(open, lineRange{start: startLine})
+			openTags = append(openTags, tag)
+			openGated = append(openGated, isGated)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			for i := len(openTags) - 1; i >= 0; i-- {
+				if openTags[i] != string(name) {
+					continue
+				}
+				if openGated[i] {
+					ranges = append(ranges, lineRange{open[i].start, line})
+				}
+				open, openTags, openGated = open[:i], openTags[:i], openGated[:i]
+				break
+			}
+		}
+	}
+	return ranges
+}
+
+// hasContent reports whether a tag encloses content we need to track.
+// Only elements that load or run third-party resources are considered.
+func hasContent(tag string) bool {
+	switch tag {
+	case "script", "iframe", "noscript":
+		return true
+	}
+	return false
+}
+
+// isConsentGated reports whether an element carries CMP blocking markup
+func isConsentGated(tag string, attrs map[string]string) bool {
+	if tag == "script" && !javaScriptTypes[strings.ToLower(strings.TrimSpace(attrs["type"]))] {
+		return true
+	}
+	for key, val := range attrs {
+		switch key {
+		case "data-cookieconsent":
+			// CookieBot marks its own and essential scripts with "ignore"
+			if !strings.EqualFold(val, "ignore") {
+				return true
+			}
+		case "data-cookiecategory", "data-consent", "data-category", "data-usercentrics", "data-cmp-src":
+			return true
+		case "class":
+			if strings.Contains(val, "optanon-category-") || strings.Contains(val, "trustecm") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package compliance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/models"
+)
+
+const page = `<html>
+<head>
+<script src="https://www.google-analytics.com/analytics.js"></script>
+<script id="Cookiebot" src="https://consent.cookiebot.com/uc.js" data-cookieconsent="ignore"></script>
+<script type="text/plain" data-cookieconsent="marketing">
+  fbq('track', 'PageView');
+</script>
+<script class="optanon-category-C0004">
+  window.hjSiteSettings = {};
+</script>
+<script src="https://cdn.logrocket.com/LogRocket.min.js"></script>
+</head>
+</html>`
+
+func TestAnalyzeWithCMP(t *testing.T) {
+	findings := []models.Finding{
+		{Category: "TrackingPixel", PatternType: "Google Analytics", Location: "https://example.com#L3"},
+		{Category: "ConsentManagement", PatternType: "CookieBot", Location: "https://example.com#L4"},
+		{Category: "TrackingPixel", PatternType: "Facebook Pixel", Location: "https://example.com#L6"},
+		{Category: "Tracking", PatternType: "Hotjar", Location: "https://example.com#L9"},
+		{Category: "SessionRecording", PatternType: "LogRocket", Location: "https://example.com#L11"},
+		{Category: "CMS", PatternType: "WordPress", Location: "https://example.com#L1"},
+	}
+
+	report := Analyze("https://example.com", page, findings)
+	if len(report.CMPs) != 1 || report.CMPs[0] != "CookieBot" || report.CMPLine != 4 {
+		t.Fatalf("Unexpected CMP detection: %v at line %d", report.CMPs, report.CMPLine)
+	}
+
+	want := map[string]string{
+		"Google Analytics": StatusBeforeCMP,
+		"Facebook Pixel":   StatusGated,
+		"Hotjar":           StatusGated,
+		"LogRocket":        StatusUngated,
+	}
+	if len(report.Trackers) != len(want) {
+		t.Fatalf("Expected %d trackers, got %d", len(want), len(report.Trackers))
+	}
+	for _, tracker := range report.Trackers {
+		if tracker.Status != want[tracker.PatternType] {
+			t.Errorf("%s: got status %s, want %s", tracker.PatternType, tracker.Status, want[tracker.PatternType])
+		}
+	}
+	if got := len(report.Violations()); got != 2 {
+		t.Errorf("Expected 2 violations, got %d", got)
+	}
+}
+
+func TestAnalyzeWithoutCMP(t *testing.T) {
+	findings := []models.Finding{
+		{Category: "AdNetwork", PatternType: "Google AdSense", Location: "https://example.com#L3"},
+	}
+
+	report := Analyze("https://example.com", `<script src="https://pagead2.googlesyndication.com/x.js"></script>`, findings)
+	if len(report.Trackers) != 1 || report.Trackers[0].Status != StatusNoCMP {
+		t.Errorf("Expected tracker without CMP, got %+v", report.Trackers)
+	}
+}
+
+func TestGatingWindows(t *testing.T) {
+	findings := []models.Finding{
+		{Category: "ConsentManagement", PatternType: "CookieBot", Location: "https://example.com#L4"},
+		{Category: "TrackingPixel", PatternType: "Facebook Pixel", Location: "https://example.com#L6"},
+		{Category: "Tracking", PatternType: "Hotjar", Location: "https://example.com#L9"},
+		{Category: "SessionRecording", PatternType: "LogRocket", Location: "https://example.com#L11"},
+	}
+
+	// Overlapping windows of lines 1-7 and 5-13
+	lines := strings.SplitAfter(page, "\n")
+	var g Gating
+	g.Add(strings.Join(lines[:7], ""), 1)
+	g.Add(strings.Join(lines[4:], ""), 5)
+
+	report := g.Analyze("https://example.com", findings)
+	want := map[string]string{
+		"Facebook Pixel": StatusGated,
+		"Hotjar":         StatusGated,
+		"LogRocket":      StatusUngated,
+	}
+	for _, tracker := range report.Trackers {
+		if tracker.Status != want[tracker.PatternType] {
+			t.Errorf("%s: got status %s, want %s", tracker.PatternType, tracker.Status, want[tracker.PatternType])
+		}
+	}
+}
+
+func TestRendered(t *testing.T) {
+	vendors := []Vendor{
+		{Category: "ConsentManagement", PatternType: "OneTrust", Domains: []string{"cdn.cookielaw.org"}},
+		{Category: "TrackingPixel", PatternType: "Google Analytics", Domains: []string{"google-analytics.com", "googletagmanager.com"}},
+		{Category: "SessionRecording", PatternType: "Hotjar", Domains: []string{"static.hotjar.com"}},
+		{Category: "AdNetwork", PatternType: "Criteo", Domains: []string{"criteo.net"}},
+	}
+	requests := []string{
+		"https://www.example.com/",
+		"https://cdn.cookielaw.org/scripttemplates/otSDKStub.js",
+		"https://www.googletagmanager.com/gtag/js?id=G-1",
+		"https://www.google-analytics.com/g/collect?v=2",
+		"https://static.hotjar.com/c/hotjar-1.js",
+		"https://notcriteo.net/x.js",
+	}
+
+	report := Rendered("https://www.example.com/", requests, vendors, nil)
+	if len(report.CMPs) != 1 || report.CMPs[0] != "OneTrust" {
+		t.Fatalf("Expected the CMP from its request, got %v", report.CMPs)
+	}
+	want := map[string]string{
+		"Google Analytics": "https://www.googletagmanager.com/gtag/js?id=G-1",
+		"Hotjar":           "https://static.hotjar.com/c/hotjar-1.js",
+	}
+	if len(report.Trackers) != len(want) {
+		t.Fatalf("Expected %d fired trackers, got %+v", len(want), report.Trackers)
+	}
+	for _, tracker := range report.Trackers {
+		if tracker.Status != StatusFired || tracker.Request != want[tracker.PatternType] {
+			t.Errorf("%s: got %s from %s", tracker.PatternType, tracker.Status, tracker.Request)
+		}
+	}
+
+	// Without a CMP there is no banner to fire before
+	report = Rendered("https://www.example.com/", requests[2:], vendors, nil)
+	if len(report.Trackers) != 0 {
+		t.Errorf("Expected no fired trackers without a CMP, got %+v", report.Trackers)
+	}
+	findings := []models.Finding{{Category: "ConsentManagement", PatternType: "CookieBot", Location: "https://www.example.com/#L4"}}
+	report = Rendered("https://www.example.com/", requests[2:], vendors, findings)
+	if len(report.CMPs) != 1 || len(report.Trackers) != 2 {
+		t.Errorf("Expected the CMP from findings and 2 fired trackers, got %v and %+v", report.CMPs, report.Trackers)
+	}
+}
@@ -0,0 +1,265 @@
+package cookies
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gregcmartin/spectre/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Entry maps a known cookie name to the vendor and category used for the
+// same vendor in the pattern definitions
+type Entry struct {
+	Name     string
+	Prefix   bool // match any cookie whose name starts with Name
+	Vendor   string
+	Category string
+}
+
+// Known cookies, grouped by category in the same order as patterns.go
+var Known = []Entry{
+	// CMS
+	{Name: "wordpress_logged_in_", Prefix: true, Vendor: "WordPress", Category: "CMS"},
+	{Name: "wordpress_sec_", Prefix: true, Vendor: "WordPress", Category: "CMS"},
+	{Name: "wordpress_test_cookie", Vendor: "WordPress", Category: "CMS"},
+	{Name: "wp-settings-", Prefix: true, Vendor: "WordPress", Category: "CMS"},
+	{Name: "SSESS", Prefix: true, Vendor: "Drupal", Category: "CMS"},
+	{Name: "Drupal.visitor.", Prefix: true, Vendor: "Drupal", Category: "CMS"},
+	{Name: "joomla_user_state", Vendor: "Joomla", Category: "CMS"},
+	{Name: "ghost-admin-api-session", Vendor: "Ghost", Category: "CMS"},
+	{Name: "_shopify_y", Vendor: "Shopify", Category: "CMS"},
+	{Name: "_shopify_s", Vendor: "Shopify", Category: "CMS"},
+	{Name: "_shopify_sa_t", Vendor: "Shopify", Category: "CMS"},
+	{Name: "secure_customer_sig", Vendor: "Shopify", Category: "CMS"},
+	{Name: "mage-cache-storage", Vendor: "Magento", Category: "CMS"},
+	{Name: "mage-cache-sessid", Vendor: "Magento", Category: "CMS"},
+	{Name: "mage-messages", Vendor: "Magento", Category: "CMS"},
+	{Name: "svSession", Vendor: "Wix", Category: "CMS"},
+	{Name: "ss_cvr", Vendor: "Squarespace", Category: "CMS"},
+	{Name: "ss_cvt", Vendor: "Squarespace", Category: "CMS"},
+
+	// TrackingPixel
+	{Name: "_fbp", Vendor: "Facebook Pixel", Category: "TrackingPixel"},
+	{Name: "_fbc", Vendor: "Facebook Pixel", Category: "TrackingPixel"},
+	{Name: "fr", Vendor: "Facebook Pixel", Category: "TrackingPixel"},
+	{Name: "_ga", Vendor: "Google Analytics", Category: "TrackingPixel"},
+	{Name: "_ga_", Prefix: true, Vendor: "Google Analytics", Category: "TrackingPixel"},
+	{Name: "_gid", Vendor: "Google Analytics", Category: "TrackingPixel"},
+	{Name: "_gat", Prefix: true, Vendor: "Google Analytics", Category: "TrackingPixel"},
+	{Name: "__utm", Prefix: true, Vendor: "Google Analytics", Category: "TrackingPixel"},
+	{Name: "li_sugr", Vendor: "LinkedIn Insight", Category: "TrackingPixel"},
+	{Name: "li_fat_id", Vendor: "LinkedIn Insight", Category: "TrackingPixel"},
+	{Name: "bcookie", Vendor: "LinkedIn Insight", Category: "TrackingPixel"},
+	{Name: "lidc", Vendor: "LinkedIn Insight", Category: "TrackingPixel"},
+	{Name: "UserMatchHistory", Vendor: "LinkedIn Insight", Category: "TrackingPixel"},
+	{Name: "AnalyticsSyncHistory", Vendor: "LinkedIn Insight", Category: "TrackingPixel"},
+	{Name: "personalization_id", Vendor: "Twitter Pixel", Category: "TrackingPixel"},
+	{Name: "muc_ads", Vendor: "Twitter Pixel", Category: "TrackingPixel"},
+	{Name: "_pinterest_sess", Vendor: "Pinterest Tag", Category: "TrackingPixel"},
+	{Name: "_pin_unauth", Vendor: "Pinterest Tag", Category: "TrackingPixel"},
+	{Name: "_pinterest_ct_ua", Vendor: "Pinterest Tag", Category: "TrackingPixel"},
+	{Name: "_ttp", Vendor: "TikTok Pixel", Category: "TrackingPixel"},
+	{Name: "_tt_enable_cookie", Vendor: "TikTok Pixel", Category: "TrackingPixel"},
+
+	// AdNetwork
+	{Name: "__gads", Vendor: "Google AdSense", Category: "AdNetwork"},
+	{Name: "__gpi", Vendor: "Google AdSense", Category: "AdNetwork"},
+	{Name: "IDE", Vendor: "Google AdSense", Category: "AdNetwork"},
+	{Name: "DSID", Vendor: "Google AdSense", Category: "AdNetwork"},
+	{Name: "test_cookie", Vendor: "Google AdSense", Category: "AdNetwork"},
+	{Name: "ad-id", Vendor: "Amazon Ads", Category: "AdNetwork"},
+	{Name: "ad-privacy", Vendor: "Amazon Ads", Category: "AdNetwork"},
+	{Name: "t_gid", Vendor: "Taboola", Category: "AdNetwork"},
+	{Name: "taboola_", Prefix: true, Vendor: "Taboola", Category: "AdNetwork"},
+	{Name: "obuid", Vendor: "Outbrain", Category: "AdNetwork"},
+	{Name: "cto_bundle", Vendor: "Criteo", Category: "AdNetwork"},
+	{Name: "cto_bidid", Vendor: "Criteo", Category: "AdNetwork"},
+
+	// AIChat
+	{Name: "intercom-id-", Prefix: true, Vendor: "Intercom", Category: "AIChat"},
+	{Name: "intercom-session-", Prefix: true, Vendor: "Intercom", Category: "AIChat"},
+	{Name: "intercom-device-id-", Prefix: true, Vendor: "Intercom", Category: "AIChat"},
+	{Name: "driftt_aid", Vendor: "Drift", Category: "AIChat"},
+	{Name: "drift_aid", Vendor: "Drift", Category: "AIChat"},
+	{Name: "drift_campaign_refresh", Vendor: "Drift", Category: "AIChat"},
+	{Name: "__zlcmid", Vendor: "Zendesk", Category: "AIChat"},
+	{Name: "crisp-client", Prefix: true, Vendor: "Crisp", Category: "AIChat"},
+	{Name: "__lc_cid", Vendor: "LiveChat", Category: "AIChat"},
+	{Name: "__lc_cst", Vendor: "LiveChat", Category: "AIChat"},
+	{Name: "__lc2_cid", Vendor: "LiveChat", Category: "AIChat"},
+	{Name: "tidio_state_", Prefix: true, Vendor: "Tidio", Category: "AIChat"},
+
+	// Tracking
+	{Name: "_hjid", Vendor: "Hotjar", Category: "Tracking"},
+	{Name: "_hj", Prefix: true, Vendor: "Hotjar", Category: "Tracking"},
+	{Name: "mf_", Prefix: true, Vendor: "Mouseflow", Category: "Tracking"},
+	{Name: "fs_uid", Vendor: "FullStory", Category: "Tracking"},
+	{Name: "fs_lua", Vendor: "FullStory", Category: "Tracking"},
+	{Name: "_lo_uid", Vendor: "Lucky Orange", Category: "Tracking"},
+	{Name: "_lo_v", Vendor: "Lucky Orange", Category: "Tracking"},
+	{Name: "_lorid", Vendor: "Lucky Orange", Category: "Tracking"},
+	{Name: "_hp2_", Prefix: true, Vendor: "Heap Analytics", Category: "Tracking"},
+	{Name: "mp_", Prefix: true, Vendor: "Mixpanel", Category: "Tracking"},
+
+	// ConsentManagement
+	{Name: "OptanonConsent", Vendor: "OneTrust", Category: "ConsentManagement"},
+	{Name: "OptanonAlertBoxClosed", Vendor: "OneTrust", Category: "ConsentManagement"},
+	{Name: "eupubconsent-v2", Vendor: "OneTrust", Category: "ConsentManagement"},
+	{Name: "CookieConsent", Vendor: "CookieBot", Category: "ConsentManagement"},
+	{Name: "notice_behavior", Vendor: "TrustArc", Category: "ConsentManagement"},
+	{Name: "notice_gdpr_prefs", Vendor: "TrustArc", Category: "ConsentManagement"},
+	{Name: "notice_preferences", Vendor: "TrustArc", Category: "ConsentManagement"},
+	{Name: "cmapi_cookie_privacy", Vendor: "TrustArc", Category: "ConsentManagement"},
+	{Name: "TAconsentID", Vendor: "TrustArc", Category: "ConsentManagement"},
+
+	// SessionRecording
+	{Name: "_lr_", Prefix: true, Vendor: "LogRocket", Category: "SessionRecording"},
+	{Name: "SL_C_", Prefix: true, Vendor: "Smartlook", Category: "SessionRecording"},
+	{Name: "_clck", Vendor: "Clarity", Category: "SessionRecording"},
+	{Name: "_clsk", Vendor: "Clarity", Category: "SessionRecording"},
+	{Name: "CLID", Vendor: "Clarity", Category: "SessionRecording"},
+
+	// ErrorTracking
+	{Name: "rollbar_", Prefix: true, Vendor: "Rollbar", Category: "ErrorTracking"},
+	{Name: "bugsnag-anonymous-id", Vendor: "BugSnag", Category: "ErrorTracking"},
+
+	// ABTesting
+	{Name: "optimizelyEndUserId", Vendor: "Optimizely", Category: "ABTesting"},
+	{Name: "optimizelySession", Vendor: "Optimizely", Category: "ABTesting"},
+	{Name: "_vwo_", Prefix: true, Vendor: "VWO", Category: "ABTesting"},
+	{Name: "_vis_opt_", Prefix: true, Vendor: "VWO", Category: "ABTesting"},
+	{Name: "_gaexp", Vendor: "Google Optimize", Category: "ABTesting"},
+}
+
+var (
+	scriptCookiePattern = regexp.MustCompile("document\\.cookie\\s*=\\s*[\"'`]\\s*([A-Za-z0-9_.!#$%&*+^|~-]+)=")
+	storageItemPattern  = regexp.MustCompile(`\b(localStorage|sessionStorage)\.setItem\(\s*["'` + "`" + `]([^"'` + "`" + `]{1,128})["'` + "`" + `]`)
+	sameSiteNames       = map[http.SameSite]string{http.SameSiteLaxMode: "Lax", http.SameSiteStrictMode: "Strict", http.SameSiteNoneMode: "None"}
+)
+
+// Lookup returns the known entry for a cookie name. Exact names take
+// precedence over prefixes, and longer prefixes over shorter ones.
+func Lookup(name string) (Entry, bool) {
+	var best Entry
+	found := false
+	for _, e := range Known {
+		if !e.Prefix {
+			if e.Name == name {
+				return e, true
+			}
+			continue
+		}
+		if strings.HasPrefix(name, e.Name) && (!found || len(e.Name) > len(best.Name)) {
+			best = e
+			found = true
+		}
+	}
+	return best, found
+}
+
+// RegistrableDomain returns the eTLD+1 for a host, or the host itself when
+// it has none (IP addresses, localhost)
+func RegistrableDomain(host string) string {
+	host = strings.TrimPrefix(strings.ToLower(host), ".")
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// FromResponse parses the Set-Cookie headers of a response
+func FromResponse(resp *http.Response) []models.Cookie {
+	host := ""
+	if resp.Request != nil && resp.Request.URL != nil {
+		host = resp.Request.URL.Hostname()
+	}
+
+	var result []models.Cookie
+	for _, c := range resp.Cookies() {
+		cookie := models.Cookie{
+			Name:     c.Name,
+			Domain:   strings.TrimPrefix(c.Domain, "."),
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: sameSiteNames[c.SameSite],
+			Size:     len(c.Name) + len(c.Value),
+			Source:   "header",
+		}
+		if cookie.Domain == "" {
+			cookie.Domain = host
+		}
+		switch {
+		case c.MaxAge > 0:
+			cookie.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second).UTC().Format(time.RFC3339)
+		case !c.Expires.IsZero():
+			cookie.Expires = c.Expires.UTC().Format(time.RFC3339)
+		}
+		result = append(result, cookie)
+	}
+	return result
+}
+
+// FromScript finds cookies written through document.cookie and keys written
+// to localStorage or sessionStorage by inline scripts
+func FromScript(content, host string) []models.Cookie {
+	var result []models.Cookie
+	for _, m := range scriptCookiePattern.FindAllStringSubmatch(content, -1) {
+		result = append(result, models.Cookie{
+			Name:   m[1],
+			Domain: host,
+			Size:   len(m[1]),
+			Source: "script",
+		})
+	}
+	for _, m := range storageItemPattern.FindAllStringSubmatch(content, -1) {
+		result = append(result, models.Cookie{
+			Name:   m[2],
+			Domain: host,
+			Size:   len(m[2]),
+			Source: m[1],
+		})
+	}
+	return result
+}
+
+// Classify attributes a cookie to a known vendor and marks it third-party
+// when its domain is outside the scanned site's registrable domain
+func Classify(cookie *models.Cookie, siteHost string) {
+	if entry, ok := Lookup(cookie.Name); ok {
+		cookie.Vendor = entry.Vendor
+		cookie.Category = entry.Category
+	}
+	if cookie.Domain != "" && siteHost != "" {
+		cookie.ThirdParty = RegistrableDomain(cookie.Domain) != RegistrableDomain(siteHost)
+	}
+}
+
+// Unclassified is the pattern type of cookies of no known vendor
+const Unclassified = "Unclassified Cookie"
+
+// PatternType returns the finding pattern type for a classified cookie
+func PatternType(cookie models.Cookie) string {
+	if cookie.Vendor == "" {
+		return Unclassified
+	}
+	return cookie.Vendor
+}
+
+// PatternTypes returns the pattern types of cookie findings: each known
+// vendor, then Unclassified
+func PatternTypes() []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, entry := range Known {
+		if !seen[entry.Vendor] {
+			seen[entry.Vendor] = true
+			types = append(types, entry.Vendor)
+		}
+	}
+	return append(types, Unclassified)
+}
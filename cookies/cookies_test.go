@@ -0,0 +1,108 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gregcmartin/spectre/models"
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		vendor string
+		found  bool
+	}{
+		{"_ga", "Google Analytics", true},
+		{"_ga_ABC123XYZ", "Google Analytics", true},
+		{"_gat_gtag_UA_1234_1", "Google Analytics", true},
+		{"_fbp", "Facebook Pixel", true},
+		{"_hjid", "Hotjar", true},
+		{"_hjSessionUser_12345", "Hotjar", true},
+		{"OptanonConsent", "OneTrust", true},
+		{"wordpress_logged_in_abc", "WordPress", true},
+		{"session_id", "", false},
+	}
+	for _, tt := range tests {
+		entry, found := Lookup(tt.name)
+		if found != tt.found || entry.Vendor != tt.vendor {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.name, entry.Vendor, found, tt.vendor, tt.found)
+		}
+	}
+}
+
+func TestKnownVendorsMatchPatterns(t *testing.T) {
+	vendors := make(map[string]bool)
+	for _, pt := range patterns.AllPatternTypes {
+		vendors[pt.Category+":"+pt.Name] = true
+	}
+	for _, e := range Known {
+		if !vendors[e.Category+":"+e.Vendor] {
+			t.Errorf("Cookie %s maps to %s (%s), which has no pattern definition", e.Name, e.Vendor, e.Category)
+		}
+	}
+}
+
+func TestFromResponseAndClassify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "_ga=GA1.2.123.456; Domain=.example.com; Path=/; Max-Age=3600; Secure; SameSite=Lax")
+		w.Header().Add("Set-Cookie", "IDE=abc; Domain=doubleclick.net; Path=/; HttpOnly; Secure; SameSite=None")
+		w.Header().Add("Set-Cookie", "sid=xyz")
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	found := FromResponse(resp)
+	if len(found) != 3 {
+		t.Fatalf("Expected 3 cookies, got %d", len(found))
+	}
+
+	ga := found[0]
+	if ga.Name != "_ga" || ga.Domain != "example.com" || ga.Path != "/" || !ga.Secure || ga.SameSite != "Lax" || ga.Expires == "" {
+		t.Errorf("Unexpected _ga cookie: %+v", ga)
+	}
+	if ga.Size != len("_ga")+len("GA1.2.123.456") {
+		t.Errorf("Expected size %d, got %d", len("_ga")+len("GA1.2.123.456"), ga.Size)
+	}
+	if sid := found[2]; sid.Domain != "127.0.0.1" || sid.Expires != "" {
+		t.Errorf("Expected host-only session cookie, got %+v", sid)
+	}
+
+	for i := range found {
+		Classify(&found[i], "www.example.com")
+	}
+	if found[0].Vendor != "Google Analytics" || found[0].ThirdParty {
+		t.Errorf("Expected first-party Google Analytics cookie, got %+v", found[0])
+	}
+	if found[1].Vendor != "Google AdSense" || !found[1].ThirdParty {
+		t.Errorf("Expected third-party Google AdSense cookie, got %+v", found[1])
+	}
+}
+
+func TestFromScript(t *testing.T) {
+	content := `document.cookie = "_fbp=fb.1.123; path=/";
+		localStorage.setItem('_hjid', id);
+		sessionStorage.setItem("cart", "[]");`
+
+	found := FromScript(content, "shop.example.com")
+	want := []models.Cookie{
+		{Name: "_fbp", Source: "script"},
+		{Name: "_hjid", Source: "localStorage"},
+		{Name: "cart", Source: "sessionStorage"},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(found))
+	}
+	for i, w := range want {
+		if found[i].Name != w.Name || found[i].Source != w.Source || found[i].Domain != "shop.example.com" {
+			t.Errorf("Entry %d: got %+v, want %s from %s", i, found[i], w.Name, w.Source)
+		}
+	}
+}
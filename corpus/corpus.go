@@ -0,0 +1,221 @@
+// Package corpus measures pattern accuracy against a directory of sample
+// documents. Each sample has a sidecar file listing the findings expected
+// in it; any other pattern that matches the sample is a false positive.
+package corpus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+// ExpectedExt is the extension of the sidecar file next to each sample,
+// e.g. wordpress.html.expected for wordpress.html
+const ExpectedExt = ".expected"
+
+// Fixture is a sample document and the patterns expected to match it
+type Fixture struct {
+	Name     string
+	Content  string
+	Expected map[string]bool // keyed by Key
+}
+
+// Key names a pattern as it appears in sidecar files, e.g.
+// "Tracking/Hotjar"
+func Key(category, name string) string {
+	return category + "/" + name
+}
+
+// Load reads every sample in dir with its sidecar. Every sample needs a
+// sidecar, which may list nothing for a sample no pattern should match.
+func Load(dir string) ([]Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	samples := make(map[string]bool)
+	var fixtures []Fixture
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ExpectedExt) {
+			continue
+		}
+		samples[name] = true
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		expected, err := readExpected(filepath.Join(dir, name+ExpectedExt))
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, Fixture{Name: name, Content: string(content), Expected: expected})
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ExpectedExt) && !samples[strings.TrimSuffix(name, ExpectedExt)] {
+			return nil, fmt.Errorf("%s: no sample for sidecar", name)
+		}
+	}
+	return fixtures, nil
+}
+
+// readExpected parses a sidecar: one Category/Pattern Name per line, with
+// blank lines and lines starting with # ignored
+func readExpected(filename string) (map[string]bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	expected := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.Contains(line, "/") {
+			return nil, fmt.Errorf("%s:%d: want Category/Pattern Name, got %q", filename, n, line)
+		}
+		expected[line] = true
+	}
+	return expected, scanner.Err()
+}
+
+// Result counts the fixtures one pattern matched or missed
+type Result struct {
+	Category string
+	Name     string
+	TP       int // expected and matched
+	FP       int // matched but not expected
+	FN       int // expected but not matched
+}
+
+// Precision is the share of matched fixtures that expected the pattern,
+// or 1 when it matched none
+func (r Result) Precision() float64 {
+	if r.TP+r.FP == 0 {
+		return 1
+	}
+	return float64(r.TP) / float64(r.TP+r.FP)
+}
+
+// Recall is the share of fixtures expecting the pattern that it matched,
+// or 1 when none expect it
+func (r Result) Recall() float64 {
+	if r.TP+r.FN == 0 {
+		return 1
+	}
+	return float64(r.TP) / float64(r.TP+r.FN)
+}
+
+// Miss is a false positive or false negative in one fixture
+type Miss struct {
+	Fixture  string
+	Pattern  string // Key of the pattern
+	Expected bool   // true for a false negative
+}
+
+// Report is the outcome of evaluating patterns against a corpus
+type Report struct {
+	Results []Result
+	Misses  []Miss
+	// Unknown lists sidecar entries naming no evaluated pattern, as
+	// "fixture: Category/Pattern Name"
+	Unknown []string
+}
+
+// Evaluate matches each pattern against each fixture the way a scan
+// matches response bodies, including the pattern's controls. Patterns that
+// only match non-body targets are skipped.
+func Evaluate(fixtures []Fixture, pts []patterns.PatternType) (*Report, error) {
+	report := &Report{}
+	known := make(map[string]bool)
+	for _, pt := range pts {
+		if pt.Pattern == "" {
+			continue
+		}
+		key := Key(pt.Category, pt.Name)
+		known[key] = true
+		result := Result{Category: pt.Category, Name: pt.Name}
+		for _, f := range fixtures {
+			matched, err := Matches(pt, f.Content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			switch {
+			case matched && f.Expected[key]:
+				result.TP++
+			case matched:
+				result.FP++
+				report.Misses = append(report.Misses, Miss{Fixture: f.Name, Pattern: key})
+			case f.Expected[key]:
+				result.FN++
+				report.Misses = append(report.Misses, Miss{Fixture: f.Name, Pattern: key, Expected: true})
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+	for _, f := range fixtures {
+		for key := range f.Expected {
+			if !known[key] {
+				report.Unknown = append(report.Unknown, f.Name+": "+key)
+			}
+		}
+	}
+	sort.Strings(report.Unknown)
+	return report, nil
+}
+
+// Matches reports whether pt has a body match in content that survives
+// its controls
+func Matches(pt patterns.PatternType, content string) (bool, error) {
+	re, err := regexp.Compile(pt.Pattern)
+	if err != nil {
+		return false, err
+	}
+	controls, err := pt.Controls()
+	if err != nil {
+		return false, err
+	}
+	kept, _ := controls.Apply(content, re.FindAllStringIndex(content, -1))
+	return len(kept) > 0, nil
+}
+
+// Uncovered lists the patterns no fixture expects, so a new pattern
+// cannot be added without samples of what it detects
+func (r *Report) Uncovered() []string {
+	var keys []string
+	for _, result := range r.Results {
+		if result.TP+result.FN == 0 {
+			keys = append(keys, Key(result.Category, result.Name))
+		}
+	}
+	return keys
+}
+
+// Write prints per-pattern counts, precision and recall as a table,
+// followed by the misses
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "%-18s %-28s %3s %3s %3s %9s %6s\n", "CATEGORY", "PATTERN", "TP", "FP", "FN", "PRECISION", "RECALL")
+	for _, result := range r.Results {
+		fmt.Fprintf(w, "%-18s %-28s %3d %3d %3d %9.2f %6.2f\n", result.Category, result.Name,
+			result.TP, result.FP, result.FN, result.Precision(), result.Recall())
+	}
+	for _, miss := range r.Misses {
+		kind := "false positive"
+		if miss.Expected {
+			kind = "false negative"
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", miss.Fixture, kind, miss.Pattern)
+	}
+}
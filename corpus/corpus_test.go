@@ -0,0 +1,113 @@
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+// TestCorpus runs every pattern over testdata. Run it with -v to see
+// per-pattern precision and recall.
+func TestCorpus(t *testing.T) {
+	fixtures, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Evaluate(fixtures, patterns.AllPatternTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var table strings.Builder
+	report.Write(&table)
+	t.Log("\n" + table.String())
+
+	for _, miss := range report.Misses {
+		if miss.Expected {
+			t.Errorf("%s: %s did not match", miss.Fixture, miss.Pattern)
+		} else {
+			t.Errorf("%s: %s matched but is not in %s%s", miss.Fixture, miss.Pattern, miss.Fixture, ExpectedExt)
+		}
+	}
+	for _, entry := range report.Unknown {
+		t.Errorf("unknown pattern in sidecar: %s", entry)
+	}
+	for _, key := range report.Uncovered() {
+		t.Errorf("%s has no fixture in testdata", key)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	pts := []patterns.PatternType{
+		{Category: "Tracking", Name: "Hotjar", Pattern: `static\.hotjar\.com|hj\.`, Exclude: `[a-z0-9_$]hj\.`},
+		{Category: "Tracking", Name: "Mixpanel", Pattern: `mixpanel`},
+		{Category: "CMS", Name: "Header Only", Targets: []patterns.Target{{Type: patterns.TargetHeader, Key: "X-Test"}}},
+	}
+	fixtures := []Fixture{
+		{Name: "a", Content: `src="https://static.hotjar.com/c/hotjar-1.js"`, Expected: map[string]bool{"Tracking/Hotjar": true}},
+		{Name: "b", Content: `var nhj.x = mixpanel`, Expected: map[string]bool{"Tracking/Hotjar": true, "Tracking/Typo": true}},
+		{Name: "c", Content: `hj.q = []`, Expected: map[string]bool{}},
+	}
+	report, err := Evaluate(fixtures, pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(report.Results))
+	}
+	hotjar := report.Results[0]
+	if hotjar.TP != 1 || hotjar.FP != 1 || hotjar.FN != 1 || hotjar.Precision() != 0.5 || hotjar.Recall() != 0.5 {
+		t.Errorf("Hotjar: got %+v", hotjar)
+	}
+	mixpanel := report.Results[1]
+	if mixpanel.FP != 1 || mixpanel.Precision() != 0 || mixpanel.Recall() != 1 {
+		t.Errorf("Mixpanel: got %+v", mixpanel)
+	}
+	if len(report.Misses) != 3 {
+		t.Errorf("got misses %+v, want 3", report.Misses)
+	}
+	if strings.Join(report.Unknown, ",") != "b: Tracking/Typo" {
+		t.Errorf("got unknown %v", report.Unknown)
+	}
+	if strings.Join(report.Uncovered(), ",") != "Tracking/Mixpanel" {
+		t.Errorf("got uncovered %v", report.Uncovered())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("page.html", "<html></html>")
+	write("page.html.expected", "# a comment\n\nCMS/WordPress\n")
+
+	fixtures, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 1 || !fixtures[0].Expected["CMS/WordPress"] || len(fixtures[0].Expected) != 1 {
+		t.Errorf("got %+v", fixtures)
+	}
+
+	write("orphan.js.expected", "")
+	if _, err := Load(dir); err == nil {
+		t.Error("sidecar without a sample: got no error")
+	}
+	os.Remove(filepath.Join(dir, "orphan.js.expected"))
+
+	write("bare.js", "")
+	if _, err := Load(dir); err == nil {
+		t.Error("sample without a sidecar: got no error")
+	}
+	write("bare.js.expected", "WordPress\n")
+	if _, err := Load(dir); err == nil {
+		t.Error("entry without a category: got no error")
+	}
+}
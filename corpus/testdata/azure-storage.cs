@@ -0,0 +1,2 @@
+// appsettings for the thumbnail worker
+var connection = "DefaultEndpointsProtocol=https;AccountName=contosothumbs;AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;EndpointSuffix=core.windows.net";
@@ -0,0 +1,4 @@
+(function () {
+  var frame = document.createElement('iframe'); frame.src = 'https://sync.adpartner.example/cookie-sync?pid=42'; frame.style.display = 'none';
+  document.body.appendChild(frame);
+})();
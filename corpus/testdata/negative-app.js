@@ -0,0 +1,13 @@
+"use strict";
+(function () {
+  var nhj = { x: 1 };
+  var $hj = { ready: false };
+  var image = new Image();
+  image.src = "/static/img/logo.svg";
+  var damage = { total: 0 };
+  function render(state) {
+    var el = document.getElementById("app");
+    el.textContent = state.title + " " + nhj.x + " " + damage.total;
+  }
+  fetch("/data/config.json").then(function (r) { return r.json(); }).then(render);
+})();
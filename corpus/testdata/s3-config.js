@@ -0,0 +1,9 @@
+// Build-time configuration injected by the deploy pipeline
+window.__APP_CONFIG__ = {
+  apiBase: "https://api.photoapp.example",
+  uploads: {
+    endpoint: "https://photoapp-user-uploads.s3.us-east-1.amazonaws.com/",
+    maxSizeMb: 25
+  },
+  release: "2024.03.1"
+};
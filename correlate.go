@@ -0,0 +1,108 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gregcmartin/spectre/correlate"
+)
+
+// runCorrelate implements the `spectre correlate` subcommand
+func runCorrelate(args []string) int {
+	fs := flag.NewFlagSet("correlate", flag.ExitOnError)
+	output := fs.String("o", "", "write cluster JSON to file instead of stdout")
+	dotFile := fs.String("dot", "", "write Graphviz graph to file")
+	gexfFile := fs.String("gexf", "", "write GEXF graph to file")
+	minSize := fs.Int("min", 2, "minimum number of sites per cluster")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: spectre correlate [options] <results.json|results.json.zip>...\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	c := correlate.New()
+	for _, path := range fs.Args() {
+		if err := readResultFile(path, c.ReadResults); err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error reading %s: %v\n", path, err)
+			return 1
+		}
+	}
+	clusters := c.Clusters(*minSize)
+
+	if err := writeCorrelateOutput(*output, clusters, correlate.WriteJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error writing clusters: %v\n", err)
+		return 1
+	}
+	if *dotFile != "" {
+		if err := writeCorrelateOutput(*dotFile, clusters, correlate.WriteDOT); err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error writing DOT graph: %v\n", err)
+			return 1
+		}
+	}
+	if *gexfFile != "" {
+		if err := writeCorrelateOutput(*gexfFile, clusters, correlate.WriteGEXF); err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error writing GEXF graph: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// readResultFile passes a result file, or every JSON file inside a zip
+// archive, to read
+func readResultFile(path string, read func(io.Reader) error) error {
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return read(file)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, entry := range archive.File {
+		name := entry.Name
+		if strings.HasPrefix(name, "__MACOSX/") || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = read(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// writeCorrelateOutput writes clusters to path, or stdout when path is empty
+func writeCorrelateOutput(path string, clusters []correlate.Cluster, write func(io.Writer, []correlate.Cluster) error) error {
+	if path == "" {
+		return write(os.Stdout, clusters)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return write(file, clusters)
+}
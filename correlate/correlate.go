@@ -0,0 +1,246 @@
+package correlate
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+// Identifier is a tracker account ID seen on one or more sites
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Link connects a site to an identifier it embeds
+type Link struct {
+	Site string     `json:"site"`
+	ID   Identifier `json:"id"`
+}
+
+// Cluster is a group of sites connected through shared identifiers
+type Cluster struct {
+	Size      int          `json:"size"`
+	Sites     []string     `json:"sites"`
+	SharedIDs []Identifier `json:"shared_ids"`
+	Links     []Link       `json:"links"`
+}
+
+// extractor pulls one identifier type out of finding values
+type extractor struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Correlator collects identifiers per site and groups sites that share them
+type Correlator struct {
+	extractors []extractor
+	siteIDs    map[string]map[Identifier]bool
+}
+
+// resultEntry is the subset of a Spectre finding needed for correlation
+type resultEntry struct {
+	URL      string `json:"url"`
+	Value    string `json:"value"`
+	Location string `json:"location"`
+}
+
+// New creates a Correlator using the TrackerID patterns
+func New() *Correlator {
+	c := &Correlator{
+		siteIDs: make(map[string]map[Identifier]bool),
+	}
+	for _, pt := range patterns.AllPatternTypes {
+		if pt.Category != "TrackerID" {
+			continue
+		}
+		re, err := regexp.Compile(pt.Pattern)
+		if err != nil {
+			continue
+		}
+		c.extractors = append(c.extractors, extractor{name: pt.Name, pattern: re})
+	}
+	return c
+}
+
+// SiteFromLocation strips the line anchor from a finding location, or the
+// line from the path:line location of a local file
+func SiteFromLocation(location string) string {
+	if idx := strings.Index(location, "#"); idx != -1 {
+		return location[:idx]
+	}
+	if !strings.Contains(location, "://") {
+		if idx := strings.LastIndex(location, ":"); idx != -1 {
+			if _, err := strconv.Atoi(location[idx+1:]); err == nil {
+				return location[:idx]
+			}
+		}
+	}
+	return location
+}
+
+// AddFinding records every identifier found in value for the given site
+func (c *Correlator) AddFinding(site, value string) {
+	if site == "" || value == "" {
+		return
+	}
+	for _, ex := range c.extractors {
+		for _, m := range ex.pattern.FindAllStringSubmatch(value, -1) {
+			id := m[0]
+			for _, group := range m[1:] {
+				if group != "" {
+					id = group
+					break
+				}
+			}
+			if c.siteIDs[site] == nil {
+				c.siteIDs[site] = make(map[Identifier]bool)
+			}
+			c.siteIDs[site][Identifier{Type: ex.name, Value: id}] = true
+		}
+	}
+}
+
+// ReadResults reads a Spectre result file, either a stream of JSON findings
+// as written by -o or a single JSON array of findings
+func (c *Correlator) ReadResults(r io.Reader) error {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(br)
+	if first == '[' {
+		var entries []resultEntry
+		if err := decoder.Decode(&entries); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			c.addEntry(e)
+		}
+		return nil
+	}
+
+	for {
+		var e resultEntry
+		err := decoder.Decode(&e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		c.addEntry(e)
+	}
+}
+
+func (c *Correlator) addEntry(e resultEntry) {
+	site := e.URL
+	if site == "" {
+		site = SiteFromLocation(e.Location)
+	}
+	c.AddFinding(site, e.Value)
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// Clusters groups sites connected by shared identifiers. Only clusters with
+// at least minSize sites are returned, largest first.
+func (c *Correlator) Clusters(minSize int) []Cluster {
+	// Union sites through each identifier they have in common
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(s string) string {
+		if parent[s] != s {
+			parent[s] = find(parent[s])
+		}
+		return parent[s]
+	}
+
+	idSites := make(map[Identifier][]string)
+	for site, ids := range c.siteIDs {
+		parent[site] = site
+		for id := range ids {
+			idSites[id] = append(idSites[id], site)
+		}
+	}
+	for _, sites := range idSites {
+		for _, site := range sites[1:] {
+			a, b := find(sites[0]), find(site)
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	for site := range c.siteIDs {
+		root := find(site)
+		groups[root] = append(groups[root], site)
+	}
+
+	var clusters []Cluster
+	for root, sites := range groups {
+		if len(sites) < minSize {
+			continue
+		}
+		sort.Strings(sites)
+
+		cluster := Cluster{Size: len(sites), Sites: sites}
+		for id, idSiteList := range idSites {
+			if len(idSiteList) < 2 || find(idSiteList[0]) != root {
+				continue
+			}
+			cluster.SharedIDs = append(cluster.SharedIDs, id)
+			for _, site := range idSiteList {
+				cluster.Links = append(cluster.Links, Link{Site: site, ID: id})
+			}
+		}
+		sortIdentifiers(cluster.SharedIDs)
+		sort.Slice(cluster.Links, func(i, j int) bool {
+			if cluster.Links[i].Site != cluster.Links[j].Site {
+				return cluster.Links[i].Site < cluster.Links[j].Site
+			}
+			return cluster.Links[i].ID.Value < cluster.Links[j].ID.Value
+		})
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Size != clusters[j].Size {
+			return clusters[i].Size > clusters[j].Size
+		}
+		return clusters[i].Sites[0] < clusters[j].Sites[0]
+	})
+	return clusters
+}
+
+func sortIdentifiers(ids []Identifier) {
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Type != ids[j].Type {
+			return ids[i].Type < ids[j].Type
+		}
+		return ids[i].Value < ids[j].Value
+	})
+}
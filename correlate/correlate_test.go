@@ -0,0 +1,139 @@
+package correlate
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestClustersFromResultStream(t *testing.T) {
+	results := `{
+  "category": "HiddenIframe",
+  "pattern_type": "Zero Size Iframe",
+  "value": "<iframe src=\"https://www.googletagmanager.com/ns.html?id=GTM-ABC123\" height=\"0\" width=\"0\">",
+  "location": "https://site1.com#L10"
+}
+{
+  "category": "TrackerID",
+  "pattern_type": "Google Tag Manager ID",
+  "value": "GTM-ABC123",
+  "location": "https://site2.com#L4"
+}
+{
+  "category": "TrackerID",
+  "pattern_type": "AdSense Publisher ID",
+  "value": "ca-pub-1234567890123456",
+  "location": "https://site2.com#L8"
+}
+{
+  "category": "AdNetwork",
+  "pattern_type": "Google AdSense",
+  "value": "google_ad_client = \"ca-pub-1234567890123456\"",
+  "location": "https://site3.com#L2"
+}
+{
+  "category": "TrackerID",
+  "pattern_type": "Google Analytics ID",
+  "value": "UA-99999-1",
+  "location": "https://lonely.com#L1"
+}
+{
+  "url": "https://site4.com/",
+  "tags": {"asset_id": "GTM-ABC123"},
+  "first_party_cookies": 0,
+  "third_party_cookies": 0
+}
+`
+	c := New()
+	if err := c.ReadResults(strings.NewReader(results)); err != nil {
+		t.Fatal(err)
+	}
+
+	clusters := c.Clusters(2)
+	if len(clusters) != 1 {
+		t.Fatalf("Expected 1 cluster, got %d", len(clusters))
+	}
+	cluster := clusters[0]
+	if cluster.Size != 3 {
+		t.Errorf("Expected cluster size 3, got %d", cluster.Size)
+	}
+	if strings.Join(cluster.Sites, ",") != "https://site1.com,https://site2.com,https://site3.com" {
+		t.Errorf("Unexpected cluster sites: %v", cluster.Sites)
+	}
+	if len(cluster.SharedIDs) != 2 {
+		t.Fatalf("Expected 2 shared IDs, got %v", cluster.SharedIDs)
+	}
+	if cluster.SharedIDs[0].Value != "ca-pub-1234567890123456" || cluster.SharedIDs[1].Value != "GTM-ABC123" {
+		t.Errorf("Unexpected shared IDs: %v", cluster.SharedIDs)
+	}
+	if len(cluster.Links) != 4 {
+		t.Errorf("Expected 4 links, got %d", len(cluster.Links))
+	}
+
+	if got := c.Clusters(1); len(got) != 2 {
+		t.Errorf("Expected singleton cluster with min size 1, got %d clusters", len(got))
+	}
+}
+
+func TestReadResultsArray(t *testing.T) {
+	c := New()
+	results := `[{"value": "fbq('init', '123456789012345');", "location": "https://a.com#L1"},
+		{"url": "https://b.com", "value": "fbq(\"init\", \"123456789012345\")", "location": "https://b.com/page#L9"}]`
+	if err := c.ReadResults(strings.NewReader(results)); err != nil {
+		t.Fatal(err)
+	}
+
+	clusters := c.Clusters(2)
+	if len(clusters) != 1 || clusters[0].SharedIDs[0].Value != "123456789012345" {
+		t.Fatalf("Expected Facebook pixel cluster, got %+v", clusters)
+	}
+	if clusters[0].SharedIDs[0].Type != "Facebook Pixel ID" {
+		t.Errorf("Expected Facebook Pixel ID type, got %s", clusters[0].SharedIDs[0].Type)
+	}
+}
+
+func TestGraphOutputs(t *testing.T) {
+	c := New()
+	c.AddFinding("https://a.com", "GTM-XYZ999")
+	c.AddFinding("https://b.com", "GTM-XYZ999")
+	clusters := c.Clusters(2)
+
+	var dot bytes.Buffer
+	if err := WriteDOT(&dot, clusters); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dot.String(), `"https://a.com" -- "id:GTM-XYZ999";`) {
+		t.Errorf("DOT output missing edge:\n%s", dot.String())
+	}
+
+	var gexf bytes.Buffer
+	if err := WriteGEXF(&gexf, clusters); err != nil {
+		t.Fatal(err)
+	}
+	var doc gexfDocument
+	if err := xml.Unmarshal(gexf.Bytes(), &doc); err != nil {
+		t.Fatalf("GEXF output is not valid XML: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Errorf("Expected 3 nodes and 2 edges, got %d and %d", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+}
+
+func TestSiteFromLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{"https://site1.com#L10", "https://site1.com"},
+		{"https://site1.com:8443", "https://site1.com:8443"},
+		{"dist/app.js:12", "dist/app.js"},
+		{"bundle.zip!/static/app.js:3", "bundle.zip!/static/app.js"},
+		{"C:/site/app.js", "C:/site/app.js"},
+	}
+	for _, tt := range tests {
+		if got := SiteFromLocation(tt.location); got != tt.want {
+			t.Errorf("SiteFromLocation(%q) = %q, want %q", tt.location, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package correlate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteJSON writes clusters as an indented JSON array
+func WriteJSON(w io.Writer, clusters []Cluster) error {
+	if clusters == nil {
+		clusters = []Cluster{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(clusters)
+}
+
+// WriteDOT writes clusters as a Graphviz graph with site and identifier nodes
+func WriteDOT(w io.Writer, clusters []Cluster) error {
+	if _, err := fmt.Fprintln(w, "graph spectre {"); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "  node [fontname=\"Helvetica\"];")
+	for i, cluster := range clusters {
+		fmt.Fprintf(w, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(w, "    label=%s;\n", strconv.Quote(fmt.Sprintf("cluster %d (%d sites)", i+1, cluster.Size)))
+		for _, site := range cluster.Sites {
+			fmt.Fprintf(w, "    %s [shape=box];\n", strconv.Quote(site))
+		}
+		for _, id := range cluster.SharedIDs {
+			fmt.Fprintf(w, "    %s [shape=ellipse, label=%s];\n", strconv.Quote(idNode(id)), strconv.Quote(id.Value))
+		}
+		fmt.Fprintln(w, "  }")
+		for _, link := range cluster.Links {
+			fmt.Fprintf(w, "  %s -- %s;\n", strconv.Quote(link.Site), strconv.Quote(idNode(link.ID)))
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// gexfDocument mirrors the subset of the GEXF 1.3 schema used for output
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	Mode       string         `xml:"mode,attr"`
+	EdgeType   string         `xml:"defaultedgetype,attr"`
+	Attributes gexfAttributes `xml:"attributes"`
+	Nodes      []gexfNode     `xml:"nodes>node"`
+	Edges      []gexfEdge     `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+// WriteGEXF writes clusters as a GEXF graph for Gephi and similar tools
+func WriteGEXF(w io.Writer, clusters []Cluster) error {
+	doc := gexfDocument{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Graph: gexfGraph{
+			Mode:     "static",
+			EdgeType: "undirected",
+			Attributes: gexfAttributes{
+				Class: "node",
+				Attributes: []gexfAttribute{
+					{ID: "0", Title: "kind", Type: "string"},
+					{ID: "1", Title: "id_type", Type: "string"},
+					{ID: "2", Title: "cluster", Type: "integer"},
+				},
+			},
+		},
+	}
+
+	for i, cluster := range clusters {
+		clusterNum := strconv.Itoa(i + 1)
+		for _, site := range cluster.Sites {
+			doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+				ID:    site,
+				Label: site,
+				AttValues: []gexfAttValue{
+					{For: "0", Value: "site"},
+					{For: "2", Value: clusterNum},
+				},
+			})
+		}
+		for _, id := range cluster.SharedIDs {
+			doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+				ID:    idNode(id),
+				Label: id.Value,
+				AttValues: []gexfAttValue{
+					{For: "0", Value: "identifier"},
+					{For: "1", Value: id.Type},
+					{For: "2", Value: clusterNum},
+				},
+			})
+		}
+		for _, link := range cluster.Links {
+			doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+				ID:     strconv.Itoa(len(doc.Graph.Edges)),
+				Source: link.Site,
+				Target: idNode(link.ID),
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// idNode returns the graph node ID for an identifier
+func idNode(id Identifier) string {
+	return "id:" + id.Value
+}
@@ -0,0 +1,226 @@
+package decode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gregcmartin/spectre/models"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// AcceptEncoding lists the content encodings Body can undo
+const AcceptEncoding = "gzip, deflate, br, zstd"
+
+// sniffSize is how much of a document is searched for a charset
+// declaration. The HTML standard prescans 1024 bytes, but declarations
+// often follow long comments or scripts.
+const sniffSize = 4096
+
+var (
+	metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([\w.:-]+)`)
+	xmlEncoding = regexp.MustCompile(`^<\?xml[^>]+encoding\s*=\s*["']([\w.:-]+)["']`)
+	gzipMagic   = []byte{0x1f, 0x8b}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	boms        = []struct {
+		bom     []byte
+		charset string
+	}{
+		{[]byte{0xef, 0xbb, 0xbf}, "utf-8"},
+		{[]byte{0xfe, 0xff}, "utf-16be"},
+		{[]byte{0xff, 0xfe}, "utf-16le"},
+	}
+)
+
+// Body returns the text of a response body: its Content-Encoding is
+// undone and its charset, from a byte order mark, the Content-Type header
+// or a meta or XML declaration, transcoded to UTF-8. Bodies compressed
+// with gzip or zstd without saying so, as served for precompressed
+// assets, are detected by their magic bytes. Close releases the
+// decompressors but not body.
+func Body(body io.Reader, contentEncoding, contentType string) (io.ReadCloser, models.ContentInfo, error) {
+	info := models.ContentInfo{ContentType: contentType}
+	r, closers, encodings, err := decompress(body, contentEncoding)
+	if err != nil {
+		closeAll(closers)
+		return nil, info, err
+	}
+	info.Encoding = strings.Join(encodings, ", ")
+
+	text, name, source := Transcode(r, contentType)
+	info.Charset = name
+	info.CharsetSource = source
+	return readCloser{text, closers}, info, nil
+}
+
+// Transcode returns r converted to UTF-8, with the charset it was read
+// as and where that charset came from: bom, header, meta, xml, or
+// default when the document does not say
+func Transcode(r io.Reader, contentType string) (io.Reader, string, string) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, _ := br.Peek(sniffSize)
+	label, source := detect(head, contentType)
+
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		// Unknown labels are scanned as UTF-8, which keeps ASCII intact
+		return br, "utf-8", "default"
+	}
+	if name == "utf-8" {
+		if source == "bom" {
+			br.Discard(3)
+		}
+		return br, name, source
+	}
+	return transform.NewReader(br, enc.NewDecoder()), name, source
+}
+
+// detect finds the declared charset of a document. Undeclared documents
+// are taken as UTF-8 unless their high bytes are not valid UTF-8, in
+// which case windows-1252 is assumed as browsers do.
+func detect(head []byte, contentType string) (string, string) {
+	for _, b := range boms {
+		if bytes.HasPrefix(head, b.bom) {
+			return b.charset, "bom"
+		}
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		if enc, _ := charset.Lookup(params["charset"]); enc != nil {
+			return params["charset"], "header"
+		}
+	}
+	if m := xmlEncoding.FindSubmatch(head); m != nil {
+		return string(m[1]), "xml"
+	}
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		if enc, _ := charset.Lookup(string(m[1])); enc != nil {
+			return string(m[1]), "meta"
+		}
+	}
+	if !validUTF8(head) {
+		return "windows-1252", "default"
+	}
+	return "utf-8", "default"
+}
+
+// validUTF8 reports whether head is UTF-8, allowing a rune cut off at
+// the end
+func validUTF8(head []byte) bool {
+	for i := len(head) - 1; i >= 0 && i > len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				head = head[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(head)
+}
+
+// decompress undoes each content encoding, last applied first. It returns
+// the encodings undone, including ones detected by magic bytes.
+func decompress(body io.Reader, contentEncoding string) (io.Reader, []io.Closer, []string, error) {
+	var encodings []string
+	for _, e := range strings.Split(contentEncoding, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+			encodings = append(encodings, e)
+		}
+	}
+
+	r := body
+	var closers []io.Closer
+	for i := len(encodings) - 1; i >= 0; i-- {
+		next, closer, err := decoder(r, encodings[i])
+		if err != nil {
+			return nil, closers, nil, fmt.Errorf("%s content encoding: %v", encodings[i], err)
+		}
+		r = next
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
+
+	if len(encodings) == 0 {
+		br := bufio.NewReader(r)
+		magic, _ := br.Peek(len(zstdMagic))
+		r = br
+		sniffed := ""
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			sniffed = "gzip"
+		case bytes.HasPrefix(magic, zstdMagic):
+			sniffed = "zstd"
+		}
+		if sniffed != "" {
+			next, closer, err := decoder(br, sniffed)
+			if err == nil {
+				r = next
+				closers = append(closers, closer)
+				encodings = append(encodings, sniffed+" (sniffed)")
+			}
+		}
+	}
+	return r, closers, encodings, nil
+}
+
+func decoder(r io.Reader, encoding string) (io.Reader, io.Closer, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, gz, nil
+	case "deflate":
+		// Deflate is meant to be zlib-wrapped, but some servers send raw
+		// deflate streams
+		br := bufio.NewReader(r)
+		header, _ := br.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, err
+			}
+			return zr, zr, nil
+		}
+		fr := flate.NewReader(br)
+		return fr, fr, nil
+	case "br":
+		return brotli.NewReader(r), nil, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		rc := zr.IOReadCloser()
+		return rc, rc, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported")
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (rc readCloser) Close() error {
+	closeAll(rc.closers)
+	return nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
@@ -0,0 +1,135 @@
+package decode
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const page = "<html><head><title>トラッカー</title></head>\n<script src=\"https://static.hotjar.com/c/hotjar.js\"></script>\n"
+
+func TestBodyEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		encode   func(w io.Writer) io.WriteCloser
+		want     string
+	}{
+		{"identity", "", nil, ""},
+		{"gzip", "gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, "gzip"},
+		{"zlib deflate", "deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }, "deflate"},
+		{"raw deflate", "deflate", func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}, "deflate"},
+		{"brotli", "br", func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }, "br"},
+		{"zstd", "zstd", func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(w)
+			return zw
+		}, "zstd"},
+		{"undeclared gzip", "", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, "gzip (sniffed)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if tt.encode == nil {
+				buf.WriteString(page)
+			} else {
+				w := tt.encode(&buf)
+				w.Write([]byte(page))
+				w.Close()
+			}
+
+			body, info, err := Body(&buf, tt.encoding, "text/html; charset=utf-8")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer body.Close()
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != page {
+				t.Errorf("got body %q, want %q", got, page)
+			}
+			if info.Encoding != tt.want {
+				t.Errorf("got encoding %q, want %q", info.Encoding, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyStackedEncodings(t *testing.T) {
+	var inner, outer bytes.Buffer
+	zw, _ := zstd.NewWriter(&inner)
+	zw.Write([]byte(page))
+	zw.Close()
+	gz := gzip.NewWriter(&outer)
+	gz.Write(inner.Bytes())
+	gz.Close()
+
+	body, info, err := Body(&outer, "zstd, gzip", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(body)
+	if string(got) != page || info.Encoding != "zstd, gzip" {
+		t.Errorf("got %q with encoding %q", got, info.Encoding)
+	}
+
+	if _, _, err := Body(strings.NewReader(page), "compress", ""); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String(page)
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("<p>跟踪器</p>\n")
+	cyrillic, _ := charmap.Windows1251.NewEncoder().String("<p>Трекер</p>\n")
+	latin, _ := charmap.Windows1252.NewEncoder().String("<p>café</p>\n")
+
+	tests := []struct {
+		name        string
+		content     string
+		contentType string
+		want        string
+		charset     string
+		source      string
+	}{
+		{"header", sjis, "text/html; charset=Shift_JIS", page, "shift_jis", "header"},
+		{"meta", `<meta charset="gbk">` + gbk, "text/html", `<meta charset="gbk">` + "<p>跟踪器</p>\n", "gbk", "meta"},
+		{"http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=windows-1251">` + cyrillic, "",
+			`<meta http-equiv="Content-Type" content="text/html; charset=windows-1251">` + "<p>Трекер</p>\n", "windows-1251", "meta"},
+		{"xml", `<?xml version="1.0" encoding="Shift_JIS"?>` + sjis, "", `<?xml version="1.0" encoding="Shift_JIS"?>` + page, "shift_jis", "xml"},
+		{"bom", "\xef\xbb\xbf" + page, "", page, "utf-8", "bom"},
+		{"header wins over meta", `<meta charset="gbk">` + page, "text/html; charset=utf-8", `<meta charset="gbk">` + page, "utf-8", "header"},
+		{"undeclared utf-8", page, "", page, "utf-8", "default"},
+		{"undeclared legacy", latin, "", "<p>café</p>\n", "windows-1252", "default"},
+		{"unknown label", `<meta charset="x-unknown">` + page, "", `<meta charset="x-unknown">` + page, "utf-8", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, charset, source := Transcode(strings.NewReader(tt.content), tt.contentType)
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if charset != tt.charset || source != tt.source {
+				t.Errorf("got charset %s from %s, want %s from %s", charset, source, tt.charset, tt.source)
+			}
+		})
+	}
+}
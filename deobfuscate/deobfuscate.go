@@ -0,0 +1,146 @@
+package deobfuscate
+
+import (
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	escapeSeq = regexp.MustCompile(`\\x([0-9a-fA-F]{2})|\\u\{([0-9a-fA-F]{1,6})\}|\\u([0-9a-fA-F]{4})`)
+	// literal is a single- or double-quoted string without escapes
+	literal       = `(?:'[^'\\\n]*'|"[^"\\\n]*")`
+	concatenation = regexp.MustCompile(literal + `(?:\s*\+\s*` + literal + `)+`)
+	literalPart   = regexp.MustCompile(literal)
+	decodeCall    = regexp.MustCompile(`(?:window\.)?(atob|decodeURIComponent|decodeURI|unescape)\(\s*(` + literal + `)\s*\)`)
+	base64Literal = regexp.MustCompile(`(['"])([A-Za-z0-9+/]{20,}={0,2})(['"])`)
+	urlLiteral    = regexp.MustCompile(`(['"])([^'"\\\n]*%[0-9A-Fa-f]{2}[^'"\\\n]*)(['"])`)
+	urlLike       = regexp.MustCompile(`(?i)^(?:https?:)?//|[a-z0-9-]+\.[a-z]{2,}(?:/|$)`)
+	percentEscape = regexp.MustCompile(`%[0-9A-Fa-f]{2}`)
+)
+
+// Normalize undoes common script obfuscation so tracker URLs and IDs can
+// be matched: \x and \u escapes are decoded, concatenated string literals
+// are joined, and atob, decodeURIComponent and unescape calls on literals,
+// as well as literals that are plainly base64- or URL-encoded URLs, are
+// replaced with their decoded text. Line breaks are kept so line numbers
+// in the result match the input.
+func Normalize(content string) string {
+	result := escapeSeq.ReplaceAllStringFunc(content, decodeEscape)
+	result = concatenation.ReplaceAllStringFunc(result, joinLiterals)
+	result = decodeCall.ReplaceAllStringFunc(result, decodeCallLiteral)
+	result = base64Literal.ReplaceAllStringFunc(result, decodeBase64Literal)
+	result = urlLiteral.ReplaceAllStringFunc(result, decodeURLLiteral)
+	return result
+}
+
+// decodeEscape decodes a printable escaped character. Quotes, backslashes
+// and control characters stay escaped so string boundaries and lines are
+// unchanged.
+func decodeEscape(seq string) string {
+	m := escapeSeq.FindStringSubmatch(seq)
+	hex := m[1] + m[2] + m[3]
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || code < 0x20 || code == 0x7f || code > 0x10ffff {
+		return seq
+	}
+	r := rune(code)
+	if r == '"' || r == '\'' || r == '\\' {
+		return seq
+	}
+	return string(r)
+}
+
+// joinLiterals joins 'a' + "b" + 'c' into one literal, keeping any line
+// breaks between the parts after it
+func joinLiterals(expr string) string {
+	var joined strings.Builder
+	for _, part := range literalPart.FindAllString(expr, -1) {
+		joined.WriteString(part[1 : len(part)-1])
+	}
+	return quote(joined.String()) + strings.Repeat("\n", strings.Count(expr, "\n"))
+}
+
+func decodeCallLiteral(call string) string {
+	m := decodeCall.FindStringSubmatch(call)
+	value := m[2][1 : len(m[2])-1]
+	var decoded string
+	var ok bool
+	if m[1] == "atob" {
+		decoded, ok = decodeBase64(value)
+	} else {
+		decoded, ok = decodePercent(value)
+	}
+	if !ok {
+		return call
+	}
+	return quote(decoded) + strings.Repeat("\n", strings.Count(call, "\n"))
+}
+
+// decodeBase64Literal decodes a base64 literal when it holds a URL or domain
+func decodeBase64Literal(lit string) string {
+	m := base64Literal.FindStringSubmatch(lit)
+	if m[1] != m[3] {
+		return lit
+	}
+	decoded, ok := decodeBase64(m[2])
+	if !ok || !urlLike.MatchString(decoded) {
+		return lit
+	}
+	return quote(decoded)
+}
+
+// decodeURLLiteral decodes a percent-encoded literal when it holds a URL
+func decodeURLLiteral(lit string) string {
+	m := urlLiteral.FindStringSubmatch(lit)
+	if m[1] != m[3] || len(percentEscape.FindAllString(m[2], 3)) < 3 {
+		return lit
+	}
+	decoded, ok := decodePercent(m[2])
+	if !ok || !urlLike.MatchString(decoded) {
+		return lit
+	}
+	return quote(decoded)
+}
+
+func decodeBase64(value string) (string, bool) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "=")); err != nil {
+			return "", false
+		}
+	}
+	return printable(string(data))
+}
+
+func decodePercent(value string) (string, bool) {
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return "", false
+	}
+	return printable(decoded)
+}
+
+// printable rejects decoded text that is binary or would break the line
+// structure of the normalized content
+func printable(s string) (string, bool) {
+	if s == "" {
+		return "", false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || r == 0xfffd {
+			return "", false
+		}
+	}
+	return s, true
+}
+
+// quote wraps a string in whichever quote it does not contain
+func quote(s string) string {
+	if strings.Contains(s, `"`) {
+		return "'" + s + "'"
+	}
+	return `"` + s + `"`
+}
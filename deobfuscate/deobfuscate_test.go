@@ -0,0 +1,41 @@
+package deobfuscate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"hex escapes", `var u = "\x68ttps://connect.facebook.net";`, `var u = "https://connect.facebook.net";`},
+		{"unicode escapes", `s = 'fbq\u{28}';`, `s = 'fbq(';`},
+		{"escaped quote kept", `s = "a\x22b";`, `s = "a\x22b";`},
+		{"concatenation", `src = "https://static." + 'hotjar' + ".com/c.js";`, `src = "https://static.hotjar.com/c.js";`},
+		{"atob", `var p = atob('aHR0cHM6Ly9zdGF0aWMuaG90amFyLmNvbS9jLmpz');`, `var p = "https://static.hotjar.com/c.js";`},
+		{"decodeURIComponent", `decodeURIComponent("fbq%28%27init%27%29")`, `"fbq('init')"`},
+		{"base64 literal", `var u = "Ly93d3cuZ29vZ2xlLWFuYWx5dGljcy5jb20vYW5hbHl0aWNzLmpz";`, `var u = "//www.google-analytics.com/analytics.js";`},
+		{"base64 non-URL kept", `var t = "QUJDREVGR0hJSktMTU5PUFFSU1RVVldY";`, `var t = "QUJDREVGR0hJSktMTU5PUFFSU1RVVldY";`},
+		{"percent literal", `"https%3A%2F%2Fbat.bing.com%2Fbat.js"`, `"https://bat.bing.com/bat.js"`},
+		{"plain text unchanged", `<p class="intro">100% of "users"</p>`, `<p class="intro">100% of "users"</p>`},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.content); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeKeepsLines(t *testing.T) {
+	content := "var u = 'https://connect.' +\n  'facebook.net/fbevents.js';\nvar k = 'UA-1234567-1';"
+	got := Normalize(content)
+	if strings.Count(got, "\n") != strings.Count(content, "\n") {
+		t.Fatalf("Line count changed: %q", got)
+	}
+	if lines := strings.Split(got, "\n"); !strings.Contains(lines[0], "https://connect.facebook.net/fbevents.js") || !strings.Contains(lines[2], "UA-1234567-1") {
+		t.Errorf("Unexpected normalized lines: %q", lines)
+	}
+}
@@ -0,0 +1,520 @@
+package filters
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Resource types of network rules
+const (
+	TypeScript      = "script"
+	TypeImage       = "image"
+	TypeStylesheet  = "stylesheet"
+	TypeSubdocument = "subdocument"
+	TypeObject      = "object"
+	TypeMedia       = "media"
+	TypeFont        = "font"
+	TypeOther       = "other"
+)
+
+// typeNames maps rule options, including common aliases, to resource types
+var typeNames = map[string]string{
+	"script": TypeScript, "image": TypeImage, "stylesheet": TypeStylesheet, "css": TypeStylesheet,
+	"subdocument": TypeSubdocument, "frame": TypeSubdocument, "object": TypeObject,
+	"object-subrequest": TypeObject, "media": TypeMedia, "font": TypeFont, "other": TypeOther,
+	"xmlhttprequest": "xmlhttprequest", "xhr": "xmlhttprequest", "ping": "ping", "beacon": "ping",
+	"websocket": "websocket", "webrtc": "webrtc", "document": "document", "doc": "document",
+	"popup": "popup",
+}
+
+// kindTypes maps the element kinds reported by thirdparty.Extract to the
+// resource types rules use
+var kindTypes = map[string]string{
+	"script":                TypeScript,
+	"img":                   TypeImage,
+	"iframe":                TypeSubdocument,
+	"media":                 TypeMedia,
+	"object":                TypeObject,
+	"embed":                 TypeObject,
+	"link stylesheet":       TypeStylesheet,
+	"link modulepreload":    TypeScript,
+	"link icon":             TypeImage,
+	"link apple-touch-icon": TypeImage,
+}
+
+// ResourceType returns the rule resource type of an element kind as
+// reported by thirdparty.Extract
+func ResourceType(kind string) string {
+	if t, ok := kindTypes[kind]; ok {
+		return t
+	}
+	return TypeOther
+}
+
+// ignoredOptions do not change which requests a rule matches
+var ignoredOptions = map[string]bool{"important": true, "all": true, "match-case": true}
+
+// Request is a resource loaded by a page
+type Request struct {
+	URL        string
+	Type       string
+	PageHost   string // empty for local files
+	ThirdParty bool
+}
+
+// Rule is a compiled network rule
+type Rule struct {
+	Text     string // the rule as written in the list
+	List     string // name of the list it came from
+	Category string // AdNetwork or Tracking, from the list
+
+	exception   bool
+	pattern     string
+	re          *regexp.Regexp
+	hostAnchor  bool
+	startAnchor bool
+	endAnchor   bool
+	matchCase   bool
+	thirdParty  int // 1 for third-party only, -1 for first-party only
+	types       map[string]bool
+	notTypes    map[string]bool
+	domains     []string
+	notDomains  []string
+}
+
+// ListInfo summarizes a loaded filter list
+type ListInfo struct {
+	Name     string
+	Category string
+	Rules    int // network rules compiled
+	Skipped  int // network rules with unsupported options or syntax
+}
+
+// Engine matches requests against the network rules of filter lists
+type Engine struct {
+	Lists      []ListInfo
+	blocks     index
+	exceptions index
+}
+
+// New returns an Engine without rules
+func New() *Engine {
+	return &Engine{blocks: newIndex(), exceptions: newIndex()}
+}
+
+// Load adds the network rules of an Adblock Plus filter list file
+func (e *Engine) Load(path string) (ListInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ListInfo{}, err
+	}
+	defer f.Close()
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return e.Parse(name, f)
+}
+
+// Parse adds the network rules of an Adblock Plus filter list. The list is
+// named by its "! Title:" header, or name when it has none. Element hiding
+// and other cosmetic rules are ignored.
+func (e *Engine) Parse(name string, r io.Reader) (ListInfo, error) {
+	var rules []*Rule
+	info := ListInfo{Name: name}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "!") {
+			if title := strings.TrimPrefix(line, "! Title:"); title != line {
+				info.Name = strings.TrimSpace(title)
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "[") || cosmetic(line) {
+			continue
+		}
+		rule, ok := parseRule(line)
+		if !ok {
+			info.Skipped++
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if err := sc.Err(); err != nil {
+		return info, fmt.Errorf("reading %s: %v", name, err)
+	}
+
+	info.Category = Category(info.Name)
+	info.Rules = len(rules)
+	for _, rule := range rules {
+		rule.List = info.Name
+		rule.Category = info.Category
+		if rule.exception {
+			e.exceptions.add(rule)
+		} else {
+			e.blocks.add(rule)
+		}
+	}
+	e.Lists = append(e.Lists, info)
+	return info, nil
+}
+
+// Category reports matches of privacy lists, such as EasyPrivacy and
+// Fanboy's Social list, as Tracking and those of other lists as AdNetwork
+func Category(listName string) string {
+	lower := strings.ToLower(listName)
+	for _, word := range []string{"privacy", "tracking", "tracker", "social", "analytics"} {
+		if strings.Contains(lower, word) {
+			return "Tracking"
+		}
+	}
+	return "AdNetwork"
+}
+
+// Match returns, for each list blocking a request, the first rule that
+// blocks it. Nothing is returned when an exception rule of any list allows
+// the request.
+func (e *Engine) Match(req Request) []*Rule {
+	lower := strings.ToLower(req.URL)
+	tokens := urlTokens(lower)
+	rules := e.blocks.match(req, lower, tokens, false)
+	if len(rules) == 0 || len(e.exceptions.match(req, lower, tokens, true)) > 0 {
+		return nil
+	}
+	return rules
+}
+
+// cosmetic reports element hiding, scriptlet and HTML filtering rules
+func cosmetic(line string) bool {
+	for _, sep := range []string{"##", "#@#", "#?#", "#$#", "#%#", "#@?#", "$$", "$@$"} {
+		if strings.Contains(line, sep) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionsPattern matches the text after $ in a rule with options
+var optionsPattern = regexp.MustCompile(`^~?[a-z0-9_-]+(?:=[^,]*)?(?:,~?[a-z0-9_-]+(?:=[^,]*)?)*$`)
+
+// parseRule compiles a network rule, reporting false for rules whose
+// options or syntax are not supported
+func parseRule(text string) (*Rule, bool) {
+	rule := &Rule{Text: text}
+	line := text
+	if strings.HasPrefix(line, "@@") {
+		rule.exception = true
+		line = line[2:]
+	}
+
+	if i := strings.LastIndex(line, "$"); i >= 0 && optionsPattern.MatchString(line[i+1:]) {
+		if !rule.parseOptions(line[i+1:]) {
+			return nil, false
+		}
+		line = line[:i]
+	}
+
+	if len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+		expr := line[1 : len(line)-1]
+		if !rule.matchCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, false
+		}
+		rule.re = re
+		return rule, true
+	}
+
+	switch {
+	case strings.HasPrefix(line, "||"):
+		rule.hostAnchor = true
+		line = line[2:]
+	case strings.HasPrefix(line, "|"):
+		rule.startAnchor = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "|") {
+		rule.endAnchor = true
+		line = line[:len(line)-1]
+	}
+	if !rule.hostAnchor && !rule.startAnchor {
+		line = strings.TrimLeft(line, "*")
+	}
+	if !rule.endAnchor {
+		line = strings.TrimRight(line, "*")
+	}
+	if strings.Contains(line, "|") {
+		return nil, false
+	}
+	if !rule.matchCase {
+		line = strings.ToLower(line)
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+func (r *Rule) parseOptions(options string) bool {
+	for _, option := range strings.Split(options, ",") {
+		name, value := option, ""
+		if i := strings.IndexByte(option, '='); i >= 0 {
+			name, value = option[:i], option[i+1:]
+		}
+		negated := strings.HasPrefix(name, "~")
+		name = strings.TrimPrefix(name, "~")
+
+		switch {
+		case name == "third-party" || name == "3p":
+			r.thirdParty = 1
+			if negated {
+				r.thirdParty = -1
+			}
+		case name == "first-party" || name == "1p":
+			r.thirdParty = -1
+			if negated {
+				r.thirdParty = 1
+			}
+		case name == "domain" || name == "from":
+			for _, d := range strings.Split(value, "|") {
+				if strings.HasPrefix(d, "~") {
+					r.notDomains = append(r.notDomains, strings.ToLower(d[1:]))
+				} else if d != "" {
+					r.domains = append(r.domains, strings.ToLower(d))
+				}
+			}
+		case name == "match-case":
+			r.matchCase = true
+		case typeNames[name] != "":
+			t := typeNames[name]
+			if negated {
+				if r.notTypes == nil {
+					r.notTypes = make(map[string]bool)
+				}
+				r.notTypes[t] = true
+			} else {
+				if r.types == nil {
+					r.types = make(map[string]bool)
+				}
+				r.types[t] = true
+			}
+		case ignoredOptions[name]:
+		default:
+			// Options such as csp, redirect or removeparam change requests
+			// rather than block them
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the rule applies to a request. lower is the
+// request URL in lower case.
+func (r *Rule) matches(req Request, lower string) bool {
+	if (r.thirdParty == 1 && !req.ThirdParty) || (r.thirdParty == -1 && req.ThirdParty) {
+		return false
+	}
+	if (r.types != nil && !r.types[req.Type]) || r.notTypes[req.Type] {
+		return false
+	}
+	if len(r.domains) > 0 && !hostIn(req.PageHost, r.domains) {
+		return false
+	}
+	if hostIn(req.PageHost, r.notDomains) {
+		return false
+	}
+
+	u := lower
+	if r.matchCase {
+		u = req.URL
+	}
+	if r.re != nil {
+		return r.re.MatchString(u)
+	}
+	switch {
+	case r.hostAnchor:
+		start := strings.Index(u, "://")
+		if start < 0 {
+			return false
+		}
+		start += 3
+		end := len(u)
+		if i := strings.IndexAny(u[start:], "/?#"); i >= 0 {
+			end = start + i
+		}
+		for i := start; i < end; i++ {
+			if (i == start || u[i-1] == '.') && matchAt(r.pattern, u[i:], r.endAnchor) {
+				return true
+			}
+		}
+		return false
+	case r.startAnchor:
+		return matchAt(r.pattern, u, r.endAnchor)
+	}
+	for i := 0; i <= len(u); i++ {
+		if matchAt(r.pattern, u[i:], r.endAnchor) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAt matches a rule pattern at the start of s. * matches any run of
+// characters and ^ a separator or the end of the URL.
+func matchAt(p, s string, endAnchor bool) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchAt(p, s[i:], endAnchor) {
+					return true
+				}
+			}
+			return false
+		case '^':
+			if len(s) == 0 {
+				p = p[1:]
+				continue
+			}
+			if !separator(s[0]) {
+				return false
+			}
+		default:
+			if len(s) == 0 || s[0] != p[0] {
+				return false
+			}
+		}
+		p, s = p[1:], s[1:]
+	}
+	return !endAnchor || len(s) == 0
+}
+
+// separator reports the characters ^ matches: anything but a letter, a
+// digit or one of _ - . %
+func separator(c byte) bool {
+	return !tokenChar(c) && c != '_' && c != '-' && c != '.'
+}
+
+func tokenChar(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '%'
+}
+
+func hostIn(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// index groups rules by a token that occurs whole in every URL they match,
+// so a URL is only tested against rules sharing one of its tokens
+type index struct {
+	byToken map[string][]*Rule
+	other   []*Rule
+}
+
+func newIndex() index {
+	return index{byToken: make(map[string][]*Rule)}
+}
+
+// commonTokens occur in most URLs and make poor index keys
+var commonTokens = map[string]bool{"http": true, "https": true, "www": true, "com": true, "js": true}
+
+func (x *index) add(r *Rule) {
+	if token := ruleToken(r); token != "" {
+		x.byToken[token] = append(x.byToken[token], r)
+		return
+	}
+	x.other = append(x.other, r)
+}
+
+// match returns the first matching rule of each list, or only the first
+// matching rule overall when first is set
+func (x *index) match(req Request, lower string, tokens []string, first bool) []*Rule {
+	var found []*Rule
+	try := func(r *Rule) bool {
+		for _, f := range found {
+			if f.List == r.List {
+				return false
+			}
+		}
+		if !r.matches(req, lower) {
+			return false
+		}
+		found = append(found, r)
+		return first
+	}
+	for _, t := range tokens {
+		for _, r := range x.byToken[t] {
+			if try(r) {
+				return found
+			}
+		}
+	}
+	for _, r := range x.other {
+		if try(r) {
+			return found
+		}
+	}
+	return found
+}
+
+// ruleToken picks the longest run of token characters in the pattern that
+// is not next to a wildcard or an unanchored end, and so must appear as a
+// whole token in a matching URL
+func ruleToken(r *Rule) string {
+	if r.re != nil {
+		return ""
+	}
+	p := strings.ToLower(r.pattern)
+	best := ""
+	for i := 0; i < len(p); {
+		if !tokenChar(p[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(p) && tokenChar(p[j]) {
+			j++
+		}
+		startOK := (i == 0 && (r.hostAnchor || r.startAnchor)) || (i > 0 && p[i-1] != '*')
+		endOK := (j == len(p) && r.endAnchor) || (j < len(p) && p[j] != '*')
+		token := p[i:j]
+		if startOK && endOK && len(token) > 1 && !commonTokens[token] && len(token) > len(best) {
+			best = token
+		}
+		i = j
+	}
+	return best
+}
+
+// urlTokens returns the distinct runs of token characters in a URL
+func urlTokens(lower string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(lower); {
+		if !tokenChar(lower[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(lower) && tokenChar(lower[j]) {
+			j++
+		}
+		if t := lower[i:j]; !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+		i = j
+	}
+	return tokens
+}
@@ -0,0 +1,114 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+)
+
+const easyList = `[Adblock Plus 2.0]
+! Title: EasyList
+! Comment lines and cosmetic rules are ignored
+example.com##.ad-banner
+||adserver.test^
+||ads.cdn.test^$script,third-party
+/banner/*/ad_
+-ad-300x250.
+|https://exact.test/ad.js|
+@@||adserver.test/allowed/
+||popunder.test^$popup
+||tagged.test^$domain=news.test|~sports.news.test
+||csp.test^$csp=script-src 'none'
+/ad[0-9]+\.gif/
+||cased.test/Banner$match-case
+`
+
+const easyPrivacy = `! Title: EasyPrivacy
+||metrics.test^$third-party
+/pixel.gif?
+`
+
+func TestMatch(t *testing.T) {
+	e := New()
+	ads, err := e.Parse("easylist", strings.NewReader(easyList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	privacy, err := e.Parse("easyprivacy", strings.NewReader(easyPrivacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ads.Name != "EasyList" || ads.Category != "AdNetwork" || ads.Rules != 10 || ads.Skipped != 1 {
+		t.Errorf("unexpected EasyList summary %+v", ads)
+	}
+	if privacy.Category != "Tracking" || privacy.Rules != 2 {
+		t.Errorf("unexpected EasyPrivacy summary %+v", privacy)
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		rule string
+	}{
+		{"host anchor", Request{URL: "https://adserver.test/x.js", Type: TypeScript, ThirdParty: true}, "||adserver.test^"},
+		{"subdomain", Request{URL: "https://eu.adserver.test/x.js", Type: TypeScript, ThirdParty: true}, "||adserver.test^"},
+		{"not a label boundary", Request{URL: "https://myadserver.test/x.js", Type: TypeScript, ThirdParty: true}, ""},
+		{"exception", Request{URL: "https://adserver.test/allowed/x.js", Type: TypeScript, ThirdParty: true}, ""},
+		{"type option", Request{URL: "https://ads.cdn.test/lib.js", Type: TypeScript, ThirdParty: true}, "||ads.cdn.test^$script,third-party"},
+		{"wrong type", Request{URL: "https://ads.cdn.test/logo.png", Type: TypeImage, ThirdParty: true}, ""},
+		{"first-party", Request{URL: "https://ads.cdn.test/lib.js", Type: TypeScript}, ""},
+		{"wildcard", Request{URL: "https://site.test/banner/top/ad_1.png", Type: TypeImage}, "/banner/*/ad_"},
+		{"substring", Request{URL: "https://site.test/img/x-ad-300x250.png", Type: TypeImage}, "-ad-300x250."},
+		{"start and end anchors", Request{URL: "https://exact.test/ad.js", Type: TypeScript}, "|https://exact.test/ad.js|"},
+		{"end anchor", Request{URL: "https://exact.test/ad.js?v=2", Type: TypeScript}, ""},
+		{"popup only", Request{URL: "https://popunder.test/", Type: TypeSubdocument}, ""},
+		{"domain option", Request{URL: "https://tagged.test/t.js", Type: TypeScript, PageHost: "www.news.test"}, "||tagged.test^$domain=news.test|~sports.news.test"},
+		{"excluded domain", Request{URL: "https://tagged.test/t.js", Type: TypeScript, PageHost: "sports.news.test"}, ""},
+		{"other domain", Request{URL: "https://tagged.test/t.js", Type: TypeScript, PageHost: "blog.test"}, ""},
+		{"unsupported option skipped", Request{URL: "https://csp.test/", Type: TypeScript}, ""},
+		{"regex", Request{URL: "https://site.test/AD42.gif", Type: TypeImage}, "/ad[0-9]+\\.gif/"},
+		{"match case", Request{URL: "https://cased.test/Banner.png", Type: TypeImage}, "||cased.test/Banner$match-case"},
+		{"case differs", Request{URL: "https://cased.test/banner.png", Type: TypeImage}, ""},
+		{"privacy list", Request{URL: "https://cdn.metrics.test/collect", Type: TypeImage, ThirdParty: true}, "||metrics.test^$third-party"},
+		{"both lists", Request{URL: "https://adserver.test/pixel.gif?x", Type: TypeImage, ThirdParty: true}, "||adserver.test^,/pixel.gif?"},
+		{"separator at end", Request{URL: "https://metrics.test", Type: TypeImage, ThirdParty: true}, "||metrics.test^$third-party"},
+		{"query", Request{URL: "https://site.test/pixel.gif?id=1", Type: TypeImage}, "/pixel.gif?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var texts []string
+			for _, rule := range e.Match(tt.req) {
+				texts = append(texts, rule.Text)
+			}
+			if got := strings.Join(texts, ","); got != tt.rule {
+				t.Errorf("Match(%s) = %q, want %q", tt.req.URL, got, tt.rule)
+			}
+		})
+	}
+
+	if rules := e.Match(Request{URL: "https://cdn.metrics.test/c", ThirdParty: true}); len(rules) != 1 || rules[0].List != "EasyPrivacy" || rules[0].Category != "Tracking" {
+		t.Errorf("got rules %+v, want one EasyPrivacy Tracking rule", rules)
+	}
+}
+
+func TestRuleToken(t *testing.T) {
+	tests := []struct {
+		rule  string
+		token string
+	}{
+		{"||adserver.test^", "adserver"},
+		{"/banner/*/ad_", "banner"},
+		{"-ad-300x250.", "300x250"},
+		{"ads*", ""},
+		{"|https://www.example.com/", "example"},
+		{"/ad[0-9]+\\.gif/", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := parseRule(tt.rule)
+		if !ok {
+			t.Fatalf("parseRule(%q) failed", tt.rule)
+		}
+		if got := ruleToken(rule); got != tt.token {
+			t.Errorf("ruleToken(%q) = %q, want %q", tt.rule, got, tt.token)
+		}
+	}
+}
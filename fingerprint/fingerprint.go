@@ -0,0 +1,146 @@
+package fingerprint
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gregcmartin/spectre/patterns"
+	"golang.org/x/net/html"
+)
+
+// Page holds the parts of a response that targets are matched against
+type Page struct {
+	URL     string
+	Header  http.Header
+	Cookies []string            // cookie names set by the response
+	Meta    map[string][]string // lowercased meta name to content values
+	Scripts []string            // script src values in document order
+}
+
+// NewPage builds a Page, reading meta tags and script sources from body
+func NewPage(url string, header http.Header, cookieNames []string, body string) Page {
+	page := Page{
+		URL:     url,
+		Header:  header,
+		Cookies: cookieNames,
+		Meta:    make(map[string][]string),
+	}
+	page.Add(body, len(body))
+	return page
+}
+
+// Add reads the meta tags and script sources of a window of the body from
+// the tags that start before limit, so a body can be read in overlapping
+// windows
+func (p *Page) Add(content string, limit int) {
+	if p.Meta == nil {
+		p.Meta = make(map[string][]string)
+	}
+	z := html.NewTokenizer(strings.NewReader(content))
+	offset := 0
+	for offset < limit {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		offset += len(z.Raw())
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		tag := string(name)
+		if tag != "meta" && tag != "script" {
+			continue
+		}
+		attrs := make(map[string]string)
+		for hasAttr {
+			var key, val []byte
+			key, val, hasAttr = z.TagAttr()
+			attrs[string(key)] = string(val)
+		}
+		switch {
+		case tag == "meta" && attrs["name"] != "":
+			key := strings.ToLower(attrs["name"])
+			p.Meta[key] = append(p.Meta[key], attrs["content"])
+		case tag == "script" && attrs["src"] != "":
+			p.Scripts = append(p.Scripts, attrs["src"])
+		}
+	}
+}
+
+// Matcher is a compiled Target
+type Matcher struct {
+	Target patterns.Target
+	re     *regexp.Regexp
+}
+
+// Match is a target hit. Value is the header, cookie, meta, script or URL
+// text that matched, prefixed with its key where there is one.
+type Match struct {
+	Target string
+	Key    string
+	Value  string
+}
+
+// Compile compiles a pattern's targets
+func Compile(targets []patterns.Target) ([]Matcher, error) {
+	var matchers []Matcher
+	for _, t := range targets {
+		m := Matcher{Target: t}
+		if t.Pattern != "" {
+			re, err := regexp.Compile(t.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			m.re = re
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func (m Matcher) matches(value string) bool {
+	return m.re == nil || m.re.MatchString(value)
+}
+
+// Match returns the target's hits on a page
+func (m Matcher) Match(p Page) []Match {
+	var found []Match
+	switch m.Target.Type {
+	case patterns.TargetHeader:
+		key := http.CanonicalHeaderKey(m.Target.Key)
+		for _, v := range p.Header.Values(key) {
+			if m.matches(v) {
+				found = append(found, Match{Target: m.Target.Type, Key: key, Value: key + ": " + v})
+			}
+		}
+	case patterns.TargetCookie:
+		names := append([]string(nil), p.Cookies...)
+		sort.Strings(names)
+		for _, name := range names {
+			if m.matches(name) {
+				found = append(found, Match{Target: m.Target.Type, Value: name})
+			}
+		}
+	case patterns.TargetMeta:
+		key := strings.ToLower(m.Target.Key)
+		for _, v := range p.Meta[key] {
+			if m.matches(v) {
+				found = append(found, Match{Target: m.Target.Type, Key: key, Value: key + ": " + v})
+			}
+		}
+	case patterns.TargetScript:
+		for _, src := range p.Scripts {
+			if m.matches(src) {
+				found = append(found, Match{Target: m.Target.Type, Value: src})
+			}
+		}
+	case patterns.TargetURL:
+		if p.URL != "" && m.matches(p.URL) {
+			found = append(found, Match{Target: m.Target.Type, Value: p.URL})
+		}
+	}
+	return found
+}
@@ -0,0 +1,73 @@
+package fingerprint
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+const body = `<html><head>
+<meta name="Generator" content="WordPress 6.4.2">
+<script src="//cdn.shopify.com/s/files/theme.js"></script>
+<script>var inline = true;</script>
+</head></html>`
+
+func TestNewPage(t *testing.T) {
+	page := NewPage("https://shop.example.com/", nil, nil, body)
+	if got := page.Meta["generator"]; len(got) != 1 || got[0] != "WordPress 6.4.2" {
+		t.Errorf("Unexpected generator meta: %v", got)
+	}
+	if len(page.Scripts) != 1 || page.Scripts[0] != "//cdn.shopify.com/s/files/theme.js" {
+		t.Errorf("Unexpected scripts: %v", page.Scripts)
+	}
+}
+
+func TestPageAdd(t *testing.T) {
+	// Windows overlapping on the script tag, which the second owns
+	split := strings.Index(body, "<script src")
+	var page Page
+	page.Add(body[:split+20], split)
+	page.Add(body[split:], len(body)-split)
+	if got := page.Meta["generator"]; len(got) != 1 || got[0] != "WordPress 6.4.2" {
+		t.Errorf("Unexpected generator meta: %v", got)
+	}
+	if len(page.Scripts) != 1 || page.Scripts[0] != "//cdn.shopify.com/s/files/theme.js" {
+		t.Errorf("Unexpected scripts: %v", page.Scripts)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Shopify-Stage", "production")
+	header.Set("X-Generator", "Drupal 10 (https://www.drupal.org)")
+	page := NewPage("https://shop.example.com/cart", header, []string{"_shopify_y", "SESSd41d8cd98f00b204e9800998ecf8427e"}, body)
+
+	tests := []struct {
+		target patterns.Target
+		want   string
+	}{
+		{patterns.Target{Type: patterns.TargetHeader, Key: "x-shopify-stage"}, "X-Shopify-Stage: production"},
+		{patterns.Target{Type: patterns.TargetHeader, Key: "X-Generator", Pattern: `(?i)^Drupal`}, "X-Generator: Drupal 10 (https://www.drupal.org)"},
+		{patterns.Target{Type: patterns.TargetHeader, Key: "X-Generator", Pattern: `(?i)^Joomla`}, ""},
+		{patterns.Target{Type: patterns.TargetHeader, Key: "X-Drupal-Cache"}, ""},
+		{patterns.Target{Type: patterns.TargetCookie, Pattern: `^SS?ESS[0-9a-f]{32}$`}, "SESSd41d8cd98f00b204e9800998ecf8427e"},
+		{patterns.Target{Type: patterns.TargetMeta, Key: "generator", Pattern: `(?i)^WordPress`}, "generator: WordPress 6.4.2"},
+		{patterns.Target{Type: patterns.TargetScript, Pattern: `//cdn\.shopify\.com/`}, "//cdn.shopify.com/s/files/theme.js"},
+		{patterns.Target{Type: patterns.TargetURL, Pattern: `/cart$`}, "https://shop.example.com/cart"},
+	}
+	for _, tt := range tests {
+		matchers, err := Compile([]patterns.Target{tt.target})
+		if err != nil {
+			t.Fatalf("Compile(%+v): %v", tt.target, err)
+		}
+		found := matchers[0].Match(page)
+		switch {
+		case tt.want == "" && len(found) != 0:
+			t.Errorf("%+v: expected no match, got %+v", tt.target, found)
+		case tt.want != "" && (len(found) != 1 || found[0].Value != tt.want):
+			t.Errorf("%+v: got %+v, want %q", tt.target, found, tt.want)
+		}
+	}
+}
@@ -2,4 +2,10 @@ module github.com/gregcmartin/spectre
 
 go 1.19
 
-require golang.org/x/net v0.15.0
+require (
+	github.com/andybalholm/brotli v1.0.6
+	github.com/klauspost/compress v1.16.7
+	golang.org/x/net v0.15.0
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,351 @@
+package headers
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gregcmartin/spectre/cookies"
+	"github.com/gregcmartin/spectre/models"
+)
+
+// Recorded response headers, in report order
+var Recorded = []string{
+	"Content-Security-Policy",
+	"Content-Security-Policy-Report-Only",
+	"Strict-Transport-Security",
+	"X-Frame-Options",
+	"Referrer-Policy",
+	"Permissions-Policy",
+	"Server",
+	"X-Powered-By",
+}
+
+// PatternTypes lists the pattern types of SecurityHeaders findings: the
+// score, each issue, and CSP sources that allow a detected tracker
+var PatternTypes = []string{
+	"Header Score",
+	"Missing Content-Security-Policy",
+	"Weak Content-Security-Policy",
+	"Missing HSTS",
+	"Weak HSTS",
+	"Missing X-Frame-Options",
+	"Missing Referrer-Policy",
+	"Weak Referrer-Policy",
+	"Missing Permissions-Policy",
+	"Server Version Disclosure",
+	"CSP Whitelisted Tracker",
+}
+
+// Issue is a single header weakness found during analysis
+type Issue struct {
+	Name   string // finding pattern type, e.g. "Missing HSTS"
+	Header string
+	Detail string
+}
+
+// Check is the score for one header out of its maximum weight
+type Check struct {
+	Header string
+	Score  int
+	Max    int
+}
+
+var (
+	versionPattern = regexp.MustCompile(`\d+\.\d+`)
+	hostSource     = regexp.MustCompile(`^(?:[a-z][a-z0-9+.-]*://)?(\*\.)?([a-z0-9-]+(?:\.[a-z0-9-]+)+|\*)(?::[0-9*]+)?(?:/.*)?$`)
+)
+
+// strictReferrerPolicies do not leak full URLs to other origins
+var strictReferrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+}
+
+// Analyze records and scores the security headers of a response. pageHost
+// is used to tell third-party CSP sources from the site's own.
+func Analyze(h http.Header, pageHost string, https bool) (models.HeaderAnalysis, []Issue) {
+	analysis := models.HeaderAnalysis{Headers: make(map[string]string)}
+	for _, name := range Recorded {
+		if v := strings.Join(h.Values(name), ", "); v != "" {
+			analysis.Headers[name] = v
+		}
+	}
+
+	var checks []Check
+	var issues []Issue
+	add := func(c Check, found []Issue) {
+		checks = append(checks, c)
+		issues = append(issues, found...)
+	}
+
+	csp := ParseCSP(analysis.Headers["Content-Security-Policy"])
+	add(scoreCSP(csp, analysis.Headers["Content-Security-Policy-Report-Only"] != ""))
+	add(scoreHSTS(analysis.Headers["Strict-Transport-Security"], https))
+	add(scoreFrameOptions(analysis.Headers["X-Frame-Options"], csp))
+	add(scoreReferrerPolicy(analysis.Headers["Referrer-Policy"]))
+	add(scorePermissionsPolicy(analysis.Headers["Permissions-Policy"]))
+	add(scoreDisclosure(analysis.Headers["Server"], analysis.Headers["X-Powered-By"]))
+
+	total, max := 0, 0
+	for _, c := range checks {
+		total += c.Score
+		max += c.Max
+	}
+	analysis.Score = total * 100 / max
+	analysis.Grade = Grade(analysis.Score)
+	for _, issue := range issues {
+		analysis.Issues = append(analysis.Issues, issue.Name+": "+issue.Detail)
+	}
+	analysis.CSPDomains = ThirdPartySources(csp, pageHost)
+	return analysis, issues
+}
+
+// Grade converts a 0-100 score to a letter grade
+func Grade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 75:
+		return "B"
+	case score >= 60:
+		return "C"
+	case score >= 40:
+		return "D"
+	}
+	return "F"
+}
+
+// ParseCSP splits a policy into directives and their source lists. When
+// several policies are sent, the first definition of a directive wins.
+func ParseCSP(policy string) map[string][]string {
+	directives := make(map[string][]string)
+	for _, part := range strings.FieldsFunc(policy, func(r rune) bool { return r == ';' || r == ',' }) {
+		fields := strings.Fields(strings.TrimSpace(part))
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, ok := directives[name]; ok {
+			continue
+		}
+		directives[name] = fields[1:]
+	}
+	return directives
+}
+
+// scriptSources returns the sources governing scripts, falling back to
+// default-src as browsers do
+func scriptSources(csp map[string][]string) []string {
+	if sources, ok := csp["script-src"]; ok {
+		return sources
+	}
+	return csp["default-src"]
+}
+
+func scoreCSP(csp map[string][]string, reportOnly bool) (Check, []Issue) {
+	c := Check{Header: "Content-Security-Policy", Max: 30}
+	if len(csp) == 0 {
+		detail := "no policy is enforced"
+		if reportOnly {
+			c.Score = 5
+			detail = "policy is only sent as Content-Security-Policy-Report-Only"
+		}
+		return c, []Issue{{Name: "Missing Content-Security-Policy", Header: c.Header, Detail: detail}}
+	}
+
+	c.Score = 15
+	var issues []Issue
+	scripts := scriptSources(csp)
+	if hasSource(scripts, "'unsafe-inline'") && !hasNonceOrHash(scripts) {
+		issues = append(issues, Issue{Name: "Weak Content-Security-Policy", Header: c.Header, Detail: "scripts allow 'unsafe-inline'"})
+	} else if scripts != nil {
+		c.Score += 5
+	}
+	if hasSource(scripts, "'unsafe-eval'") {
+		issues = append(issues, Issue{Name: "Weak Content-Security-Policy", Header: c.Header, Detail: "scripts allow 'unsafe-eval'"})
+	} else if scripts != nil {
+		c.Score += 5
+	}
+	if hasWildcard(scripts) {
+		issues = append(issues, Issue{Name: "Weak Content-Security-Policy", Header: c.Header, Detail: "scripts allow any host"})
+	} else if scripts != nil {
+		c.Score += 5
+	}
+	if scripts == nil {
+		issues = append(issues, Issue{Name: "Weak Content-Security-Policy", Header: c.Header, Detail: "no script-src or default-src directive"})
+	}
+	return c, issues
+}
+
+func scoreHSTS(value string, https bool) (Check, []Issue) {
+	c := Check{Header: "Strict-Transport-Security", Max: 20}
+	if value == "" {
+		if !https {
+			// HSTS is ignored over plain HTTP; the missing upgrade is what matters
+			return c, []Issue{{Name: "Missing HSTS", Header: c.Header, Detail: "site served over plain HTTP"}}
+		}
+		return c, []Issue{{Name: "Missing HSTS", Header: c.Header, Detail: "browsers may connect over plain HTTP"}}
+	}
+
+	c.Score = 10
+	var issues []Issue
+	lower := strings.ToLower(value)
+	maxAge := 0
+	for _, part := range strings.Split(lower, ";") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "max-age=") {
+			maxAge, _ = strconv.Atoi(strings.Trim(strings.TrimPrefix(part, "max-age="), `"`))
+		}
+	}
+	if maxAge >= 15552000 {
+		c.Score += 5
+	} else {
+		issues = append(issues, Issue{Name: "Weak HSTS", Header: c.Header, Detail: "max-age is below 180 days"})
+	}
+	if strings.Contains(lower, "includesubdomains") {
+		c.Score += 5
+	} else {
+		issues = append(issues, Issue{Name: "Weak HSTS", Header: c.Header, Detail: "includeSubDomains is not set"})
+	}
+	return c, issues
+}
+
+func scoreFrameOptions(value string, csp map[string][]string) (Check, []Issue) {
+	c := Check{Header: "X-Frame-Options", Max: 10}
+	v := strings.ToUpper(strings.TrimSpace(value))
+	if v == "DENY" || v == "SAMEORIGIN" {
+		c.Score = 10
+		return c, nil
+	}
+	if ancestors, ok := csp["frame-ancestors"]; ok && !hasWildcard(ancestors) {
+		c.Score = 10
+		return c, nil
+	}
+	return c, []Issue{{Name: "Missing X-Frame-Options", Header: c.Header, Detail: "page can be framed by any site"}}
+}
+
+func scoreReferrerPolicy(value string) (Check, []Issue) {
+	c := Check{Header: "Referrer-Policy", Max: 10}
+	if value == "" {
+		// Browsers default to strict-origin-when-cross-origin
+		c.Score = 5
+		return c, []Issue{{Name: "Missing Referrer-Policy", Header: c.Header, Detail: "relies on the browser default"}}
+	}
+	policies := strings.Split(strings.ToLower(value), ",")
+	policy := strings.TrimSpace(policies[len(policies)-1])
+	switch {
+	case strictReferrerPolicies[policy]:
+		c.Score = 10
+		return c, nil
+	case policy == "origin" || policy == "origin-when-cross-origin":
+		c.Score = 5
+		return c, nil
+	}
+	return c, []Issue{{Name: "Weak Referrer-Policy", Header: c.Header, Detail: "full URLs are sent to third parties (" + policy + ")"}}
+}
+
+func scorePermissionsPolicy(value string) (Check, []Issue) {
+	c := Check{Header: "Permissions-Policy", Max: 10}
+	if value == "" {
+		return c, []Issue{{Name: "Missing Permissions-Policy", Header: c.Header, Detail: "embedded third parties may request powerful features"}}
+	}
+	c.Score = 10
+	return c, nil
+}
+
+func scoreDisclosure(server, poweredBy string) (Check, []Issue) {
+	c := Check{Header: "Server", Max: 20}
+	var issues []Issue
+	if versionPattern.MatchString(server) {
+		issues = append(issues, Issue{Name: "Server Version Disclosure", Header: "Server", Detail: server})
+	} else {
+		c.Score += 10
+	}
+	if poweredBy != "" {
+		issues = append(issues, Issue{Name: "Server Version Disclosure", Header: "X-Powered-By", Detail: poweredBy})
+	} else {
+		c.Score += 10
+	}
+	return c, issues
+}
+
+func hasSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNonceOrHash(sources []string) bool {
+	for _, s := range sources {
+		s = strings.ToLower(s)
+		if strings.HasPrefix(s, "'nonce-") || strings.HasPrefix(s, "'sha") || s == "'strict-dynamic'" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasWildcard(sources []string) bool {
+	for _, s := range sources {
+		if s == "*" || s == "https:" || s == "http:" || s == "data:" {
+			return true
+		}
+	}
+	return false
+}
+
+// ThirdPartySources returns the hosts allowed by any CSP directive that are
+// outside the page's registrable domain, sorted and without duplicates
+func ThirdPartySources(csp map[string][]string, pageHost string) []string {
+	site := cookies.RegistrableDomain(pageHost)
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, sources := range csp {
+		for _, source := range sources {
+			m := hostSource.FindStringSubmatch(strings.ToLower(source))
+			if m == nil || m[2] == "*" {
+				continue
+			}
+			host := m[1] + m[2]
+			if cookies.RegistrableDomain(m[2]) == site || seen[host] {
+				continue
+			}
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// WhitelistedTrackers matches the third-party hosts a CSP allows against
+// the domains of vendors detected on the page. It returns a map of allowed
+// host to the comma-separated vendors served from its registrable domain.
+func WhitelistedTrackers(cspDomains []string, vendorDomains map[string][]string) map[string]string {
+	result := make(map[string]string)
+	for _, host := range cspDomains {
+		hostSite := cookies.RegistrableDomain(strings.TrimPrefix(host, "*."))
+		var vendors []string
+		for vendor, domains := range vendorDomains {
+			for _, d := range domains {
+				if cookies.RegistrableDomain(d) == hostSite {
+					vendors = append(vendors, vendor)
+					break
+				}
+			}
+		}
+		if len(vendors) > 0 {
+			sort.Strings(vendors)
+			result[host] = strings.Join(vendors, ", ")
+		}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package headers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseCSP(t *testing.T) {
+	csp := ParseCSP("default-src 'self'; script-src 'self' https://www.google-analytics.com;  script-src *")
+	if got := csp["script-src"]; !reflect.DeepEqual(got, []string{"'self'", "https://www.google-analytics.com"}) {
+		t.Errorf("Expected first script-src to win, got %v", got)
+	}
+	if got := csp["default-src"]; !reflect.DeepEqual(got, []string{"'self'"}) {
+		t.Errorf("Unexpected default-src: %v", got)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		https  bool
+		score  int
+		grade  string
+		issues int
+	}{
+		{
+			name: "hardened",
+			header: http.Header{
+				"Content-Security-Policy":   {"default-src 'self'; frame-ancestors 'none'"},
+				"Strict-Transport-Security": {"max-age=31536000; includeSubDomains"},
+				"Referrer-Policy":           {"strict-origin-when-cross-origin"},
+				"Permissions-Policy":        {"camera=()"},
+				"Server":                    {"nginx"},
+			},
+			https: true,
+			score: 100,
+			grade: "A",
+		},
+		{
+			name:   "bare",
+			header: http.Header{},
+			https:  true,
+			score:  25,
+			grade:  "F",
+			issues: 5,
+		},
+		{
+			name: "weak",
+			header: http.Header{
+				"Content-Security-Policy":   {"script-src 'self' 'unsafe-inline' 'unsafe-eval'"},
+				"Strict-Transport-Security": {"max-age=3600"},
+				"X-Frame-Options":           {"SAMEORIGIN"},
+				"Referrer-Policy":           {"unsafe-url"},
+				"Server":                    {"Apache/2.4.41"},
+				"X-Powered-By":              {"PHP/7.4.3"},
+			},
+			https:  true,
+			score:  40,
+			grade:  "D",
+			issues: 8,
+		},
+	}
+	for _, tt := range tests {
+		analysis, issues := Analyze(tt.header, "www.example.com", tt.https)
+		if analysis.Score != tt.score || analysis.Grade != tt.grade {
+			t.Errorf("%s: got %d (%s), want %d (%s)", tt.name, analysis.Score, analysis.Grade, tt.score, tt.grade)
+		}
+		if len(issues) != tt.issues {
+			t.Errorf("%s: got %d issues, want %d: %v", tt.name, len(issues), tt.issues, analysis.Issues)
+		}
+	}
+}
+
+func TestThirdPartySources(t *testing.T) {
+	csp := ParseCSP("script-src 'self' https://cdn.example.com *.google-analytics.com https://www.googletagmanager.com/gtm.js; img-src * data: https://www.google-analytics.com")
+	want := []string{"*.google-analytics.com", "www.google-analytics.com", "www.googletagmanager.com"}
+	if got := ThirdPartySources(csp, "www.example.com"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestWhitelistedTrackers(t *testing.T) {
+	vendors := map[string][]string{
+		"Google Analytics": {"google-analytics.com", "googletagmanager.com"},
+		"Hotjar":           {"static.hotjar.com"},
+	}
+	got := WhitelistedTrackers([]string{"*.google-analytics.com", "cdn.jsdelivr.net", "www.googletagmanager.com"}, vendors)
+	want := map[string]string{
+		"*.google-analytics.com":   "Google Analytics",
+		"www.googletagmanager.com": "Google Analytics",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,372 @@
+package iframes
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Pattern types reported for iframes that are not visible to the user
+const (
+	KindHidden    = "Hidden Iframe"
+	KindOffscreen = "Off-screen Iframe"
+	KindZeroSize  = "Zero Size Iframe"
+)
+
+// Kinds lists the pattern types of hidden iframe findings
+var Kinds = []string{KindHidden, KindOffscreen, KindZeroSize}
+
+// kindRank orders kinds from weakest to strongest evidence of hiding
+var kindRank = map[string]int{KindZeroSize: 1, KindOffscreen: 2, KindHidden: 3}
+
+// Iframe is an iframe element with its effective visibility
+type Iframe struct {
+	Line    int
+	Offset  int    // byte offset of the start tag
+	Tag     string // raw start tag
+	Src     string
+	Domain  string // src host; empty for relative sources
+	Sandbox string
+	Allow   string
+	Width   string
+	Height  string
+	Kind    string // empty when the iframe is visible
+	Reasons []string
+}
+
+// Hidden reports whether the iframe is not visible to the user
+func (f Iframe) Hidden() bool {
+	return f.Kind != ""
+}
+
+// element is a start tag, kept to evaluate stylesheet rules against
+type element struct {
+	tag   string
+	attrs map[string]string
+}
+
+// offscreenOffset is the negative offset beyond which an element is
+// treated as positioned off-screen
+const offscreenOffset = -500
+
+var (
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssRule    = regexp.MustCompile(`([^{}]+)\{([^{}]*)\}`)
+	compound   = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[.#][\w-]+|\[[^\]]+\])*)$`)
+	simplePart = regexp.MustCompile(`[.#][\w-]+|\[[^\]]+\]`)
+	attrSel    = regexp.MustCompile(`^\[\s*([\w-]+)\s*(?:([~^$*|]?=)\s*["']?([^"'\]]*)["']?)?\s*\]$`)
+	cssLength  = regexp.MustCompile(`^(-?\d+(?:\.\d+)?)(px)?$`)
+)
+
+// voidElements never have an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Analyze finds the iframes in a page and computes whether each is
+// visible, from its attributes and inline style, stylesheet rules in
+// <style> elements, and hidden ancestors
+func Analyze(content string) []Iframe {
+	type pending struct {
+		iframe    Iframe
+		self      element
+		ancestors []element
+	}
+
+	var found []pending
+	var stack []element
+	var css strings.Builder
+	inStyle := false
+
+	z := html.NewTokenizer(strings.NewReader(content))
+	line, offset := 1, 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		startLine, startOffset := line, offset
+		line += bytes.Count(raw, []byte("\n"))
+		offset += len(raw)
+
+		switch tt {
+		case html.TextToken:
+			if inStyle {
+				css.Write(raw)
+				css.WriteByte('\n')
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			el := element{tag: string(name), attrs: make(map[string]string)}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				el.attrs[string(key)] = string(val)
+			}
+			if el.tag == "iframe" {
+				f := Iframe{
+					Line:    startLine,
+					Offset:  startOffset,
+					Tag:     string(raw),
+					Src:     el.attrs["src"],
+					Sandbox: el.attrs["sandbox"],
+					Allow:   el.attrs["allow"],
+					Width:   el.attrs["width"],
+					Height:  el.attrs["height"],
+				}
+				if u, err := url.Parse(strings.TrimSpace(f.Src)); err == nil {
+					f.Domain = strings.ToLower(u.Hostname())
+				}
+				found = append(found, pending{f, el, append([]element(nil), stack...)})
+			}
+			inStyle = el.tag == "style" && tt == html.StartTagToken
+			if tt == html.StartTagToken && !voidElements[el.tag] {
+				stack = append(stack, el)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			inStyle = false
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].tag == string(name) {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+
+	rules := parseStylesheet(css.String())
+	iframes := make([]Iframe, 0, len(found))
+	for _, p := range found {
+		f := p.iframe
+		evaluate(&f, p.self, rules, false)
+		for _, a := range p.ancestors {
+			evaluate(&f, a, rules, true)
+		}
+		iframes = append(iframes, f)
+	}
+	return iframes
+}
+
+// evaluate adds the reasons an element hides the iframe. Size does not
+// carry over from ancestors, as overflow usually leaves the iframe visible.
+func evaluate(f *Iframe, el element, rules []rule, ancestor bool) {
+	where := ""
+	if ancestor {
+		where = " on ancestor <" + el.tag + ">"
+	}
+	report := func(kind, reason string) {
+		if ancestor && kind == KindZeroSize {
+			return
+		}
+		f.Reasons = append(f.Reasons, reason+where)
+		if kindRank[kind] > kindRank[f.Kind] {
+			f.Kind = kind
+		}
+	}
+
+	if _, ok := el.attrs["hidden"]; ok {
+		report(KindHidden, "hidden attribute")
+	}
+	if !ancestor {
+		for _, dim := range []string{"width", "height"} {
+			if v, ok := el.attrs[dim]; ok && tiny(v) {
+				report(KindZeroSize, dim+"="+strings.TrimSpace(v))
+			}
+		}
+	}
+	for _, h := range hiding(parseDeclarations(el.attrs["style"])) {
+		report(h.kind, h.reason+" (inline style)")
+	}
+	for _, r := range rules {
+		if !r.matches(el) {
+			continue
+		}
+		for _, h := range hiding(r.declarations) {
+			report(h.kind, h.reason+" ("+r.selector+" rule)")
+		}
+	}
+}
+
+type hide struct {
+	kind, reason string
+}
+
+// hiding returns the ways a set of CSS declarations hides an element
+func hiding(decls map[string]string) []hide {
+	var found []hide
+	if decls["display"] == "none" {
+		found = append(found, hide{KindHidden, "display:none"})
+	}
+	if v := decls["visibility"]; v == "hidden" || v == "collapse" {
+		found = append(found, hide{KindHidden, "visibility:" + v})
+	}
+	if v, ok := decls["opacity"]; ok {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n <= 0.01 {
+			found = append(found, hide{KindHidden, "opacity:" + v})
+		}
+	}
+	if strings.HasPrefix(decls["clip"], "rect(0") {
+		found = append(found, hide{KindHidden, "clip:" + decls["clip"]})
+	}
+	for _, side := range []string{"left", "top", "margin-left", "margin-top"} {
+		if n, ok := length(decls[side]); ok && n <= offscreenOffset {
+			found = append(found, hide{KindOffscreen, side + ":" + decls[side]})
+		}
+	}
+	for _, dim := range []string{"width", "height"} {
+		if v, ok := decls[dim]; ok && tiny(v) {
+			found = append(found, hide{KindZeroSize, dim + ":" + v})
+		}
+	}
+	return found
+}
+
+// tiny reports whether a size is 0 or 1 pixel
+func tiny(v string) bool {
+	n, ok := length(v)
+	return ok && n >= 0 && n <= 1
+}
+
+func length(v string) (float64, bool) {
+	m := cssLength.FindStringSubmatch(strings.TrimSpace(strings.ToLower(v)))
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	return n, err == nil
+}
+
+// parseDeclarations parses "prop: value; ..." into lowercased properties
+func parseDeclarations(style string) map[string]string {
+	decls := make(map[string]string)
+	for _, decl := range strings.Split(style, ";") {
+		parts := strings.SplitN(decl, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		prop := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.ToLower(strings.TrimSpace(parts[1]))
+		value = strings.TrimSpace(strings.TrimSuffix(value, "!important"))
+		if prop != "" {
+			decls[prop] = value
+		}
+	}
+	return decls
+}
+
+// rule is a stylesheet rule with a single selector
+type rule struct {
+	selector     string
+	tag          string
+	parts        []string
+	declarations map[string]string
+}
+
+// parseStylesheet reads the rules of a stylesheet. Only the last compound
+// selector is kept, so descendant and child combinators match more widely
+// than a browser would; selectors with pseudo-classes are skipped as they
+// apply conditionally.
+func parseStylesheet(css string) []rule {
+	var rules []rule
+	css = cssComment.ReplaceAllString(css, "")
+	for _, m := range cssRule.FindAllStringSubmatch(css, -1) {
+		decls := parseDeclarations(m[2])
+		if len(hiding(decls)) == 0 {
+			continue
+		}
+		for _, selector := range strings.Split(m[1], ",") {
+			selector = strings.TrimSpace(selector)
+			if selector == "" || strings.HasPrefix(selector, "@") || strings.Contains(selector, ":") {
+				continue
+			}
+			fields := strings.FieldsFunc(selector, func(r rune) bool {
+				return r == ' ' || r == '\t' || r == '\n' || r == '>' || r == '+' || r == '~'
+			})
+			if len(fields) == 0 {
+				continue
+			}
+			last := fields[len(fields)-1]
+			c := compound.FindStringSubmatch(last)
+			if c == nil {
+				continue
+			}
+			rules = append(rules, rule{
+				selector:     selector,
+				tag:          strings.ToLower(c[1]),
+				parts:        simplePart.FindAllString(c[2], -1),
+				declarations: decls,
+			})
+		}
+	}
+	return rules
+}
+
+func (r rule) matches(el element) bool {
+	if r.tag != "" && r.tag != "*" && r.tag != el.tag {
+		return false
+	}
+	if r.tag == "" && len(r.parts) == 0 {
+		return false
+	}
+	for _, part := range r.parts {
+		switch part[0] {
+		case '.':
+			if !hasClass(el.attrs["class"], part[1:]) {
+				return false
+			}
+		case '#':
+			if el.attrs["id"] != part[1:] {
+				return false
+			}
+		case '[':
+			if !matchAttr(el, part) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasClass(classes, name string) bool {
+	for _, c := range strings.Fields(classes) {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAttr(el element, selector string) bool {
+	m := attrSel.FindStringSubmatch(selector)
+	if m == nil {
+		return false
+	}
+	v, ok := el.attrs[strings.ToLower(m[1])]
+	if !ok {
+		return false
+	}
+	switch m[2] {
+	case "":
+		return true
+	case "=":
+		return v == m[3]
+	case "^=":
+		return strings.HasPrefix(v, m[3])
+	case "$=":
+		return strings.HasSuffix(v, m[3])
+	case "*=":
+		return strings.Contains(v, m[3])
+	case "~=":
+		return hasClass(v, m[3])
+	case "|=":
+		return v == m[3] || strings.HasPrefix(v, m[3]+"-")
+	}
+	return false
+}
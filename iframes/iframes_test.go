@@ -0,0 +1,63 @@
+package iframes
+
+import (
+	"strings"
+	"testing"
+)
+
+const page = `<html><head><style>
+.tracker-frame { position: absolute; left: -9999px; }
+#sync { visibility : hidden !important }
+@media screen { iframe[data-sync] { display:none } }
+a:hover { display:none }
+</style></head><body>
+<iframe src=https://www.googletagmanager.com/ns.html?id=GTM-ABC height=0 width=0 style=display:none></iframe>
+<iframe class="ad tracker-frame" src="https://ads.example.net/x" sandbox="allow-scripts" allow="camera"></iframe>
+<iframe id=sync src="https://sync.example.org/"></iframe>
+<iframe data-sync src="/local"></iframe>
+<div hidden><iframe src="https://inner.example.com/"></iframe></div>
+<iframe src="https://www.youtube.com/embed/x" width="560" height="315"></iframe>
+<iframe style="width: 1px; height: 1PX" src="https://pixel.example.com/"></iframe>
+<a href="#"><iframe src="https://visible.example.com/"></iframe></a>
+</body></html>`
+
+func TestAnalyze(t *testing.T) {
+	want := []struct {
+		line   int
+		domain string
+		kind   string
+		reason string
+	}{
+		{7, "www.googletagmanager.com", KindHidden, "display:none (inline style)"},
+		{8, "ads.example.net", KindOffscreen, "left:-9999px (.tracker-frame rule)"},
+		{9, "sync.example.org", KindHidden, "visibility:hidden (#sync rule)"},
+		{10, "", KindHidden, "display:none (iframe[data-sync] rule)"},
+		{11, "inner.example.com", KindHidden, "hidden attribute on ancestor <div>"},
+		{12, "www.youtube.com", "", ""},
+		{13, "pixel.example.com", KindZeroSize, "width:1px (inline style)"},
+		{14, "visible.example.com", "", ""},
+	}
+
+	found := Analyze(page)
+	if len(found) != len(want) {
+		t.Fatalf("Expected %d iframes, got %d", len(want), len(found))
+	}
+	for i, w := range want {
+		f := found[i]
+		if f.Line != w.line || f.Domain != w.domain || f.Kind != w.kind {
+			t.Errorf("Iframe %d: got line %d, domain %q, kind %q; want %d, %q, %q", i, f.Line, f.Domain, f.Kind, w.line, w.domain, w.kind)
+		}
+		if !strings.HasPrefix(page[f.Offset:], f.Tag) {
+			t.Errorf("Iframe %d: offset %d is not at its tag", i, f.Offset)
+		}
+		if w.reason != "" && !strings.Contains(strings.Join(f.Reasons, "; "), w.reason) {
+			t.Errorf("Iframe %d: reasons %v do not include %q", i, f.Reasons, w.reason)
+		}
+	}
+	if found[1].Sandbox != "allow-scripts" || found[1].Allow != "camera" {
+		t.Errorf("Expected sandbox and allow attributes, got %q and %q", found[1].Sandbox, found[1].Allow)
+	}
+	if found[0].Width != "0" || found[0].Height != "0" {
+		t.Errorf("Expected unquoted zero dimensions, got %qx%q", found[0].Width, found[0].Height)
+	}
+}
@@ -0,0 +1,325 @@
+package input
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Input formats
+const (
+	FormatText      = "text"      // a URL, domain or path per line
+	FormatJSONL     = "jsonl"     // JSON objects with a URL field; other fields are tags
+	FormatCSV       = "csv"       // a header row and a URL column; other columns are tags
+	FormatHttpx     = "httpx"     // httpx -json output
+	FormatSubfinder = "subfinder" // subfinder output, as host lines or -json
+	FormatNmap      = "nmap"      // nmap -oX output; open HTTP services are scanned
+)
+
+// Formats lists the supported input formats
+var Formats = []string{FormatText, FormatJSONL, FormatCSV, FormatHttpx, FormatSubfinder, FormatNmap}
+
+// DefaultColumn is the URL field of JSON Lines and CSV input
+const DefaultColumn = "url"
+
+// httpxTags are the httpx fields kept as tags; the rest describe the
+// response Spectre fetches again
+var httpxTags = []string{"input", "host", "port", "title", "status_code", "webserver", "tech", "cdn_name"}
+
+// Record is one input read from a file: a URL, domain or path, and the
+// tags to keep with its findings
+type Record struct {
+	Input string
+	Tags  map[string]string
+}
+
+// Read reads the records of an input in format, calling fn for each.
+// column names the URL field of JSON Lines and CSV input. A malformed
+// record stops the read with an error naming its line.
+func Read(r io.Reader, format, column string, fn func(Record)) error {
+	if column == "" {
+		column = DefaultColumn
+	}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return readLines(r, func(line string) error {
+			fn(Record{Input: line})
+			return nil
+		})
+	case FormatJSONL:
+		return readJSONL(r, func(fields map[string]interface{}) error {
+			input, ok := fields[column].(string)
+			if !ok || input == "" {
+				return fmt.Errorf("no %q field", column)
+			}
+			delete(fields, column)
+			fn(Record{Input: input, Tags: tags(fields)})
+			return nil
+		})
+	case FormatCSV:
+		return readCSV(r, column, fn)
+	case FormatHttpx:
+		return readJSONL(r, func(fields map[string]interface{}) error {
+			input, ok := fields["url"].(string)
+			if !ok || input == "" {
+				return fmt.Errorf("no \"url\" field")
+			}
+			kept := map[string]interface{}{"tool": FormatHttpx}
+			for _, name := range httpxTags {
+				if v, ok := fields[name]; ok {
+					kept[name] = v
+				}
+			}
+			fn(Record{Input: input, Tags: tags(kept)})
+			return nil
+		})
+	case FormatSubfinder:
+		return readLines(r, func(line string) error {
+			if !strings.HasPrefix(line, "{") {
+				fn(Record{Input: line, Tags: map[string]string{"tool": FormatSubfinder}})
+				return nil
+			}
+			var found struct {
+				Host   string `json:"host"`
+				Input  string `json:"input"`
+				Source string `json:"source"`
+			}
+			if err := json.Unmarshal([]byte(line), &found); err != nil {
+				return err
+			}
+			if found.Host == "" {
+				return fmt.Errorf("no \"host\" field")
+			}
+			fn(Record{Input: found.Host, Tags: tags(map[string]interface{}{"tool": FormatSubfinder, "domain": found.Input, "source": found.Source})})
+			return nil
+		})
+	case FormatNmap:
+		return readNmap(r, fn)
+	}
+	return CheckFormat(format)
+}
+
+// CheckFormat returns an error for an unknown input format
+func CheckFormat(format string) error {
+	for _, known := range Formats {
+		if strings.EqualFold(format, known) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown input format %q, use one of: %s", format, strings.Join(Formats, ", "))
+}
+
+// readLines calls fn for each non-blank line, numbering errors by line
+func readLines(r io.Reader, fn func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// readJSONL decodes each non-blank line as a JSON object
+func readJSONL(r io.Reader, fn func(map[string]interface{}) error) error {
+	return readLines(r, func(line string) error {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return err
+		}
+		return fn(fields)
+	})
+}
+
+// tags turns JSON fields into tag strings. Lists of strings are joined with
+// commas, other structured values are kept as JSON, and empty values are
+// dropped.
+func tags(fields map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+	for k, v := range fields {
+		var s string
+		switch v := v.(type) {
+		case nil:
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		case []interface{}:
+			s = joinStrings(v)
+		default:
+			data, _ := json.Marshal(v)
+			s = string(data)
+		}
+		if s != "" {
+			out[k] = s
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// joinStrings joins a list of strings with commas, or returns it as JSON
+// if it holds anything else
+func joinStrings(list []interface{}) string {
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			data, _ := json.Marshal(list)
+			return string(data)
+		}
+		items = append(items, s)
+	}
+	return strings.Join(items, ",")
+}
+
+// readCSV reads a CSV file with a header row, taking the input from column
+// and the other non-empty cells as tags named by their header
+func readCSV(r io.Reader, column string, fn func(Record)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	index := -1
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		header[i] = name
+		if strings.EqualFold(name, column) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("no %q column in header %s", column, strings.Join(header, ","))
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if index >= len(row) || strings.TrimSpace(row[index]) == "" {
+			continue
+		}
+		record := Record{Input: strings.TrimSpace(row[index])}
+		for i, cell := range row {
+			if i == index || i >= len(header) || strings.TrimSpace(cell) == "" {
+				continue
+			}
+			if record.Tags == nil {
+				record.Tags = make(map[string]string)
+			}
+			record.Tags[header[i]] = strings.TrimSpace(cell)
+		}
+		fn(record)
+	}
+}
+
+// nmapRun is the part of nmap's XML output naming HTTP services
+type nmapRun struct {
+	Hosts []struct {
+		Addresses []struct {
+			Addr string `xml:"addr,attr"`
+			Type string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			ID       int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name    string `xml:"name,attr"`
+				Product string `xml:"product,attr"`
+				Version string `xml:"version,attr"`
+				Tunnel  string `xml:"tunnel,attr"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// readNmap reads the open HTTP and HTTPS services of nmap XML output. A
+// host is addressed by the name it was scanned as, else its first name,
+// else its IP address.
+func readNmap(r io.Reader, fn func(Record)) error {
+	var run nmapRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return err
+	}
+	for _, host := range run.Hosts {
+		var ip string
+		for _, addr := range host.Addresses {
+			if addr.Type == "ipv4" || addr.Type == "ipv6" {
+				ip = addr.Addr
+				break
+			}
+		}
+		name := ip
+		sort.SliceStable(host.Hostnames, func(i, j int) bool {
+			return host.Hostnames[i].Type == "user" && host.Hostnames[j].Type != "user"
+		})
+		if len(host.Hostnames) > 0 {
+			name = host.Hostnames[0].Name
+		}
+		if name == "" {
+			continue
+		}
+
+		for _, port := range host.Ports {
+			service := port.Service.Name
+			if port.Protocol != "tcp" || port.State.State != "open" || !strings.Contains(service, "http") {
+				continue
+			}
+			scheme := "http"
+			if strings.HasPrefix(service, "https") || port.Service.Tunnel == "ssl" {
+				scheme = "https"
+			}
+			address := net.JoinHostPort(name, strconv.Itoa(port.ID))
+			if scheme == "http" && port.ID == 80 || scheme == "https" && port.ID == 443 {
+				address = name
+				if strings.Contains(name, ":") {
+					address = "[" + name + "]"
+				}
+			}
+			fn(Record{
+				Input: scheme + "://" + address,
+				Tags: tags(map[string]interface{}{
+					"tool":    FormatNmap,
+					"ip":      ip,
+					"port":    strconv.Itoa(port.ID),
+					"service": service,
+					"product": strings.TrimSpace(port.Service.Product + " " + port.Service.Version),
+				}),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package input normalizes the URLs, bare domains and local paths given on
+// the command line or stdin into scan targets, and reads them from the
+// output of other recon tools.
+package input
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Target is a normalized scan input
+type Target struct {
+	URL string // URL, or local path when Local is set
+	// Fallback is the plain HTTP URL tried when URL cannot be fetched, for
+	// inputs given without a scheme
+	Fallback string
+	Local    bool
+	Tags     map[string]string // from structured input, kept with the findings
+}
+
+// Normalizer turns input lines into targets, dropping those already seen.
+// It is not safe for concurrent use.
+type Normalizer struct {
+	// WWW expands an apex domain, or its www. name, into both
+	WWW  bool
+	seen map[string]bool
+}
+
+// Normalize returns the targets of an input line. Blank lines, comments
+// and inputs already seen have none. A line without a scheme is a local
+// path when it exists or looks like one, and otherwise a domain tried over
+// HTTPS first.
+func (n *Normalizer) Normalize(line string) ([]Target, error) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+	if i := strings.Index(line, "#"); i == 0 || i > 0 && (line[i-1] == ' ' || line[i-1] == '\t') {
+		line = strings.TrimSpace(line[:i])
+	}
+	if line == "" {
+		return nil, nil
+	}
+
+	var targets []Target
+	var err error
+	switch {
+	case strings.HasPrefix(strings.ToLower(line), "file://"):
+		targets = []Target{{URL: line}}
+	case strings.Contains(line, "://"):
+		targets, err = URLs(line, n.WWW)
+	case isPath(line):
+		targets = []Target{{URL: line, Local: true}}
+	default:
+		targets, err = Domain(line, n.WWW)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if n.seen == nil {
+		n.seen = make(map[string]bool)
+	}
+	var fresh []Target
+	for _, t := range targets {
+		key := dedupeKey(t.URL)
+		if !n.seen[key] {
+			n.seen[key] = true
+			fresh = append(fresh, t)
+		}
+	}
+	return fresh, nil
+}
+
+// URLs normalizes an HTTP or HTTPS URL, expanding its host into the apex
+// and www. names when www is set
+func URLs(raw string, www bool) ([]Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	hosts, err := hosts(u, www)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	for _, host := range hosts {
+		u.Host = host
+		targets = append(targets, Target{URL: u.String()})
+	}
+	return targets, nil
+}
+
+// Domain turns a bare domain, which may carry a port and path, into HTTPS
+// targets that fall back to plain HTTP
+func Domain(domain string, www bool) ([]Target, error) {
+	targets, err := URLs("https://"+domain, www)
+	if err != nil {
+		return nil, err
+	}
+	for i := range targets {
+		targets[i].Fallback = "http://" + strings.TrimPrefix(targets[i].URL, "https://")
+	}
+	return targets, nil
+}
+
+// Host lowercases a host name and converts internationalized names to
+// their punycode form. IP addresses are returned as they are.
+func Host(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %v", host, err)
+	}
+	return ascii, nil
+}
+
+// hosts returns the normalized host of u with its port, expanded into the
+// apex and www. names when www is set and the host is one of them
+func hosts(u *url.URL, www bool) ([]string, error) {
+	host, err := Host(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	names := []string{host}
+	if www && net.ParseIP(host) == nil {
+		apex := strings.TrimPrefix(host, "www.")
+		if registered, err := publicsuffix.EffectiveTLDPlusOne(apex); err == nil && registered == apex {
+			names = []string{apex, "www." + apex}
+		}
+	}
+
+	port := u.Port()
+	for i, name := range names {
+		switch {
+		case port != "":
+			names[i] = net.JoinHostPort(name, port)
+		case strings.Contains(name, ":"):
+			names[i] = "[" + name + "]"
+		}
+	}
+	return names, nil
+}
+
+// isPath reports whether a line without a scheme names a local file
+// rather than a domain
+func isPath(line string) bool {
+	if _, err := os.Stat(line); err == nil {
+		return true
+	}
+	if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "/") || strings.HasPrefix(line, "~") || strings.Contains(line, `\`) {
+		return true
+	}
+	// A domain has a dot in its host, unlike most relative paths
+	host := line
+	if i := strings.IndexAny(host, "/?"); i >= 0 {
+		host = host[:i]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return !strings.Contains(host, ".") && host != "localhost"
+}
+
+// dedupeKey identifies a target URL, treating an empty path as /
+func dedupeKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		return raw
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
@@ -0,0 +1,216 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		www   bool
+		lines []string
+		want  []Target
+	}{
+		{
+			name:  "blanks and comments",
+			lines: []string{"", "   ", "# targets", "\t# indented"},
+		},
+		{
+			name:  "bare domain",
+			lines: []string{"  Example.COM  # apex"},
+			want:  []Target{{URL: "https://example.com", Fallback: "http://example.com"}},
+		},
+		{
+			name:  "port and path",
+			lines: []string{"example.com:8443/app?x=1#top"},
+			want:  []Target{{URL: "https://example.com:8443/app?x=1#top", Fallback: "http://example.com:8443/app?x=1#top"}},
+		},
+		{
+			name:  "scheme kept",
+			lines: []string{"HTTP://Example.com/"},
+			want:  []Target{{URL: "http://example.com/"}},
+		},
+		{
+			name:  "duplicates",
+			lines: []string{"example.com", "https://example.com/", "example.com.", "http://example.com"},
+			want:  []Target{{URL: "https://example.com", Fallback: "http://example.com"}, {URL: "http://example.com"}},
+		},
+		{
+			name:  "idn",
+			lines: []string{"bücher.de", "https://xn--bcher-kva.de"},
+			want:  []Target{{URL: "https://xn--bcher-kva.de", Fallback: "http://xn--bcher-kva.de"}},
+		},
+		{
+			name:  "ip addresses",
+			lines: []string{"127.0.0.1:8080", "http://[::1]/"},
+			want:  []Target{{URL: "https://127.0.0.1:8080", Fallback: "http://127.0.0.1:8080"}, {URL: "http://[::1]/"}},
+		},
+		{
+			name:  "www expansion",
+			www:   true,
+			lines: []string{"www.example.co.uk", "example.co.uk", "https://blog.example.com", "10.0.0.1"},
+			want: []Target{
+				{URL: "https://example.co.uk", Fallback: "http://example.co.uk"},
+				{URL: "https://www.example.co.uk", Fallback: "http://www.example.co.uk"},
+				{URL: "https://blog.example.com"},
+				{URL: "https://10.0.0.1", Fallback: "http://10.0.0.1"},
+			},
+		},
+		{
+			name:  "local paths",
+			lines: []string{file, "./dist", "src", "file:///tmp/a.html"},
+			want:  []Target{{URL: file, Local: true}, {URL: "./dist", Local: true}, {URL: "src", Local: true}, {URL: "file:///tmp/a.html"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Normalizer{WWW: tt.www}
+			var got []Target
+			for _, line := range tt.lines {
+				targets, err := n.Normalize(line)
+				if err != nil {
+					t.Fatalf("Normalize(%q): %v", line, err)
+				}
+				got = append(got, targets...)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("target %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	for _, line := range []string{"ftp://example.com", "https://bad host", "https:///path", "exa mple.com", "xn--a.com"} {
+		n := &Normalizer{}
+		if targets, err := n.Normalize(line); err == nil {
+			t.Errorf("Normalize(%q) = %+v, want an error", line, targets)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	nmap := `<?xml version="1.0"?>
+<nmaprun>
+  <host>
+    <address addr="203.0.113.7" addrtype="ipv4"/>
+    <address addr="00:11:22:33:44:55" addrtype="mac"/>
+    <hostnames><hostname name="ptr.example.net" type="PTR"/><hostname name="shop.example.com" type="user"/></hostnames>
+    <ports>
+      <port protocol="tcp" portid="443"><state state="open"/><service name="http" product="nginx" version="1.25" tunnel="ssl"/></port>
+      <port protocol="tcp" portid="8080"><state state="open"/><service name="http-proxy"/></port>
+      <port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+      <port protocol="tcp" portid="80"><state state="closed"/><service name="http"/></port>
+    </ports>
+  </host>
+  <host>
+    <address addr="2001:db8::1" addrtype="ipv6"/>
+    <ports><port protocol="tcp" portid="80"><state state="open"/><service name="http"/></port></ports>
+  </host>
+</nmaprun>`
+
+	tests := []struct {
+		format, column, in string
+		want               []Record
+	}{
+		{
+			format: "text",
+			in:     "example.com\n\n# comment\n",
+			want:   []Record{{Input: "example.com"}, {Input: "# comment"}},
+		},
+		{
+			format: "jsonl",
+			in:     `{"url":"https://example.com","asset_id":"A-1","owner":null,"prod":true,"ports":[80,443]}` + "\n\n" + `{"url":"b.example.com","rank":12}`,
+			want: []Record{
+				{Input: "https://example.com", Tags: map[string]string{"asset_id": "A-1", "prod": "true", "ports": "[80,443]"}},
+				{Input: "b.example.com", Tags: map[string]string{"rank": "12"}},
+			},
+		},
+		{
+			format: "jsonl",
+			column: "target",
+			in:     `{"target":"example.com"}`,
+			want:   []Record{{Input: "example.com"}},
+		},
+		{
+			format: "csv",
+			column: "Website",
+			in:     "\ufeffAsset,WEBSITE,Owner\nA-1, example.com ,web team\nA-2,,\nA-3,shop.example.com\n",
+			want: []Record{
+				{Input: "example.com", Tags: map[string]string{"Asset": "A-1", "Owner": "web team"}},
+				{Input: "shop.example.com", Tags: map[string]string{"Asset": "A-3"}},
+			},
+		},
+		{
+			format: "httpx",
+			in:     `{"url":"https://example.com:8443","input":"example.com","status_code":200,"title":"Home","tech":["Nginx","PHP"],"body":"<html>","hash":{"body_md5":"x"}}`,
+			want: []Record{{Input: "https://example.com:8443", Tags: map[string]string{
+				"tool": "httpx", "input": "example.com", "status_code": "200", "title": "Home", "tech": "Nginx,PHP",
+			}}},
+		},
+		{
+			format: "subfinder",
+			in:     "a.example.com\n" + `{"host":"b.example.com","input":"example.com","source":"crtsh"}`,
+			want: []Record{
+				{Input: "a.example.com", Tags: map[string]string{"tool": "subfinder"}},
+				{Input: "b.example.com", Tags: map[string]string{"tool": "subfinder", "domain": "example.com", "source": "crtsh"}},
+			},
+		},
+		{
+			format: "nmap",
+			in:     nmap,
+			want: []Record{
+				{Input: "https://shop.example.com", Tags: map[string]string{"tool": "nmap", "ip": "203.0.113.7", "port": "443", "service": "http", "product": "nginx 1.25"}},
+				{Input: "http://shop.example.com:8080", Tags: map[string]string{"tool": "nmap", "ip": "203.0.113.7", "port": "8080", "service": "http-proxy"}},
+				{Input: "http://[2001:db8::1]", Tags: map[string]string{"tool": "nmap", "ip": "2001:db8::1", "port": "80", "service": "http"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got []Record
+			err := Read(strings.NewReader(tt.in), tt.format, tt.column, func(r Record) {
+				got = append(got, r)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		format, in, want string
+	}{
+		{"jsonl", "{\"url\":\"a.com\"}\nnot json", "line 2"},
+		{"jsonl", `{"host":"a.com"}`, `no "url" field`},
+		{"csv", "host,owner\na.com,x", `no "url" column`},
+		{"nmap", "<nmaprun><host>", "EOF"},
+		{"xml", "", "unknown input format"},
+	}
+	for _, tt := range tests {
+		err := Read(strings.NewReader(tt.in), tt.format, "", func(Record) {})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Read(%s, %q): got error %v, want %q", tt.format, tt.in, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package local
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Separator joins an archive path and the path of an entry inside it, as in
+// bundle.zip!/static/app.js
+const Separator = "!/"
+
+// sniffSize is how much of a file is checked for NUL bytes, as git does
+const sniffSize = 8000
+
+// MaxNestedSize bounds archives inside archives, which are read into
+// memory as zip needs random access
+const MaxNestedSize = 64 << 20
+
+// Filter selects files by glob. Patterns without a slash match the base
+// name; others match the path relative to the scanned root or archive, with
+// ** matching any number of directories. Archives are opened whatever the
+// include patterns so their entries can be matched.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// Excluded reports whether a path or directory is excluded
+func (f Filter) Excluded(rel string) bool {
+	return matchAny(f.Exclude, rel)
+}
+
+// Included reports whether a file should be scanned
+func (f Filter) Included(rel string) bool {
+	if f.Excluded(rel) {
+		return false
+	}
+	return len(f.Include) == 0 || IsArchive(rel) || matchAny(f.Include, rel)
+}
+
+// Walk calls fn for every file under root that the filter includes,
+// skipping excluded directories. A root that is a file is passed to fn as is.
+func Walk(root string, f Filter, fn func(path string) error) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fn(root)
+	}
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if rel != "." && f.Excluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || !f.Included(rel) {
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+// Open calls fn with the contents of a file, or of each included entry
+// when the file is a zip, jar or tarball. Entries are named
+// archive!/inner/path, and archives nested in archives are opened in turn.
+// Binary files and entries are skipped.
+func Open(name string, f Filter, fn func(name string, r io.Reader) error) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if isZip(name) {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return openZip(name, file, info.Size(), f, fn)
+	}
+	return open(name, file, f, fn)
+}
+
+// open scans r as an archive or a single file
+func open(name string, r io.Reader, f Filter, fn func(string, io.Reader) error) error {
+	switch {
+	case isZip(name):
+		data, err := io.ReadAll(io.LimitReader(r, MaxNestedSize+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > MaxNestedSize {
+			return fmt.Errorf("%s: nested archive larger than %d MB", name, MaxNestedSize>>20)
+		}
+		return openZip(name, bytes.NewReader(data), int64(len(data)), f, fn)
+	case isTar(name):
+		if strings.HasSuffix(strings.ToLower(name), ".tar") {
+			return openTar(name, r, f, fn)
+		}
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		defer gz.Close()
+		return openTar(name, gz, f, fn)
+	}
+
+	br := bufio.NewReaderSize(r, sniffSize)
+	if Binary(br) {
+		return nil
+	}
+	return fn(name, br)
+}
+
+func openZip(name string, r io.ReaderAt, size int64, f Filter, fn func(string, io.Reader) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() || !f.Included(entry.Name) {
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("%s%s%s: %v", name, Separator, entry.Name, err)
+		}
+		err = open(name+Separator+entry.Name, rc, f, fn)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openTar(name string, r io.Reader, f Filter, fn func(string, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		inner := strings.TrimPrefix(hdr.Name, "./")
+		if hdr.Typeflag != tar.TypeReg || !f.Included(inner) {
+			continue
+		}
+		if err := open(name+Separator+inner, tr, f, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// IsArchive reports whether a file name is a zip, jar, war or tarball
+func IsArchive(name string) bool {
+	return isZip(name) || isTar(name)
+}
+
+func isZip(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".zip", ".jar", ".war":
+		return true
+	}
+	return false
+}
+
+func isTar(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".tar")
+}
+
+// Binary reports whether the start of r contains a NUL byte. Text in any
+// ASCII-compatible encoding never does.
+func Binary(r *bufio.Reader) bool {
+	head, _ := r.Peek(sniffSize)
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if Match(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether a slash-separated path matches a glob
+func Match(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,160 @@
+package local
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.js", "static/js/app.js", true},
+		{"*.js", "static/js/app.js.map", false},
+		{"node_modules", "web/node_modules", true},
+		{"static/*.js", "static/app.js", true},
+		{"static/*.js", "static/js/app.js", false},
+		{"static/**/*.js", "static/js/vendor/app.js", true},
+		{"static/**/*.js", "static/app.js", true},
+		{"**/vendor", "a/b/vendor", true},
+		{"**/vendor", "a/b/vendors", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := Filter{Include: []string{"*.js", "*.html"}, Exclude: []string{"*.min.js"}}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"app.js", true},
+		{"index.html", true},
+		{"style.css", false},
+		{"lib.min.js", false},
+		{"bundle.zip", true},
+		{"site.tar.gz", true},
+	}
+	for _, tt := range tests {
+		if got := f.Included(tt.path); got != tt.want {
+			t.Errorf("Included(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+
+	// A jar inside a tarball inside a zip, alongside text and binary files
+	var jar bytes.Buffer
+	zw := zip.NewWriter(&jar)
+	addZip(t, zw, "META-INF/app.js", "var id = 'UA-1234567-1';\n")
+	zw.Close()
+
+	var tgz bytes.Buffer
+	gz := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gz)
+	addTar(t, tw, "./static/index.html", "<html></html>\n")
+	addTar(t, tw, "./static/logo.png", "\x89PNG\r\n\x1a\n\x00\x00")
+	addTar(t, tw, "./lib/app.jar", jar.String())
+	tw.Close()
+	gz.Close()
+
+	var outer bytes.Buffer
+	zw = zip.NewWriter(&outer)
+	addZip(t, zw, "site.tar.gz", tgz.String())
+	addZip(t, zw, "notes.txt", "plain text\n")
+	addZip(t, zw, "static/app.js.map", "{}\n")
+	zw.Close()
+
+	archive := filepath.Join(dir, "bundle.zip")
+	if err := os.WriteFile(archive, outer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	err := Open(archive, Filter{Exclude: []string{"*.map"}}, func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, "app.js") && !strings.Contains(string(data), "UA-1234567-1") {
+			t.Errorf("%s: unexpected content %q", name, data)
+		}
+		names = append(names, strings.TrimPrefix(name, archive))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"!/notes.txt",
+		"!/site.tar.gz!/lib/app.jar!/META-INF/app.js",
+		"!/site.tar.gz!/static/index.html",
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("got entries %v, want %v", names, want)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "js/app.js", "node_modules/lib/index.js", "css/site.css"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var found []string
+	err := Walk(dir, Filter{Include: []string{"*.js", "*.html"}, Exclude: []string{"node_modules"}}, func(path string) error {
+		rel, _ := filepath.Rel(dir, path)
+		found = append(found, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(found, ",") != "index.html,js/app.js" {
+		t.Errorf("got %v, want [index.html js/app.js]", found)
+	}
+}
+
+func addZip(t *testing.T, zw *zip.Writer, name, content string) {
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addTar(t *testing.T, tw *tar.Writer, name, content string) {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"bufio"
 	"crypto/tls"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -11,13 +11,35 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gregcmartin/spectre/buckets"
+	"github.com/gregcmartin/spectre/compliance"
+	"github.com/gregcmartin/spectre/cookies"
+	"github.com/gregcmartin/spectre/decode"
+	"github.com/gregcmartin/spectre/deobfuscate"
+	"github.com/gregcmartin/spectre/filters"
+	"github.com/gregcmartin/spectre/fingerprint"
+	"github.com/gregcmartin/spectre/headers"
+	"github.com/gregcmartin/spectre/iframes"
+	"github.com/gregcmartin/spectre/input"
+	"github.com/gregcmartin/spectre/local"
+	"github.com/gregcmartin/spectre/matcher"
 	"github.com/gregcmartin/spectre/models"
 	"github.com/gregcmartin/spectre/patterns"
+	"github.com/gregcmartin/spectre/redirects"
+	"github.com/gregcmartin/spectre/render"
+	"github.com/gregcmartin/spectre/score"
+	"github.com/gregcmartin/spectre/secrets"
+	"github.com/gregcmartin/spectre/selection"
+	"github.com/gregcmartin/spectre/stream"
+	"github.com/gregcmartin/spectre/suppress"
+	"github.com/gregcmartin/spectre/thirdparty"
+	"github.com/gregcmartin/spectre/versions"
 )
 
 // CompiledPatterns holds pre-compiled regex patterns
@@ -25,6 +47,9 @@ type CompiledPatterns struct {
 	Category    string
 	PatternType string
 	Pattern     *regexp.Regexp
+	Targets     []fingerprint.Matcher
+	Domains     []string
+	Controls    patterns.Controls
 }
 
 // Scanner handles the scanning operations
@@ -35,8 +60,46 @@ type Scanner struct {
 	Detailed     bool
 	Majestic     bool
 	UserAgent    string
-	Category     string
+	Selection    *selection.Selection // categories, patterns and risk levels reported; nil reports all
 	CompiledPats []CompiledPatterns
+	// Prefilter skips body patterns whose required literals are absent
+	Prefilter *matcher.Matcher
+
+	// RevealSecrets disables masking of Secrets category values
+	RevealSecrets bool
+	// Buckets checks CloudStorage findings for anonymous access when set
+	Buckets *buckets.Checker
+	// Consent correlates trackers with consent management on each page
+	Consent bool
+	// Render loads pages in a headless browser when set, to report the
+	// trackers they request before consent is given
+	Render *render.Browser
+	// Versions checks detected CMS versions for known vulnerabilities when set
+	Versions *versions.Database
+	// ProbeVersions requests CMS version files such as Drupal's CHANGELOG.txt
+	ProbeVersions bool
+	// ChunkSize and Overlap set the streaming window; zero uses the defaults
+	ChunkSize int
+	Overlap   int
+	// Filter selects the files scanned in local directories and archives
+	Filter local.Filter
+	// Trackers classifies third-party domains; unlisted domains are
+	// reported as unclassified
+	Trackers *thirdparty.List
+	// Filters matches page resources against filter list rules when set
+	Filters *filters.Engine
+	// MaxRedirects is how many redirects are followed before the last
+	// redirect response is scanned instead
+	MaxRedirects int
+	// Scoring rates each URL's findings into a privacy score when set
+	Scoring *score.Model
+	// MinConfidence drops pattern matches rated below it: low, medium or
+	// high. Empty keeps every match.
+	MinConfidence string
+	// Suppressions hides accepted findings, which are still counted and
+	// recorded; ShowSuppressed prints them anyway
+	Suppressions   *suppress.List
+	ShowSuppressed bool
 }
 
 var (
@@ -46,8 +109,35 @@ var (
 	detailed *bool
 	majestic *bool
 	percent  *int
+	www      *bool
+	format   *string
+	column   *string
 	category string
+	names    *string
+	risk     *string
 	jsonFile *string
+	reveal   *bool
+
+	consent       *bool
+	renderPages   *bool
+	chrome        *string
+	checkBuckets  *bool
+	s3Endpoint    *string
+	gcsEndpoint   *string
+	azureEndpoint *string
+
+	probeVersions *bool
+	vulnDB        *string
+	chunkSize     *int
+	include       *string
+	exclude       *string
+	maxRedirects  *int
+	trackerList   *string
+	filterLists   *string
+	scoreModel    *string
+	suppressions  *string
+	showSupp      *bool
+	minConfidence *string
 )
 
 func init() {
@@ -57,12 +147,42 @@ func init() {
 	detailed = flag.Bool("d", false, "detailed mode")
 	majestic = flag.Bool("m", false, "use Majestic Million list")
 	percent = flag.Int("p", 100, "percentage of Majestic Million to scan (1-100)")
+	www = flag.Bool("www", false, "scan both the apex and www. host of each domain")
+	format = flag.String("input-format", input.FormatText, "input format: "+strings.Join(input.Formats, ", ")+"; arguments are files to read unless text")
+	column = flag.String("url-column", input.DefaultColumn, "URL field of jsonl and csv input")
 	jsonFile = flag.String("o", "", "output results to JSON file")
-	flag.StringVar(&category, "c", "all", "category to scan (TrackingPixel, AdNetwork, AIChat, HiddenIframe, Tracking, or 'all')")
+	reveal = flag.Bool("reveal-secrets", false, "show detected secrets unmasked")
+	consent = flag.Bool("consent", false, "report trackers loaded without or before consent management")
+	renderPages = flag.Bool("render", false, "load pages in headless Chrome to report trackers that fire before consent; implies -consent")
+	chrome = flag.String("chrome", "", "Chrome or Chromium used by -render, or the ws:// DevTools endpoint of a running browser (default: found on the PATH)")
+	checkBuckets = flag.Bool("check-buckets", false, "check discovered S3/GCS/Azure storage for anonymous access")
+	s3Endpoint = flag.String("s3-endpoint", buckets.DefaultS3Endpoint, "S3 endpoint used by -check-buckets")
+	gcsEndpoint = flag.String("gcs-endpoint", buckets.DefaultGCSEndpoint, "GCS endpoint used by -check-buckets")
+	azureEndpoint = flag.String("azure-endpoint", buckets.DefaultAzureEndpoint, "Azure Blob endpoint used by -check-buckets ({account} is replaced)")
+	probeVersions = flag.Bool("probe-versions", false, "request CMS version files (CHANGELOG.txt, joomla.xml, magento_version)")
+	vulnDB = flag.String("vuln-db", "", "CMS vulnerability database file (default: bundled dataset)")
+	chunkSize = flag.Int("chunk-size", stream.DefaultChunkSize>>10, "streaming scan chunk size in KB; bounds memory per document")
+	include = flag.String("include", "", "comma-separated globs of local files to scan, e.g. '*.js,*.html'")
+	exclude = flag.String("exclude", "", "comma-separated globs of local files and directories to skip, e.g. 'node_modules,*.map'")
+	maxRedirects = flag.Int("max-redirects", redirects.DefaultMax, "redirects to follow before scanning the last redirect response (0 to not follow)")
+	trackerList = flag.String("tracker-list", "", "tracker domain list in Disconnect JSON or EasyPrivacy format (default: bundled list)")
+	filterLists = flag.String("filter-list", "", "comma-separated Adblock Plus filter lists (EasyList, EasyPrivacy, ...) to match page resources against")
+	scoreModel = flag.String("score-model", "", "JSON file of privacy score weights (default: bundled model)")
+	suppressions = flag.String("suppress", "", "YAML file of accepted findings to hide")
+	showSupp = flag.Bool("show-suppressed", false, "show and output findings matched by -suppress")
+	minConfidence = flag.String("min-confidence", patterns.ConfidenceLow, "lowest confidence of pattern matches to report (low, medium, high)")
+	flag.StringVar(&category, "c", "all", "comma-separated categories to scan, or 'all'; prefix with - to skip, e.g. 'all,-CMS'")
+	names = flag.String("patterns", "", "comma-separated pattern names or tags to scan; prefix with - to skip, e.g. 'google,-Google Optimize'")
+	risk = flag.String("risk", "", "comma-separated risk levels to report (low, medium, high, critical)")
 }
 
 // NewScanner creates a new Scanner instance
-func NewScanner(stats *models.Statistics, findings *models.Findings, silent, detailed, majestic bool, ua, category string) *Scanner {
+func NewScanner(stats *models.Statistics, findings *models.Findings, silent, detailed, majestic bool, ua string, sel *selection.Selection) *Scanner {
+	compiled := compilePatterns(sel)
+	res := make([]*regexp.Regexp, len(compiled))
+	for i, cp := range compiled {
+		res[i] = cp.Pattern
+	}
 	return &Scanner{
 		Stats:        stats,
 		Findings:     findings,
@@ -70,8 +190,10 @@ func NewScanner(stats *models.Statistics, findings *models.Findings, silent, det
 		Detailed:     detailed,
 		Majestic:     majestic,
 		UserAgent:    ua,
-		Category:     category,
-		CompiledPats: compilePatterns(category),
+		Selection:    sel,
+		CompiledPats: compiled,
+		Prefilter:    matcher.New(res),
+		MaxRedirects: redirects.DefaultMax,
 	}
 }
 
@@ -87,16 +209,29 @@ func banner() {
 		`                             ` + "\033[36m[\033[37mVersion 1.0\033[36m]\n")
 }
 
-// compilePatterns pre-compiles all regex patterns for better performance
-func compilePatterns(category string) []CompiledPatterns {
+// compilePatterns pre-compiles the selected regex patterns for better
+// performance
+func compilePatterns(sel *selection.Selection) []CompiledPatterns {
 	var compiled []CompiledPatterns
 
 	for _, pt := range patterns.AllPatternTypes {
-		if category != "all" && !strings.EqualFold(category, pt.Category) {
+		if !sel.Pattern(pt) {
 			continue
 		}
 
-		re, err := regexp.Compile(pt.Pattern)
+		// A pattern may match only non-body targets
+		var re *regexp.Regexp
+		if pt.Pattern != "" {
+			var err error
+			if re, err = regexp.Compile(pt.Pattern); err != nil {
+				continue
+			}
+		}
+		targets, err := fingerprint.Compile(pt.Targets)
+		if err != nil {
+			continue
+		}
+		controls, err := pt.Controls()
 		if err != nil {
 			continue
 		}
@@ -105,6 +240,9 @@ func compilePatterns(category string) []CompiledPatterns {
 			Category:    pt.Category,
 			PatternType: pt.Name,
 			Pattern:     re,
+			Targets:     targets,
+			Domains:     controls.Domains,
+			Controls:    controls,
 		})
 	}
 
@@ -112,7 +250,7 @@ func compilePatterns(category string) []CompiledPatterns {
 }
 
 // ProcessMajesticStream processes the Majestic Million list
-func (s *Scanner) ProcessMajesticStream(urls chan<- string, percent int) error {
+func (s *Scanner) ProcessMajesticStream(urls chan<- input.Target, percent int, www bool) error {
 	resp, err := http.Get("https://downloads.majestic.com/majestic_million.csv")
 	if err != nil {
 		return err
@@ -156,7 +294,13 @@ func (s *Scanner) ProcessMajesticStream(urls chan<- string, percent int) error {
 		}
 
 		if len(record) > 2 {
-			urls <- "https://" + record[2]
+			targets, err := input.Domain(record[2], www)
+			if err != nil {
+				continue
+			}
+			for _, t := range targets {
+				urls <- t
+			}
 			total++
 		}
 	}
@@ -179,29 +323,296 @@ func drawProgressBar(current, total int) string {
 	return fmt.Sprintf("\r\033[34m[*]\033[37m Progress: [%s] %d%% (%d/%d domains)", bar, percentage, current, total)
 }
 
-// findMatchLocation finds the line number and context of a match in the content
-func findMatchLocation(urlStr, content string, match string) string {
-	if match == "" || content == "" {
-		return urlStr
+// getLine returns the full line of content containing a match
+func getLine(content, match string) string {
+	idx := strings.Index(content, match)
+	if idx == -1 {
+		return match
+	}
+	start := strings.LastIndex(content[:idx], "\n") + 1
+	end := strings.Index(content[idx:], "\n")
+	if end == -1 {
+		return content[start:]
+	}
+	return content[start : idx+end]
+}
+
+// checkBucket checks the bucket named in a CloudStorage finding for
+// anonymous access and returns the details and risk level to record
+func (s *Scanner) checkBucket(patternType, match, content string) (map[string]string, string) {
+	target, ok := buckets.ParseTarget(patternType, match)
+	if !ok {
+		return nil, ""
+	}
+
+	result := s.Buckets.Check(target)
+	if key := buckets.ObjectKey(target, getLine(content, match)); key != "" {
+		result = s.Buckets.CheckRead(result, key)
+	}
+
+	details := map[string]string{
+		"provider": result.Provider,
+		"bucket":   result.Bucket,
+		"exposure": result.Status,
+	}
+	if result.Account != "" {
+		details["account"] = result.Account
+	}
+	if result.Endpoint != "" {
+		details["endpoint"] = result.Endpoint
+	}
+	if result.SampleKey != "" {
+		details["sample_key"] = result.SampleKey
+	}
+	if result.Error != "" {
+		details["error"] = result.Error
+	}
+	return details, buckets.RiskLevel(result.Status)
+}
+
+// minConfidence returns the rank of the lowest confidence reported
+func (s *Scanner) minConfidence() int {
+	return patterns.ConfidenceRank(s.MinConfidence)
+}
+
+// show checks a finding against the suppression list, marking it with the
+// justification of the rule it matches, and reports whether to print it
+func (s *Scanner) show(urlStr string, finding *models.Finding) bool {
+	if !s.selected(*finding) {
+		return false
+	}
+	rule := s.Suppressions.Match(urlStr, finding.Category, finding.PatternType, finding.Value, time.Now())
+	if rule != nil {
+		finding.Suppressed = rule.Justification
+	}
+	if s.Silent || s.Majestic || (rule != nil && !s.ShowSuppressed) {
+		return false
+	}
+	if rule != nil {
+		fmt.Printf("\033[34m[*]\033[37m Suppressed: %s\n", rule.Justification)
+	}
+	return true
+}
+
+// selected reports whether a finding is of a selected type and risk level
+func (s *Scanner) selected(finding models.Finding) bool {
+	if !s.Selection.Finding(finding.Category, finding.PatternType) {
+		return false
+	}
+	risk := finding.RiskLevel
+	if risk == "" {
+		_, risk, _ = models.Describe(finding.Category, finding.PatternType)
+	}
+	return s.Selection.Risk(risk)
+}
+
+// add counts a finding, under its category or as suppressed, and records it
+func (s *Scanner) add(urlStr string, finding models.Finding) {
+	if !s.selected(finding) {
+		return
+	}
+	if finding.Suppressed != "" {
+		s.Stats.IncrementSuppressed()
+	} else {
+		s.Stats.Increment(finding.Category)
+	}
+	s.Findings.AddFinding(urlStr, finding)
+}
+
+// recordCookies classifies cookies set for a URL and records each new one
+// in the URL's cookie inventory and as a Cookies finding
+func (s *Scanner) recordCookies(urlStr string, found []models.Cookie) {
+	if !s.Selection.Category("Cookies") {
+		return
+	}
+	siteHost := ""
+	if u, err := url.Parse(urlStr); err == nil {
+		siteHost = u.Hostname()
+	}
+
+	for _, cookie := range found {
+		cookies.Classify(&cookie, siteHost)
+		if !s.Findings.AddCookie(urlStr, cookie) {
+			continue
+		}
+
+		party := "first-party"
+		if cookie.ThirdParty {
+			party = "third-party"
+		}
+		details := map[string]string{
+			"domain":    cookie.Domain,
+			"source":    cookie.Source,
+			"party":     party,
+			"secure":    strconv.FormatBool(cookie.Secure),
+			"http_only": strconv.FormatBool(cookie.HttpOnly),
+			"size":      strconv.Itoa(cookie.Size),
+		}
+		if cookie.Path != "" {
+			details["path"] = cookie.Path
+		}
+		if cookie.Expires != "" {
+			details["expires"] = cookie.Expires
+		}
+		if cookie.SameSite != "" {
+			details["same_site"] = cookie.SameSite
+		}
+		if cookie.Category != "" {
+			details["vendor_category"] = cookie.Category
+		}
+
+		finding := models.Finding{
+			Category:       "Cookies",
+			PatternType:    cookies.PatternType(cookie),
+			Value:          cookie.Name,
+			Location:       urlStr,
+			Implementation: details,
+		}
+		if s.show(urlStr, &finding) {
+			fmt.Printf("\033[32m[+]\033[37m Found Cookies (%s) %s: %s\n", finding.PatternType, party, cookie.Name)
+		}
+		s.Stats.IncrementCookies(cookie.ThirdParty)
+		s.add(urlStr, finding)
+	}
+}
+
+// analyzeHeaders scores a response's security headers and cross-references
+// the third-party hosts its CSP allows with trackers detected on the page
+func (s *Scanner) analyzeHeaders(urlStr string, resp *http.Response) {
+	if !s.Selection.Category("SecurityHeaders") {
+		return
+	}
+	pageURL := resp.Request.URL
+	analysis, issues := headers.Analyze(resp.Header, pageURL.Hostname(), pageURL.Scheme == "https")
+
+	detected := make(map[string]bool)
+	for _, f := range s.Findings.ForURL(urlStr) {
+		if compliance.TrackerCategories[f.Category] {
+			detected[f.Category+":"+f.PatternType] = true
+		}
+	}
+	vendorDomains := make(map[string][]string)
+	for _, cp := range s.CompiledPats {
+		if detected[cp.Category+":"+cp.PatternType] {
+			vendorDomains[cp.PatternType] = cp.Domains
+		}
+	}
+	analysis.CSPTrackers = headers.WhitelistedTrackers(analysis.CSPDomains, vendorDomains)
+	s.Findings.SetHeaders(urlStr, analysis)
+
+	if !s.Silent && !s.Majestic {
+		fmt.Printf("\033[34m[*]\033[37m Security headers: %d/100 (%s)\n", analysis.Score, analysis.Grade)
+	}
+
+	scoreDetails := make(map[string]string)
+	for name, value := range analysis.Headers {
+		scoreDetails[strings.ToLower(name)] = value
+	}
+	if len(analysis.CSPDomains) > 0 {
+		scoreDetails["csp_third_party"] = strings.Join(analysis.CSPDomains, " ")
 	}
+	s.addHeaderFinding(urlStr, "Header Score", fmt.Sprintf("%d/100 (%s)", analysis.Score, analysis.Grade), scoreDetails)
 
-	lines := strings.Split(content, "\n")
-	for i, line := range lines {
-		if strings.Contains(line, match) {
-			lineNum := i + 1
-			return fmt.Sprintf("%s#L%d", urlStr, lineNum)
+	for _, issue := range issues {
+		s.addHeaderFinding(urlStr, issue.Name, issue.Detail, map[string]string{"header": issue.Header})
+	}
+	for _, host := range analysis.CSPDomains {
+		if vendor, ok := analysis.CSPTrackers[host]; ok {
+			s.addHeaderFinding(urlStr, "CSP Whitelisted Tracker", host, map[string]string{"vendor": vendor})
 		}
 	}
-	return urlStr
 }
 
-// getLineNumber returns the line number for a match in the content
-func getLineNumber(content, match string) int {
-	idx := strings.Index(content, match)
-	if idx == -1 {
-		return 1
+// addHeaderFinding records a SecurityHeaders finding
+func (s *Scanner) addHeaderFinding(urlStr, patternType, value string, details map[string]string) {
+	finding := models.Finding{
+		Category:       "SecurityHeaders",
+		PatternType:    patternType,
+		Value:          value,
+		Location:       urlStr,
+		Implementation: details,
+	}
+	if s.show(urlStr, &finding) {
+		if s.Detailed {
+			fmt.Printf("\033[32m[+]\033[37m Found SecurityHeaders (%s): %s\n", patternType, value)
+		} else {
+			fmt.Printf("\033[32m[+]\033[37m Found SecurityHeaders (%s)\n", patternType)
+		}
+	}
+	s.add(urlStr, finding)
+}
+
+// checkConsent reports trackers on a page that are not held until consent
+func (s *Scanner) checkConsent(urlStr string, gating *compliance.Gating) {
+	s.reportConsent(urlStr, gating.Analyze(urlStr, s.Findings.ForURL(urlStr)))
+}
+
+// checkRendered loads a page in the browser and reports the trackers it
+// sends requests to before its consent banner is accepted
+func (s *Scanner) checkRendered(urlStr string) {
+	requests, err := s.Render.Requests(urlStr)
+	if err != nil {
+		if !s.Silent {
+			fmt.Printf("\033[31m[-]\033[37m Error rendering %s: %v\n", urlStr, err)
+		}
+		return
 	}
-	return strings.Count(content[:idx], "\n") + 1
+	var sent []string
+	for _, r := range requests {
+		sent = append(sent, r.URL)
+	}
+	var vendors []compliance.Vendor
+	for _, cp := range s.CompiledPats {
+		if len(cp.Domains) > 0 && (compliance.TrackerCategories[cp.Category] || cp.Category == "ConsentManagement") {
+			vendors = append(vendors, compliance.Vendor{Category: cp.Category, PatternType: cp.PatternType, Domains: cp.Domains})
+		}
+	}
+	s.reportConsent(urlStr, compliance.Rendered(urlStr, sent, vendors, s.Findings.ForURL(urlStr)))
+}
+
+// reportConsent records the consent violations of a page's report
+func (s *Scanner) reportConsent(urlStr string, report compliance.Report) {
+	reported := make(map[string]bool)
+	for _, tracker := range report.Violations() {
+		// Report each tracker once per status, at its first location
+		patternType := compliance.PatternTypes[tracker.Status]
+		if reported[patternType+":"+tracker.PatternType] {
+			continue
+		}
+		reported[patternType+":"+tracker.PatternType] = true
+
+		details := map[string]string{
+			"tracker_category": tracker.Category,
+			"status":           tracker.Status,
+		}
+		if len(report.CMPs) > 0 {
+			details["cmp"] = strings.Join(report.CMPs, ", ")
+		}
+		if tracker.Request != "" {
+			details["request"] = tracker.Request
+		}
+
+		finding := models.Finding{
+			Category:       "ConsentCompliance",
+			PatternType:    patternType,
+			Value:          tracker.PatternType,
+			Location:       tracker.Location,
+			Implementation: details,
+		}
+		if s.show(urlStr, &finding) {
+			if tracker.Request != "" {
+				fmt.Printf("\033[33m[!]\033[37m %s: %s (%s) requested %s\n", patternType, tracker.PatternType, tracker.Category, tracker.Request)
+			} else {
+				fmt.Printf("\033[33m[!]\033[37m %s: %s (%s) at line %d\n", patternType, tracker.PatternType, tracker.Category, tracker.Line)
+			}
+		}
+		s.add(urlStr, finding)
+	}
+}
+
+// checksConsent reports whether pages are checked for consent compliance
+func (s *Scanner) checksConsent() bool {
+	return s.Consent && s.Selection.Category("ConsentCompliance")
 }
 
 // ScanContent scans content for tracking elements
@@ -210,28 +621,640 @@ func (s *Scanner) ScanContent(urlStr string, content string) {
 		return
 	}
 
-	for _, cp := range s.CompiledPats {
-		matches := cp.Pattern.FindAllString(content, -1)
+	s.scanWindow(urlStr, content, 1, len(content))
+	if s.checksConsent() {
+		var gating compliance.Gating
+		gating.Add(content, 1)
+		s.checkConsent(urlStr, &gating)
+	}
+}
+
+// scanWindow scans part of a document starting at document line
+// firstLine. Only matches starting before limit are recorded; the rest of
+// the window is the overlap that starts the next window.
+func (s *Scanner) scanWindow(urlStr, content string, firstLine, limit int) {
+	s.matchPatterns(urlStr, content, "", firstLine, limit)
+	if decoded := deobfuscate.Normalize(content); decoded != content {
+		s.matchPatterns(urlStr, decoded, content, firstLine, limit)
+	}
+
+	if s.Selection.Category("HiddenIframe") {
+		s.scanIframes(urlStr, content, firstLine, limit)
+	}
+
+	var resources []thirdparty.Resource
+	if s.Selection.Category("ThirdParty") || s.Filters != nil {
+		resources = s.resources(urlStr, content, firstLine, limit)
+	}
+	if s.Selection.Category("ThirdParty") {
+		s.recordThirdParties(urlStr, resources)
+	}
+	if s.Filters != nil {
+		s.matchFilters(urlStr, resources)
+	}
 
-		for _, match := range matches {
+	host := ""
+	if u, err := url.Parse(urlStr); err == nil {
+		host = u.Hostname()
+	}
+	s.recordCookies(urlStr, cookies.FromScript(content[:limit], host))
+}
+
+// resources returns the resources referenced in a window by tags that
+// start before limit, at document lines
+func (s *Scanner) resources(urlStr, content string, firstLine, limit int) []thirdparty.Resource {
+	var resources []thirdparty.Resource
+	for _, r := range thirdparty.Extract(content, urlStr) {
+		if r.Offset >= limit {
+			continue
+		}
+		r.Line += firstLine - 1
+		resources = append(resources, r)
+	}
+	return resources
+}
+
+// matchFilters reports resources blocked by the loaded filter lists under
+// the list's category, with the matching rule
+func (s *Scanner) matchFilters(urlStr string, resources []thirdparty.Resource) {
+	pageHost := ""
+	if u, err := url.Parse(urlStr); err == nil {
+		pageHost = strings.ToLower(u.Hostname())
+	}
+	for _, r := range resources {
+		resourceType := filters.ResourceType(r.Kind)
+		for _, rule := range s.Filters.Match(filters.Request{URL: r.URL, Type: resourceType, PageHost: pageHost, ThirdParty: r.ThirdParty}) {
+			s.addFilterMatch(urlStr, r, resourceType, rule)
+		}
+	}
+}
+
+// addFilterMatch reports a resource blocked by a filter rule
+func (s *Scanner) addFilterMatch(urlStr string, r thirdparty.Resource, resourceType string, rule *filters.Rule) {
+	if !s.Selection.Category(rule.Category) {
+		return
+	}
+
+	finding := models.Finding{
+		Category:    rule.Category,
+		PatternType: rule.List,
+		Value:       r.URL,
+		Location:    location(urlStr, r.Line),
+		Description: "Resource matched by a " + rule.List + " filter rule",
+		Implementation: map[string]string{
+			"rule":          rule.Text,
+			"list":          rule.List,
+			"resource_type": resourceType,
+			"third_party":   strconv.FormatBool(r.ThirdParty),
+		},
+	}
+	if s.show(urlStr, &finding) {
+		if s.Detailed {
+			fmt.Printf("\033[32m[+]\033[37m Found %s (%s) at %s: %s [%s]\n", rule.Category, rule.List, displayLocation(urlStr, r.Line), r.URL, rule.Text)
+		} else {
+			fmt.Printf("\033[32m[+]\033[37m Found %s (%s) at %s\n", rule.Category, rule.List, displayLocation(urlStr, r.Line))
+		}
+	}
+	s.add(urlStr, finding)
+}
+
+// recordThirdParties adds the other sites a page loads resources from to
+// its inventory, reporting each new domain as a known tracker or as
+// unclassified
+func (s *Scanner) recordThirdParties(urlStr string, resources []thirdparty.Resource) {
+	for _, d := range thirdparty.Group(resources) {
+		domain := models.ThirdPartyDomain{Domain: d.Domain, Hosts: d.Hosts, Kinds: d.Kinds, Requests: d.Requests}
+		for _, host := range d.Hosts {
+			if e, ok := s.Trackers.Lookup(host); ok {
+				domain.Company, domain.Category = e.Company, e.Category
+				break
+			}
+		}
+		if !s.Findings.AddThirdParty(urlStr, domain) {
+			continue
+		}
+
+		patternType, riskLevel := thirdparty.Unclassified, "Low"
+		details := map[string]string{
+			"hosts": strings.Join(d.Hosts, ", "),
+			"kinds": strings.Join(d.Kinds, ", "),
+		}
+		if domain.Company != "" {
+			patternType, riskLevel = thirdparty.KnownTracker, ""
+			details["company"] = domain.Company
+			details["tracker_category"] = domain.Category
+		}
+		finding := models.Finding{
+			Category:       "ThirdParty",
+			PatternType:    patternType,
+			Value:          d.Domain,
+			Location:       location(urlStr, d.Line),
+			RiskLevel:      riskLevel,
+			Implementation: details,
+		}
+		if s.show(urlStr, &finding) {
+			if s.Detailed && domain.Company != "" {
+				fmt.Printf("\033[32m[+]\033[37m Found ThirdParty (%s) at %s: %s (%s, %s)\n", patternType, displayLocation(urlStr, d.Line), d.Domain, domain.Company, domain.Category)
+			} else {
+				fmt.Printf("\033[32m[+]\033[37m Found ThirdParty (%s) at %s: %s\n", patternType, displayLocation(urlStr, d.Line), d.Domain)
+			}
+		}
+		s.add(urlStr, finding)
+	}
+}
+
+// ScanReader scans a document read from r in overlapping windows, so
+// memory per document is bounded by the chunk size plus overlap however
+// large the document is. Documents that fit in one chunk are scanned
+// whole with ScanContent. It returns the meta tags and script sources of
+// the whole document, and the CMS versions read from it, for the analyses
+// that need the page markup. Consent is analyzed once the last window is
+// scanned, from the gated elements of each window.
+func (s *Scanner) ScanReader(urlStr string, r io.Reader) (fingerprint.Page, []versions.Detection, error) {
+	var page fingerprint.Page
+	var found versions.Collector
+	var gating *compliance.Gating
+	total, err := stream.Scan(r, s.ChunkSize, s.Overlap, func(w stream.Window) {
+		page.Add(w.Content, w.Limit)
+		if s.checksVersions() {
+			found.Add(w.Content, w.Limit)
+		}
+		if w.Whole {
+			s.ScanContent(urlStr, w.Content)
+			return
+		}
+		s.scanWindow(urlStr, w.Content, w.FirstLine, w.Limit)
+		if s.checksConsent() {
+			if gating == nil {
+				gating = &compliance.Gating{}
+			}
+			gating.Add(w.Content, w.FirstLine)
+		}
+	})
+	if err != nil {
+		return page, nil, err
+	}
+	if gating != nil {
+		s.checkConsent(urlStr, gating)
+	}
+	s.Stats.IncrementScanned(total)
+	return page, found.Detections(), nil
+}
+
+// matchPatterns records body pattern matches in content that start before
+// limit. When raw is set, content is its deobfuscated form and only matches
+// that do not appear in the raw text are recorded, marked as decoded.
+func (s *Scanner) matchPatterns(urlStr, content, raw string, firstLine, limit int) {
+	candidates := s.Prefilter.Candidates(content)
+	var lines matcher.Lines
+	// Deobfuscation keeps lines but not offsets, so decoded matches are
+	// limited to the lines before the overlap
+	limitLine := 0
+	if raw != "" && limit < len(raw) {
+		limitLine = strings.Count(raw[:limit], "\n")
+	}
+	for i, cp := range s.CompiledPats {
+		if cp.Pattern == nil || (candidates != nil && !candidates[i]) {
+			continue
+		}
+		indexes, confidence := cp.Controls.Apply(content, cp.Pattern.FindAllStringIndex(content, -1))
+		if len(indexes) > 0 && lines == nil {
+			lines = matcher.NewLines(content)
+		}
+
+		for n, loc := range indexes {
+			if patterns.ConfidenceRank(confidence[n]) < s.minConfidence() {
+				continue
+			}
+			match := content[loc[0]:loc[1]]
+			if raw == "" && loc[0] >= limit {
+				continue
+			}
+			if raw != "" && (strings.Contains(raw, match) || (limitLine > 0 && lines.Line(loc[0]) > limitLine)) {
+				continue
+			}
 			cleanedMatch := strings.TrimSpace(match)
-			location := findMatchLocation(urlStr, content, match)
-			displayLocation := fmt.Sprintf("line %d", getLineNumber(content, match))
 
-			if !s.Silent && !s.Majestic {
+			var details map[string]string
+			var riskLevel string
+			if cp.Category == "CloudStorage" && s.Buckets != nil {
+				details, riskLevel = s.checkBucket(cp.PatternType, match, content)
+			}
+			if cp.Category == "Secrets" {
+				entropy := secrets.Entropy(secrets.Token(cleanedMatch))
+				if cp.PatternType == secrets.GenericPattern && entropy < secrets.MinEntropy {
+					continue
+				}
+				details = map[string]string{
+					"entropy":      fmt.Sprintf("%.2f", entropy),
+					"verification": secrets.Hint(cp.PatternType),
+				}
+				if !s.RevealSecrets {
+					cleanedMatch = secrets.MaskMatch(cleanedMatch)
+					details["masked"] = "true"
+				}
+			} else if !s.RevealSecrets {
+				cleanedMatch = secrets.MaskEmbedded(cleanedMatch)
+			}
+
+			if raw != "" {
+				if details == nil {
+					details = make(map[string]string)
+				}
+				details["decoded"] = "true"
+			}
+
+			line := firstLine - 1 + lines.Line(loc[0])
+			where := displayLocation(urlStr, line)
+			if raw != "" {
+				where += " (decoded)"
+			}
+
+			if confidence[n] == patterns.ConfidenceLow {
+				where += " (low confidence)"
+			}
+
+			finding := models.Finding{
+				Category:       cp.Category,
+				PatternType:    cp.PatternType,
+				Value:          cleanedMatch,
+				Location:       location(urlStr, line),
+				RiskLevel:      riskLevel,
+				Confidence:     confidence[n],
+				Implementation: details,
+			}
+			if s.show(urlStr, &finding) {
 				if s.Detailed {
-					fmt.Printf("\033[32m[+]\033[37m Found %s (%s) at %s: %s\n", cp.Category, cp.PatternType, displayLocation, cleanedMatch)
+					fmt.Printf("\033[32m[+]\033[37m Found %s (%s) at %s: %s\n", cp.Category, cp.PatternType, where, cleanedMatch)
 				} else {
-					fmt.Printf("\033[32m[+]\033[37m Found %s (%s) at %s\n", cp.Category, cp.PatternType, displayLocation)
+					fmt.Printf("\033[32m[+]\033[37m Found %s (%s) at %s\n", cp.Category, cp.PatternType, where)
 				}
 			}
-			s.Stats.Increment(cp.Category)
-			s.Findings.Add(urlStr, cp.Category, cp.PatternType, cleanedMatch, location)
+			s.add(urlStr, finding)
 		}
 	}
 }
 
-// ProcessURL processes a single URL
+// location returns where a match was found: a line anchor for URLs, and
+// path:line for local files and archive entries
+func location(urlStr string, line int) string {
+	if strings.Contains(urlStr, "://") {
+		return fmt.Sprintf("%s#L%d", urlStr, line)
+	}
+	return fmt.Sprintf("%s:%d", urlStr, line)
+}
+
+// displayLocation returns the location shown on the console. Local files
+// are named, as a directory scan reports many.
+func displayLocation(urlStr string, line int) string {
+	if strings.Contains(urlStr, "://") {
+		return fmt.Sprintf("line %d", line)
+	}
+	return location(urlStr, line)
+}
+
+// iframeVisibility maps iframe kinds to the visibility detail
+var iframeVisibility = map[string]string{
+	iframes.KindHidden:    "hidden",
+	iframes.KindOffscreen: "off-screen",
+	iframes.KindZeroSize:  "zero-sized",
+}
+
+// scanIframes reports iframes in the page markup that are not visible
+func (s *Scanner) scanIframes(urlStr, content string, firstLine, limit int) {
+	for _, f := range iframes.Analyze(content) {
+		if !f.Hidden() || f.Offset >= limit {
+			continue
+		}
+		f.Line += firstLine - 1
+		details := map[string]string{
+			"visibility": iframeVisibility[f.Kind],
+			"reason":     strings.Join(f.Reasons, "; "),
+		}
+		if f.Src != "" {
+			details["src"] = f.Src
+		}
+		if f.Domain != "" {
+			details["src_domain"] = f.Domain
+		}
+		if f.Sandbox != "" {
+			details["sandbox"] = f.Sandbox
+		}
+		if f.Allow != "" {
+			details["allow"] = f.Allow
+		}
+		if f.Width != "" || f.Height != "" {
+			details["dimensions"] = f.Width + "x" + f.Height
+		}
+		if strings.HasSuffix(f.Domain, "googletagmanager.com") {
+			details["type"] = "Google Tag Manager container"
+		}
+
+		value := strings.TrimSpace(f.Tag)
+		finding := models.Finding{
+			Category:       "HiddenIframe",
+			PatternType:    f.Kind,
+			Value:          value,
+			Location:       location(urlStr, f.Line),
+			Implementation: details,
+		}
+		if s.show(urlStr, &finding) {
+			if s.Detailed {
+				fmt.Printf("\033[32m[+]\033[37m Found HiddenIframe (%s) at %s: %s\n", f.Kind, displayLocation(urlStr, f.Line), value)
+			} else {
+				fmt.Printf("\033[32m[+]\033[37m Found HiddenIframe (%s) at %s\n", f.Kind, displayLocation(urlStr, f.Line))
+			}
+		}
+		s.add(urlStr, finding)
+	}
+}
+
+// ScanTargets matches patterns against the headers, cookies, meta tags,
+// script sources and URL of a page
+func (s *Scanner) ScanTargets(urlStr string, page fingerprint.Page) {
+	for _, cp := range s.CompiledPats {
+		for _, m := range cp.Targets {
+			for _, match := range m.Match(page) {
+				details := map[string]string{"target": match.Target}
+				if match.Key != "" {
+					details["key"] = match.Key
+				}
+				finding := models.Finding{
+					Category:       cp.Category,
+					PatternType:    cp.PatternType,
+					Value:          match.Value,
+					Location:       urlStr,
+					Implementation: details,
+				}
+				if s.show(urlStr, &finding) {
+					if s.Detailed {
+						fmt.Printf("\033[32m[+]\033[37m Found %s (%s) in %s: %s\n", cp.Category, cp.PatternType, match.Target, match.Value)
+					} else {
+						fmt.Printf("\033[32m[+]\033[37m Found %s (%s) in %s\n", cp.Category, cp.PatternType, match.Target)
+					}
+				}
+				s.add(urlStr, finding)
+			}
+		}
+	}
+}
+
+// checksVersions reports whether CMS versions are assessed
+func (s *Scanner) checksVersions() bool {
+	return s.Versions != nil && s.Selection.Category("CMSVersion")
+}
+
+// checkVersions assesses the CMS versions read from a page, and from
+// version files when probing is enabled, against the vulnerability
+// database
+func (s *Scanner) checkVersions(urlStr string, found []versions.Detection, base *url.URL) {
+	if !s.checksVersions() {
+		return
+	}
+
+	for _, d := range found {
+		s.addVersion(urlStr, urlStr, d)
+	}
+	if !s.ProbeVersions || base == nil {
+		return
+	}
+
+	detected := make(map[string]bool)
+	for _, f := range s.Findings.ForURL(urlStr) {
+		if f.Category == "CMS" {
+			detected[f.PatternType] = true
+		}
+	}
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   10 * time.Second,
+	}
+	for _, probe := range versions.Probes {
+		if !detected[probe.CMS] {
+			continue
+		}
+		probeURL := base.ResolveReference(&url.URL{Path: probe.Path}).String()
+		req, err := http.NewRequest("GET", probeURL, nil)
+		if err != nil {
+			continue
+		}
+		req.Header.Set("User-Agent", s.UserAgent)
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		if d, ok := versions.FromProbe(probe, string(body)); ok {
+			s.addVersion(urlStr, probeURL, d)
+		}
+	}
+}
+
+// addVersion records a CMSVersion finding with its database assessment
+func (s *Scanner) addVersion(urlStr, location string, d versions.Detection) {
+	a := s.Versions.Assess(d)
+	details := map[string]string{
+		"version": d.Version,
+		"source":  d.Source,
+		"status":  a.Status,
+	}
+	if a.Supported != "" {
+		details["supported"] = a.Supported
+	}
+	if d.Detail != "" {
+		details["detail"] = d.Detail
+	}
+	var ids []string
+	if len(a.Vulnerabilities) > 0 {
+		var summaries []string
+		for _, v := range a.Vulnerabilities {
+			ids = append(ids, v.ID)
+			summaries = append(summaries, v.ID+": "+v.Summary)
+		}
+		details["vulnerabilities"] = strings.Join(summaries, "; ")
+	}
+
+	finding := models.Finding{
+		Category:       "CMSVersion",
+		PatternType:    d.CMS,
+		Value:          d.CMS + " " + d.Version,
+		Location:       location,
+		RiskLevel:      a.RiskLevel,
+		Implementation: details,
+	}
+	if s.show(urlStr, &finding) {
+		marker := "\033[32m[+]\033[37m"
+		if a.Status == versions.StatusOutdated || a.Status == versions.StatusVulnerable {
+			marker = "\033[33m[!]\033[37m"
+		}
+		status := a.Status
+		if len(ids) > 0 {
+			status += " (" + strings.Join(ids, ", ") + ")"
+		}
+		fmt.Printf("%s Found CMSVersion (%s) %s: %s\n", marker, d.CMS, d.Version, status)
+	}
+	s.add(urlStr, finding)
+}
+
+// recordRedirects records the redirect chain from inputURL to finalURL and
+// reports third-party hops, cross-site redirects and HTTPS downgrades
+func (s *Scanner) recordRedirects(inputURL, finalURL string, chain *redirects.Chain) {
+	if !s.Silent && !s.Majestic && s.Detailed {
+		for _, hop := range chain.Hops {
+			fmt.Printf("\033[34m[*]\033[37m Redirect %d %s -> %s\n", hop.Status, hop.URL, hop.Location)
+		}
+	}
+	if chain.Truncated && !s.Silent && !s.Majestic {
+		fmt.Printf("\033[33m[!]\033[37m Stopped after %d redirects at %s\n", len(chain.Hops), finalURL)
+	}
+
+	flags := redirects.Analyze(inputURL, finalURL, chain.Hops, s.vendor)
+	if len(chain.Hops) > 0 || inputURL != finalURL {
+		s.Findings.SetRedirects(finalURL, inputURL, chain.Hops)
+	}
+	if !s.Selection.Category("Redirects") {
+		return
+	}
+	for _, flag := range flags {
+		value, location := flag.Hop.URL, flag.Hop.URL
+		switch flag.Type {
+		case redirects.CrossDomain:
+			value, location = finalURL, inputURL
+		case redirects.Downgrade:
+			value = flag.Details["from"] + " -> " + flag.Details["to"]
+		}
+		finding := models.Finding{
+			Category:       "Redirects",
+			PatternType:    flag.Type,
+			Value:          value,
+			Location:       location,
+			Implementation: flag.Details,
+		}
+		if s.show(finalURL, &finding) {
+			fmt.Printf("\033[33m[!]\033[37m %s: %s\n", flag.Type, value)
+		}
+		s.add(finalURL, finding)
+	}
+}
+
+// vendor names the tracking pattern whose domains include host
+func (s *Scanner) vendor(host string) string {
+	for _, cp := range s.CompiledPats {
+		for _, domain := range cp.Domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return cp.PatternType + " (" + cp.Category + ")"
+			}
+		}
+	}
+	return ""
+}
+
+// scoreURL rates everything recorded for a URL and reports the score. Pages
+// are always scored, local files only when something was found.
+func (s *Scanner) scoreURL(urlStr string, page bool) {
+	if s.Scoring == nil || !s.Selection.Category(score.Category) {
+		return
+	}
+	u, ok := s.Findings.Get(urlStr)
+	if !ok && !page {
+		return
+	}
+	u.URL = urlStr
+	result := s.Scoring.Score(u)
+	s.Findings.SetScore(urlStr, result)
+
+	scored := 0
+	for _, f := range u.Findings {
+		if f.Category != score.Category && f.Suppressed == "" {
+			scored++
+		}
+	}
+	deductions := make([]string, 0, len(result.Deductions))
+	for _, d := range result.Deductions {
+		deductions = append(deductions, fmt.Sprintf("%s -%s", d.Reason, strconv.FormatFloat(d.Points, 'f', -1, 64)))
+	}
+	value := fmt.Sprintf("%d/100 (%s)", result.Score, result.Grade)
+	if !s.Silent && !s.Majestic {
+		fmt.Printf("\033[34m[*]\033[37m Privacy score: %s\n", value)
+		if s.Detailed && len(deductions) > 0 {
+			fmt.Printf("    %s\n", strings.Join(deductions, ", "))
+		}
+	}
+	details := map[string]string{
+		"score":    strconv.Itoa(result.Score),
+		"grade":    result.Grade,
+		"findings": strconv.Itoa(scored),
+	}
+	if len(deductions) > 0 {
+		details["deductions"] = strings.Join(deductions, ", ")
+	}
+	finding := models.Finding{
+		Category:       score.Category,
+		PatternType:    "Privacy Score",
+		Value:          value,
+		Location:       urlStr,
+		RiskLevel:      score.RiskLevel(result.Grade),
+		Implementation: details,
+	}
+	if s.selected(finding) {
+		s.Findings.AddFinding(urlStr, finding)
+	}
+}
+
+// ProcessPath scans a local file or directory, including the text entries
+// of zips, jars and tarballs, which are named archive!/inner/path
+func (s *Scanner) ProcessPath(filePath string) error {
+	err := local.Walk(filePath, s.Filter, func(path string) error {
+		return local.Open(path, s.Filter, s.scanFile)
+	})
+	if err != nil && !s.Silent {
+		fmt.Printf("\033[31m[-]\033[37m Error reading file %s: %v\n", filePath, err)
+	}
+	return err
+}
+
+// scanFile scans a local document, which has no headers or cookies.
+// Documents declaring a charset other than UTF-8 are transcoded.
+func (s *Scanner) scanFile(name string, r io.Reader) error {
+	text, charset, source := decode.Transcode(r, "")
+	var content *models.ContentInfo
+	if charset != "utf-8" {
+		content = &models.ContentInfo{Charset: charset, CharsetSource: source}
+	}
+	defer s.Findings.Finish(name, content)
+	page, found, err := s.ScanReader(name, text)
+	if err != nil {
+		return err
+	}
+	page.URL = name
+	s.ScanTargets(name, page)
+	s.checkVersions(name, found, nil)
+	s.scoreURL(name, false)
+	return nil
+}
+
+// ProcessTarget scans a normalized input, keeping its tags with the
+// findings. A domain given without a scheme is fetched over plain HTTP when
+// the HTTPS request fails.
+func (s *Scanner) ProcessTarget(t input.Target) error {
+	s.Findings.SetTags(t.URL, t.Tags)
+	err := s.ProcessURL(t.URL)
+	var fetchErr *url.Error
+	if t.Fallback != "" && errors.As(err, &fetchErr) {
+		s.Findings.SetTags(t.Fallback, t.Tags)
+		err = s.ProcessURL(t.Fallback)
+	}
+	if errors.As(err, &fetchErr) && !s.Silent && !s.Majestic {
+		fmt.Printf("\033[31m[-]\033[37m Error fetching %s: %v\n", fetchErr.URL, fetchErr.Err)
+	}
+	return err
+}
+
+// ProcessURL processes a single URL, or a local path
 func (s *Scanner) ProcessURL(urlStr string) error {
 	if strings.HasPrefix(urlStr, "file://") {
 		filePath := strings.TrimPrefix(urlStr, "file://")
@@ -240,18 +1263,24 @@ func (s *Scanner) ProcessURL(urlStr string) error {
 			return err
 		}
 
-		content, err := ioutil.ReadFile(filePath)
+		file, err := os.Open(filePath)
+		if err == nil {
+			defer file.Close()
+			err = s.scanFile(urlStr, file)
+		}
 		if err != nil {
 			if !s.Silent {
 				fmt.Printf("\033[31m[-]\033[37m Error reading file %s: %v\n", filePath, err)
 			}
 			return err
 		}
-
-		s.ScanContent(urlStr, string(content))
 		return nil
 	}
 
+	if !strings.Contains(urlStr, "://") {
+		return s.ProcessPath(urlStr)
+	}
+
 	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
 		return fmt.Errorf("invalid URL format")
 	}
@@ -259,9 +1288,11 @@ func (s *Scanner) ProcessURL(urlStr string) error {
 	transp := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
+	chain := &redirects.Chain{Max: s.MaxRedirects}
 	client := &http.Client{
-		Transport: transp,
-		Timeout:   10 * time.Second,
+		Transport:     transp,
+		Timeout:       10 * time.Second,
+		CheckRedirect: chain.CheckRedirect,
 	}
 
 	req, err := http.NewRequest("GET", urlStr, nil)
@@ -270,18 +1301,50 @@ func (s *Scanner) ProcessURL(urlStr string) error {
 	}
 
 	req.Header.Set("User-Agent", s.UserAgent)
+	// Asking for encodings explicitly turns off the transport's own gzip
+	// handling, so every encoding is undone in one place
+	req.Header.Set("Accept-Encoding", decode.AcceptEncoding)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// Findings belong to the page actually served, and keep the input URL
+	inputURL := urlStr
+	urlStr = resp.Request.URL.String()
+	var content *models.ContentInfo
+	defer func() { s.Findings.Finish(urlStr, content) }()
+	s.recordRedirects(inputURL, urlStr, chain)
+
+	respCookies := append(chain.Cookies, cookies.FromResponse(resp)...)
+	s.recordCookies(urlStr, respCookies)
+	body, info, err := decode.Body(resp.Body, resp.Header.Get("Content-Encoding"), resp.Header.Get("Content-Type"))
+	if err != nil {
+		if !s.Silent {
+			fmt.Printf("\033[31m[-]\033[37m Error decoding %s: %v\n", urlStr, err)
+		}
+		return err
+	}
+	defer body.Close()
+	content = &info
+	page, found, err := s.ScanReader(urlStr, body)
 	if err != nil {
 		return err
 	}
 
-	s.ScanContent(urlStr, string(body))
+	if s.Render != nil && s.checksConsent() {
+		s.checkRendered(urlStr)
+	}
+
+	page.URL, page.Header = urlStr, resp.Header
+	for _, c := range respCookies {
+		page.Cookies = append(page.Cookies, c.Name)
+	}
+	s.ScanTargets(urlStr, page)
+	s.checkVersions(urlStr, found, resp.Request.URL)
+	s.analyzeHeaders(urlStr, resp)
+	s.scoreURL(urlStr, true)
 	return nil
 }
 
@@ -291,6 +1354,12 @@ func printStats(stats *models.Statistics) {
 		fmt.Printf("    URLs Scanned: %d\n", stats.ScannedURLs)
 		fmt.Printf("    Elements Found: %d\n", stats.FoundSecrets)
 		fmt.Printf("    Data Processed: %.2f MB\n", float64(stats.ProcessedBytes)/1024/1024)
+		if stats.Suppressed > 0 {
+			fmt.Printf("    Suppressed: %d\n", stats.Suppressed)
+		}
+		if stats.FirstPartyCookies+stats.ThirdPartyCookies > 0 {
+			fmt.Printf("    Cookies: %d first-party, %d third-party\n", stats.FirstPartyCookies, stats.ThirdPartyCookies)
+		}
 
 		if len(stats.Categories) > 0 {
 			fmt.Printf("\n    Elements by Category:\n")
@@ -301,6 +1370,40 @@ func printStats(stats *models.Statistics) {
 	}
 }
 
+// maxListedScores bounds the scores listed in the summary
+const maxListedScores = 10
+
+// printScores lists the lowest privacy scores of a scan
+func printScores(scored []models.URLFindings) {
+	if len(scored) == 0 {
+		return
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Score.Score != scored[j].Score.Score {
+			return scored[i].Score.Score < scored[j].Score.Score
+		}
+		return scored[i].URL < scored[j].URL
+	})
+	fmt.Printf("\n    Privacy Scores (lowest first):\n")
+	for _, u := range scored[:min(len(scored), maxListedScores)] {
+		fmt.Printf("    - %3d/100 (%s) %s\n", u.Score.Score, u.Score.Grade, u.URL)
+	}
+	if len(scored) > maxListedScores {
+		fmt.Printf("    ... and %d more\n", len(scored)-maxListedScores)
+	}
+}
+
+// splitList splits a comma-separated flag value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -309,7 +1412,26 @@ func min(a, b int) int {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "correlate" {
+		os.Exit(runCorrelate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "scores" {
+		os.Exit(runScores(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rules" {
+		os.Exit(runRules(os.Args[2:]))
+	}
+
 	flag.Parse()
+	sel, err := selection.Parse(category, *names, *risk)
+	if err != nil {
+		fmt.Printf("\033[31m[-]\033[37m %v\n", err)
+		os.Exit(1)
+	}
+	if err := input.CheckFormat(*format); err != nil {
+		fmt.Printf("\033[31m[-]\033[37m %v\n", err)
+		os.Exit(1)
+	}
 
 	if !*silent && !*majestic {
 		banner()
@@ -331,8 +1453,90 @@ func main() {
 		defer findings.CloseJSONFile()
 	}
 
-	scanner := NewScanner(stats, findings, *silent, *detailed, *majestic, *ua, category)
-	urls := make(chan string)
+	scanner := NewScanner(stats, findings, *silent, *detailed, *majestic, *ua, sel)
+	scanner.RevealSecrets = *reveal
+	scanner.Consent = *consent || *renderPages
+	scanner.Versions = versions.Default()
+	if *vulnDB != "" {
+		db, err := versions.Load(*vulnDB)
+		if err != nil {
+			fmt.Printf("\033[31m[-]\033[37m Error loading vulnerability database: %v\n", err)
+			os.Exit(1)
+		}
+		scanner.Versions = db
+	}
+	scanner.ProbeVersions = *probeVersions
+	scanner.Trackers = thirdparty.Default()
+	if *trackerList != "" {
+		list, err := thirdparty.Load(*trackerList)
+		if err != nil {
+			fmt.Printf("\033[31m[-]\033[37m Error loading tracker list: %v\n", err)
+			os.Exit(1)
+		}
+		scanner.Trackers = list
+	}
+	scanner.Trackers.AddPatterns(patterns.AllPatternTypes)
+	if lists := splitList(*filterLists); len(lists) > 0 {
+		scanner.Filters = filters.New()
+		for _, path := range lists {
+			info, err := scanner.Filters.Load(path)
+			if err != nil {
+				fmt.Printf("\033[31m[-]\033[37m Error loading filter list: %v\n", err)
+				os.Exit(1)
+			}
+			if !*silent && !*majestic {
+				fmt.Printf("\033[34m[*]\033[37m Loaded %d %s rules from %s (%d unsupported)\n", info.Rules, info.Category, info.Name, info.Skipped)
+			}
+		}
+	}
+	scanner.Scoring = score.Default()
+	if *scoreModel != "" {
+		model, err := score.Load(*scoreModel)
+		if err != nil {
+			fmt.Printf("\033[31m[-]\033[37m Error loading score model: %v\n", err)
+			os.Exit(1)
+		}
+		scanner.Scoring = model
+	}
+	if *suppressions != "" {
+		list, err := suppress.Load(*suppressions)
+		if err != nil {
+			fmt.Printf("\033[31m[-]\033[37m Error loading suppressions: %v\n", err)
+			os.Exit(1)
+		}
+		if !*silent && !*majestic {
+			for _, r := range list.Expired(time.Now()) {
+				fmt.Printf("\033[33m[!]\033[37m Suppression expired on %s: %s\n", r.Expires, r.Justification)
+			}
+		}
+		scanner.Suppressions = list
+	}
+	scanner.ShowSuppressed = *showSupp
+	findings.ShowSuppressed = *showSupp
+	if patterns.ConfidenceRank(*minConfidence) == 0 {
+		fmt.Printf("\033[31m[-]\033[37m Unknown confidence level %q: use low, medium or high\n", *minConfidence)
+		os.Exit(1)
+	}
+	scanner.MinConfidence = *minConfidence
+	scanner.ChunkSize = *chunkSize << 10
+	scanner.MaxRedirects = *maxRedirects
+	scanner.Filter = local.Filter{Include: splitList(*include), Exclude: splitList(*exclude)}
+	if *checkBuckets {
+		scanner.Buckets = buckets.NewChecker()
+		scanner.Buckets.S3Endpoint = *s3Endpoint
+		scanner.Buckets.GCSEndpoint = *gcsEndpoint
+		scanner.Buckets.AzureEndpoint = *azureEndpoint
+	}
+	if *renderPages {
+		browser, err := render.Start(*chrome)
+		if err != nil {
+			fmt.Printf("\033[31m[-]\033[37m Error starting browser: %v\n", err)
+			os.Exit(1)
+		}
+		defer browser.Close()
+		scanner.Render = browser
+	}
+	urls := make(chan input.Target)
 
 	startTime := time.Now()
 
@@ -340,8 +1544,8 @@ func main() {
 	done := make(chan bool)
 	for i := 0; i < *thread; i++ {
 		go func() {
-			for url := range urls {
-				scanner.ProcessURL(url)
+			for target := range urls {
+				scanner.ProcessTarget(target)
 			}
 			done <- true
 		}()
@@ -350,28 +1554,61 @@ func main() {
 	// Handle different input modes
 	if *majestic {
 		// Handle Majestic Million mode
-		err := scanner.ProcessMajesticStream(urls, *percent)
+		err := scanner.ProcessMajesticStream(urls, *percent, *www)
 		if err != nil {
 			fmt.Println("\033[31m[-]\033[37m Error processing Majestic Million list:", err)
 			os.Exit(1)
 		}
-	} else if len(flag.Args()) > 0 {
-		// Handle command line arguments
-		for _, arg := range flag.Args() {
-			// If it's a file path without protocol, add file:// prefix
-			if !strings.HasPrefix(arg, "http://") && !strings.HasPrefix(arg, "https://") && !strings.HasPrefix(arg, "file://") {
-				absPath, err := filepath.Abs(arg)
-				if err == nil {
-					arg = "file://" + absPath
+	} else {
+		normalizer := &input.Normalizer{WWW: *www}
+		feed := func(record input.Record) {
+			targets, err := normalizer.Normalize(record.Input)
+			if err != nil {
+				if !*silent {
+					fmt.Printf("\033[31m[-]\033[37m Skipping invalid input %q: %v\n", strings.TrimSpace(record.Input), err)
+				}
+				return
+			}
+			for _, t := range targets {
+				t.Tags = record.Tags
+				if !t.Local {
+					urls <- t
+					continue
+				}
+				// Local paths are walked here and their files scanned by
+				// the workers
+				err := local.Walk(t.URL, scanner.Filter, func(path string) error {
+					urls <- input.Target{URL: path, Tags: t.Tags}
+					return nil
+				})
+				if err != nil {
+					fmt.Printf("\033[31m[-]\033[37m Error reading %s: %v\n", t.URL, err)
 				}
 			}
-			urls <- arg
 		}
-	} else {
-		// Handle stdin mode
-		stdinScanner := bufio.NewScanner(os.Stdin)
-		for stdinScanner.Scan() {
-			urls <- stdinScanner.Text()
+		switch {
+		case *format != input.FormatText && len(flag.Args()) > 0:
+			// Handle input files from other tools
+			for _, name := range flag.Args() {
+				file, err := os.Open(name)
+				if err == nil {
+					err = input.Read(file, *format, *column, feed)
+					file.Close()
+				}
+				if err != nil {
+					fmt.Printf("\033[31m[-]\033[37m Error reading %s: %v\n", name, err)
+				}
+			}
+		case len(flag.Args()) > 0:
+			// Handle command line arguments
+			for _, arg := range flag.Args() {
+				feed(input.Record{Input: arg})
+			}
+		default:
+			// Handle stdin mode
+			if err := input.Read(os.Stdin, *format, *column, feed); err != nil {
+				fmt.Printf("\033[31m[-]\033[37m Error reading input: %v\n", err)
+			}
 		}
 	}
 
@@ -386,6 +1623,7 @@ func main() {
 		duration := time.Since(startTime)
 		fmt.Printf("\n\033[34m[*]\033[37m Scan completed in %.2f seconds\n", duration.Seconds())
 		printStats(stats)
+		printScores(findings.Scores())
 	}
 
 	if outputFile != "" && !*silent && !*majestic {
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/models"
+	"github.com/gregcmartin/spectre/thirdparty"
+	"github.com/gregcmartin/spectre/versions"
+)
+
+func TestScanReaderSingleLine(t *testing.T) {
+	// A minified page on one line, so windows are cut at spaces
+	page := `<html><body><iframe src="https://frame.example.net/" style="display:none"></iframe> ` +
+		strings.Repeat("<p>filler text</p> ", 120) +
+		`<script src="https://cdn.thirdhost.net/app.js"></script> ` +
+		strings.Repeat("<p>filler text</p> ", 120) + `</body></html>`
+
+	for _, chunkSize := range []int{0, 1024} {
+		s := NewScanner(models.NewStatistics(), models.NewFindings(), true, false, false, "", nil)
+		s.Trackers = thirdparty.Default()
+		s.ChunkSize, s.Overlap = chunkSize, 256
+		if _, _, err := s.ScanReader("https://www.example.com/", strings.NewReader(page)); err != nil {
+			t.Fatal(err)
+		}
+
+		found := make(map[string]int)
+		for _, f := range s.Findings.ForURL("https://www.example.com/") {
+			found[f.Category+"/"+f.Value]++
+		}
+		if found["HiddenIframe/"+`<iframe src="https://frame.example.net/" style="display:none">`] != 1 {
+			t.Errorf("chunk size %d: expected the hidden iframe once, got %v", chunkSize, found)
+		}
+		if found["ThirdParty/thirdhost.net"] != 1 {
+			t.Errorf("chunk size %d: expected the third-party script once, got %v", chunkSize, found)
+		}
+	}
+}
+
+func TestScanReaderMarkup(t *testing.T) {
+	// The generator tag and core assets come after the first window
+	page := "<html><head>\n" + strings.Repeat("<link rel=\"preload\" href=\"/fonts/a.woff2\">\n", 60) +
+		`<meta name="generator" content="WordPress 6.4.2">` + "\n" +
+		`<script src="/wp-includes/js/jquery/jquery.min.js?ver=6.4.2"></script>` + "\n</head></html>"
+
+	s := NewScanner(models.NewStatistics(), models.NewFindings(), true, false, false, "", nil)
+	s.Trackers = thirdparty.Default()
+	s.Versions = versions.Default()
+	s.ChunkSize, s.Overlap = 1024, 256
+	markup, found, err := s.ScanReader("https://www.example.com/", strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := markup.Meta["generator"]; len(got) != 1 || got[0] != "WordPress 6.4.2" {
+		t.Errorf("Unexpected generator meta: %v", got)
+	}
+	if len(markup.Scripts) != 1 {
+		t.Errorf("Unexpected scripts: %v", markup.Scripts)
+	}
+	if len(found) != 1 || found[0].CMS != "WordPress" || found[0].Version != "6.4.2" {
+		t.Errorf("Unexpected versions: %+v", found)
+	}
+}
@@ -0,0 +1,342 @@
+package matcher
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// minLiteral is the shortest literal worth prefiltering on; shorter ones
+// appear in nearly every page
+const minLiteral = 3
+
+// Matcher decides which of a set of regexes can match a document. Each
+// regex is reduced to literals at least one of which must occur in any
+// match, and all literals are found in a single Aho-Corasick pass. Regexes
+// without usable literals are always candidates.
+type Matcher struct {
+	ac     *automaton
+	always []bool
+}
+
+// New builds a Matcher for the regexes, whose indexes are kept in the
+// result of Candidates. Nil entries never match.
+func New(res []*regexp.Regexp) *Matcher {
+	m := &Matcher{always: make([]bool, len(res))}
+	var literals []string
+	var owners []int
+	for i, re := range res {
+		if re == nil {
+			continue
+		}
+		lits, ok := Literals(re.String())
+		if !ok {
+			m.always[i] = true
+			continue
+		}
+		for _, lit := range lits {
+			literals = append(literals, lit)
+			owners = append(owners, i)
+		}
+	}
+	m.ac = newAutomaton(literals, owners)
+	return m
+}
+
+// Candidates reports, per regex, whether it may match content. Regexes
+// reported false cannot match. A nil Matcher returns nil.
+func (m *Matcher) Candidates(content string) []bool {
+	if m == nil {
+		return nil
+	}
+	result := make([]bool, len(m.always))
+	copy(result, m.always)
+	m.ac.scan(content, result)
+	return result
+}
+
+// Literals returns lowercased literals one of which occurs in every match
+// of the pattern, ignoring case. It reports false when no such set of
+// literals of useful length exists.
+func Literals(pattern string) ([]string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	lits, ok := required(re.Simplify())
+	if !ok {
+		return nil, false
+	}
+	sort.Strings(lits)
+	return dedupe(lits), true
+}
+
+// required returns literals one of which must occur in any match of re
+func required(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		lit := string(re.Rune)
+		if len(lit) < minLiteral || !ascii(lit) {
+			return nil, false
+		}
+		return []string{strings.ToLower(lit)}, true
+	case syntax.OpCapture:
+		return required(re.Sub[0])
+	case syntax.OpPlus:
+		return required(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min < 1 {
+			return nil, false
+		}
+		return required(re.Sub[0])
+	case syntax.OpConcat:
+		// Any one required part will do; prefer the part whose shortest
+		// literal is longest, as it is the most selective. Runs of parts
+		// with a small exact set of strings, such as g(?:a\.js|tag), are
+		// expanded into their concatenations.
+		var best []string
+		consider := func(lits []string) {
+			if len(lits) > 0 && shortest(lits) >= minLiteral && (best == nil || shortest(lits) > shortest(best)) {
+				best = lits
+			}
+		}
+		var run []string
+		for _, sub := range re.Sub {
+			if set, ok := exact(sub); ok {
+				if run = product(run, set); run == nil {
+					run = set
+				}
+				continue
+			}
+			consider(run)
+			run = nil
+			if lits, ok := required(sub); ok {
+				consider(lits)
+			}
+		}
+		consider(run)
+		return best, best != nil
+	case syntax.OpAlternate:
+		var all []string
+		for _, sub := range re.Sub {
+			lits, ok := required(sub)
+			if !ok {
+				return nil, false
+			}
+			all = append(all, lits...)
+		}
+		return all, true
+	}
+	return nil, false
+}
+
+// maxExact bounds the size of exact string sets
+const maxExact = 64
+
+// exact returns the complete, lowercased set of strings re matches, when
+// it is small
+func exact(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		lit := string(re.Rune)
+		if !ascii(lit) {
+			return nil, false
+		}
+		return []string{strings.ToLower(lit)}, true
+	case syntax.OpCharClass:
+		var set []string
+		for i := 0; i < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if r >= 0x80 || len(set) >= 8 {
+					return nil, false
+				}
+				set = append(set, strings.ToLower(string(r)))
+			}
+		}
+		sort.Strings(set)
+		return dedupe(set), len(set) > 0
+	case syntax.OpCapture:
+		return exact(re.Sub[0])
+	case syntax.OpConcat:
+		var set []string
+		for _, sub := range re.Sub {
+			sub, ok := exact(sub)
+			if !ok {
+				return nil, false
+			}
+			if set = product(set, sub); set == nil {
+				set = sub
+			}
+			if len(set) > maxExact {
+				return nil, false
+			}
+		}
+		return set, set != nil
+	case syntax.OpAlternate:
+		var set []string
+		for _, sub := range re.Sub {
+			sub, ok := exact(sub)
+			if !ok {
+				return nil, false
+			}
+			set = append(set, sub...)
+		}
+		return set, len(set) <= maxExact
+	}
+	return nil, false
+}
+
+// product concatenates every string of a with every string of b. It
+// returns nil when a is empty or the result would be too large.
+func product(a, b []string) []string {
+	if len(a) == 0 || len(a)*len(b) > maxExact {
+		return nil
+	}
+	out := make([]string, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			out = append(out, x+y)
+		}
+	}
+	return out
+}
+
+func shortest(lits []string) int {
+	n := -1
+	for _, l := range lits {
+		if n < 0 || len(l) < n {
+			n = len(l)
+		}
+	}
+	return n
+}
+
+func ascii(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// automaton is an Aho-Corasick automaton over ASCII-lowercased bytes
+type automaton struct {
+	next  []map[byte]int32
+	fail  []int32
+	out   [][]int // regex indexes whose literal ends at the state
+	root  [256]int32
+	empty bool
+}
+
+func newAutomaton(literals []string, owners []int) *automaton {
+	a := &automaton{
+		next:  []map[byte]int32{{}},
+		fail:  []int32{0},
+		out:   [][]int{nil},
+		empty: len(literals) == 0,
+	}
+	for i, lit := range literals {
+		state := int32(0)
+		for j := 0; j < len(lit); j++ {
+			c := lit[j]
+			nextState, ok := a.next[state][c]
+			if !ok {
+				nextState = int32(len(a.next))
+				a.next = append(a.next, map[byte]int32{})
+				a.fail = append(a.fail, 0)
+				a.out = append(a.out, nil)
+				a.next[state][c] = nextState
+			}
+			state = nextState
+		}
+		a.out[state] = append(a.out[state], owners[i])
+	}
+
+	// Breadth-first failure links
+	var queue []int32
+	for c, s := range a.next[0] {
+		a.root[c] = s
+		queue = append(queue, s)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for c, child := range a.next[state] {
+			queue = append(queue, child)
+			f := a.fail[state]
+			for f != 0 {
+				if s, ok := a.next[f][c]; ok {
+					a.fail[child] = s
+					break
+				}
+				f = a.fail[f]
+			}
+			if f == 0 {
+				if s, ok := a.next[0][c]; ok && s != child {
+					a.fail[child] = s
+				}
+			}
+			a.out[child] = append(a.out[child], a.out[a.fail[child]]...)
+		}
+	}
+	return a
+}
+
+// scan marks the owners of every literal found in content
+func (a *automaton) scan(content string, found []bool) {
+	if a.empty {
+		return
+	}
+	state := int32(0)
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		for {
+			if state == 0 {
+				state = a.root[c]
+				break
+			}
+			if s, ok := a.next[state][c]; ok {
+				state = s
+				break
+			}
+			state = a.fail[state]
+		}
+		for _, owner := range a.out[state] {
+			found[owner] = true
+		}
+	}
+}
+
+// Lines maps byte offsets in a document to line numbers
+type Lines []int
+
+// NewLines indexes the line breaks of content
+func NewLines(content string) Lines {
+	lines := Lines{}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i)
+		}
+	}
+	return lines
+}
+
+// Line returns the 1-based line of the byte at offset
+func (l Lines) Line(offset int) int {
+	return sort.SearchInts(l, offset) + 1
+}
@@ -0,0 +1,153 @@
+package matcher
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+func TestLiterals(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		ok      bool
+	}{
+		{`(?i)static\.hotjar\.com|_hjSettings`, []string{"_hjsettings", "static.hotjar.com"}, true},
+		{`\b(GTM-[A-Z0-9]{4,9})\b`, []string{"gtm-"}, true},
+		{`fbq\(\s*['"]init['"]\s*,\s*['"](\d{15,16})['"]`, []string{`"init"`, `"init'`, `'init"`, `'init'`}, true},
+		{`(?i)ga\.js|gtag`, []string{"ga.js", "gtag"}, true},
+		{`\bSK[0-9a-f]{32}\b`, nil, false},
+		{`(?i)wp|wordpress_`, nil, false},
+		{`(?:secret)?[A-Za-z0-9]{20,}`, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := Literals(tt.pattern)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("Literals(%q) = %v, %v; want %v, %v", tt.pattern, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	res := []*regexp.Regexp{
+		regexp.MustCompile(`(?i)static\.hotjar\.com`),
+		regexp.MustCompile(`\bSK[0-9a-f]{32}\b`),
+		regexp.MustCompile(`snap\.licdn\.com`),
+		nil,
+		regexp.MustCompile(`(?i)ers|she|his`),
+	}
+	m := New(res)
+	got := m.Candidates(`<script src="https://STATIC.HOTJAR.COM/c.js"></script> ushers`)
+	want := []bool{true, true, false, false, true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+// TestPrefilterIsSound checks that no pattern match is ever filtered out
+func TestPrefilterIsSound(t *testing.T) {
+	res := compiledPatterns()
+	m := New(res)
+	for name, content := range corpus(t) {
+		candidates := m.Candidates(content)
+		for i, re := range res {
+			if !candidates[i] && re.MatchString(content) {
+				t.Errorf("%s: %s matches but was filtered out", name, patterns.AllPatternTypes[i].Name)
+			}
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	content := "one\ntwo\n\nfour"
+	lines := NewLines(content)
+	for offset, want := range map[int]int{0: 1, 3: 1, 4: 2, 8: 3, 9: 4, 12: 4} {
+		if got := lines.Line(offset); got != want {
+			t.Errorf("Line(%d) = %d, want %d", offset, got, want)
+		}
+	}
+}
+
+func compiledPatterns() []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, pt := range patterns.AllPatternTypes {
+		var re *regexp.Regexp
+		if pt.Pattern != "" {
+			re = regexp.MustCompile(pt.Pattern)
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+// corpus returns test.html, a sample of the Majestic Million results and a
+// page without trackers
+func corpus(tb testing.TB) map[string]string {
+	page, err := os.ReadFile("../test.html")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return map[string]string{
+		"test.html": string(page),
+		"majestic":  majesticSample(tb, 1<<18),
+		"plain":     strings.Repeat("<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>\n", 4096),
+	}
+}
+
+func majesticSample(tb testing.TB, size int64) string {
+	r, err := zip.OpenReader("../majestic_results.json.zip")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != "majestic_results.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, size))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return string(data)
+	}
+	tb.Fatal("majestic_results.json not found")
+	return ""
+}
+
+func benchmarkScan(b *testing.B, name string, prefilter bool) {
+	content := corpus(b)[name]
+	res := compiledPatterns()
+	m := New(res)
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var candidates []bool
+		if prefilter {
+			candidates = m.Candidates(content)
+		}
+		for j, re := range res {
+			if re == nil || (prefilter && !candidates[j]) {
+				continue
+			}
+			re.FindAllStringIndex(content, -1)
+		}
+	}
+}
+
+func BenchmarkRegexTestHTML(b *testing.B)     { benchmarkScan(b, "test.html", false) }
+func BenchmarkPrefilterTestHTML(b *testing.B) { benchmarkScan(b, "test.html", true) }
+func BenchmarkRegexMajestic(b *testing.B)     { benchmarkScan(b, "majestic", false) }
+func BenchmarkPrefilterMajestic(b *testing.B) { benchmarkScan(b, "majestic", true) }
+func BenchmarkRegexPlain(b *testing.B)        { benchmarkScan(b, "plain", false) }
+func BenchmarkPrefilterPlain(b *testing.B)    { benchmarkScan(b, "plain", true) }
@@ -11,11 +11,14 @@ import (
 
 // Statistics tracks scanning metrics
 type Statistics struct {
-	ScannedURLs    int64
-	ProcessedBytes int64
-	FoundSecrets   int64
-	Categories     map[string]int
-	mu             sync.Mutex
+	ScannedURLs       int64
+	ProcessedBytes    int64
+	FoundSecrets      int64
+	FirstPartyCookies int64
+	ThirdPartyCookies int64
+	Suppressed        int64
+	Categories        map[string]int
+	mu                sync.Mutex
 }
 
 // Finding represents a single detected item
@@ -26,24 +29,117 @@ type Finding struct {
 	Location       string            `json:"location"`
 	Description    string            `json:"description"`
 	RiskLevel      string            `json:"risk_level"`
+	Confidence     string            `json:"confidence"` // low, medium or high
 	Impact         string            `json:"impact"`
 	Implementation map[string]string `json:"implementation,omitempty"`
+	InputURL       string            `json:"input_url,omitempty"`  // scanned URL when it redirected elsewhere
+	Suppressed     string            `json:"suppressed,omitempty"` // justification of the matching suppression
+	Tags           map[string]string `json:"tags,omitempty"`       // of the input the finding was scanned from
 }
 
-// URLFindings represents all findings for a URL
+// Cookie describes a cookie or web storage entry set by a scanned site
+type Cookie struct {
+	Name       string `json:"name"`
+	Domain     string `json:"domain"`
+	Path       string `json:"path,omitempty"`
+	Expires    string `json:"expires,omitempty"` // RFC 3339, empty for session cookies
+	Secure     bool   `json:"secure"`
+	HttpOnly   bool   `json:"http_only"`
+	SameSite   string `json:"same_site,omitempty"`
+	Size       int    `json:"size"`
+	Source     string `json:"source"` // header, script, localStorage or sessionStorage
+	Vendor     string `json:"vendor,omitempty"`
+	Category   string `json:"category,omitempty"`
+	ThirdParty bool   `json:"third_party"`
+}
+
+// HeaderAnalysis is the security header posture of a response
+type HeaderAnalysis struct {
+	Score       int               `json:"score"`
+	Grade       string            `json:"grade"`
+	Headers     map[string]string `json:"headers"`
+	Issues      []string          `json:"issues,omitempty"`
+	CSPDomains  []string          `json:"csp_domains,omitempty"`
+	CSPTrackers map[string]string `json:"csp_trackers,omitempty"` // allowed host to vendor
+}
+
+// ContentInfo records how a response body was decoded for scanning
+type ContentInfo struct {
+	ContentType   string `json:"content_type,omitempty"`
+	Encoding      string `json:"content_encoding,omitempty"` // encodings undone, e.g. "gzip" or "gzip (sniffed)"
+	Charset       string `json:"charset"`
+	CharsetSource string `json:"charset_source"` // bom, header, meta, xml or default
+}
+
+// Redirect is one redirect response followed on the way to a final URL
+type Redirect struct {
+	URL        string   `json:"url"`
+	Status     int      `json:"status"`
+	Location   string   `json:"location"`
+	Cookies    []string `json:"cookies,omitempty"` // names of cookies set by the hop
+	ThirdParty bool     `json:"third_party"`       // hop is on neither the input nor the final site
+}
+
+// ThirdPartyDomain is another site a page loads resources from or submits
+// forms to, grouped by registrable domain
+type ThirdPartyDomain struct {
+	Domain   string   `json:"domain"`
+	Hosts    []string `json:"hosts"`
+	Kinds    []string `json:"kinds"` // script, img, iframe, link preload, form, ...
+	Requests int      `json:"requests"`
+	Company  string   `json:"company,omitempty"`
+	Category string   `json:"category,omitempty"`
+}
+
+// ThirdPartyInventory separates known tracker domains from unclassified ones
+type ThirdPartyInventory struct {
+	Known        []ThirdPartyDomain `json:"known"`
+	Unclassified []ThirdPartyDomain `json:"unclassified"`
+}
+
+// Deduction is the number of points a category or bonus cost a score
+type Deduction struct {
+	Reason string  `json:"reason"`
+	Points float64 `json:"points"`
+}
+
+// Score is a 0-100 privacy and security score for a URL, where 100 means
+// nothing was found
+type Score struct {
+	Score      int         `json:"score"`
+	Grade      string      `json:"grade"`
+	Deductions []Deduction `json:"deductions,omitempty"` // largest first
+}
+
+// URLFindings represents all findings for a URL. Once a URL is scanned, the
+// rest is written to the JSON output as a record of its own.
 type URLFindings struct {
-	URL      string    `json:"url"`
-	Findings []Finding `json:"findings"`
+	URL               string               `json:"url"`
+	InputURL          string               `json:"input_url,omitempty"` // when redirected to URL
+	Tags              map[string]string    `json:"tags,omitempty"`      // from structured input, e.g. asset IDs
+	Redirects         []Redirect           `json:"redirects,omitempty"`
+	Findings          []Finding            `json:"findings,omitempty"`
+	Cookies           []Cookie             `json:"cookies,omitempty"`
+	FirstPartyCookies int                  `json:"first_party_cookies"`
+	ThirdPartyCookies int                  `json:"third_party_cookies"`
+	Headers           *HeaderAnalysis      `json:"headers,omitempty"`
+	Content           *ContentInfo         `json:"content,omitempty"`
+	ThirdParties      *ThirdPartyInventory `json:"third_parties,omitempty"`
+	Score             *Score               `json:"score,omitempty"`
+	finished          bool
 }
 
 // Findings manages all scan findings
 type Findings struct {
 	Items         []URLFindings
+	index         map[string]int // URL to its position in Items
 	jsonFile      *os.File
 	encoder       *json.Encoder
 	mu            sync.Mutex
 	uniqueEntries map[string]bool // Track unique findings
 	writtenKeys   map[string]bool // Track findings already written to JSON
+	// ShowSuppressed writes suppressed findings to the JSON file
+	ShowSuppressed bool
 }
 
 // NewStatistics creates a new Statistics instance
@@ -69,10 +165,29 @@ func (s *Statistics) IncrementScanned(bytes int64) {
 	s.ScannedURLs++
 }
 
+// IncrementCookies increases the first- or third-party cookie count
+func (s *Statistics) IncrementCookies(thirdParty bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if thirdParty {
+		s.ThirdPartyCookies++
+	} else {
+		s.FirstPartyCookies++
+	}
+}
+
+// IncrementSuppressed increases the suppressed finding count
+func (s *Statistics) IncrementSuppressed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Suppressed++
+}
+
 // NewFindings creates a new Findings instance
 func NewFindings() *Findings {
 	return &Findings{
 		Items:         make([]URLFindings, 0),
+		index:         make(map[string]int),
 		uniqueEntries: make(map[string]bool),
 		writtenKeys:   make(map[string]bool),
 	}
@@ -98,6 +213,218 @@ func (f *Findings) CloseJSONFile() {
 	}
 }
 
+// AddCookie records a cookie for a URL and updates its first- and
+// third-party counts. It reports false if the cookie was already recorded.
+func (f *Findings) AddCookie(url string, cookie Cookie) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := fmt.Sprintf("%s:cookie:%s:%s:%s:%s", url, cookie.Source, cookie.Name, cookie.Domain, cookie.Path)
+	if f.uniqueEntries[key] {
+		return false
+	}
+	f.uniqueEntries[key] = true
+
+	urlFindings := f.urlFindings(url)
+	urlFindings.Cookies = append(urlFindings.Cookies, cookie)
+	if cookie.ThirdParty {
+		urlFindings.ThirdPartyCookies++
+	} else {
+		urlFindings.FirstPartyCookies++
+	}
+	return true
+}
+
+// SetHeaders records the security header analysis for a URL
+func (f *Findings) SetHeaders(url string, analysis HeaderAnalysis) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.urlFindings(url).Headers = &analysis
+}
+
+// Finish records how the body of a scanned URL was decoded, which may be
+// nil, and writes everything recorded for it but its findings to the JSON
+// output. URLs with nothing recorded have no record. Its redirects,
+// cookies, headers, content and third parties are then dropped from memory;
+// its findings, input URL, tags and score are kept for the summary and
+// scores. A URL is written once.
+func (f *Findings) Finish(url string, content *ContentInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	urlFindings := f.find(url)
+	if urlFindings == nil || urlFindings.finished {
+		return
+	}
+	urlFindings.Content = content
+	if f.encoder != nil {
+		record := *urlFindings
+		record.Findings = nil
+		f.encoder.Encode(record)
+	}
+	urlFindings.finished = true
+	urlFindings.Redirects = nil
+	urlFindings.Cookies = nil
+	urlFindings.Headers = nil
+	urlFindings.Content = nil
+	urlFindings.ThirdParties = nil
+}
+
+// SetRedirects records the redirects followed from an input URL to url.
+// Findings for url added afterwards keep the input URL and its tags.
+func (f *Findings) SetRedirects(url, inputURL string, redirects []Redirect) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var tags map[string]string
+	if input := f.find(inputURL); input != nil {
+		tags = input.Tags
+	}
+	urlFindings := f.urlFindings(url)
+	if inputURL != url {
+		urlFindings.InputURL = inputURL
+	}
+	if urlFindings.Tags == nil {
+		urlFindings.Tags = tags
+	}
+	urlFindings.Redirects = redirects
+}
+
+// SetTags records the tags of the input a URL was read from. Findings for
+// url added afterwards carry them.
+func (f *Findings) SetTags(url string, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	urlFindings := f.urlFindings(url)
+	urlFindings.Tags = make(map[string]string, len(tags))
+	for k, v := range tags {
+		urlFindings.Tags[k] = v
+	}
+}
+
+// AddThirdParty merges a third-party domain into a URL's inventory, listing
+// it as known when it has a company. It reports false if the domain was
+// already recorded.
+func (f *Findings) AddThirdParty(url string, domain ThirdPartyDomain) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	urlFindings := f.urlFindings(url)
+	if urlFindings.ThirdParties == nil {
+		urlFindings.ThirdParties = &ThirdPartyInventory{Known: []ThirdPartyDomain{}, Unclassified: []ThirdPartyDomain{}}
+	}
+	list := &urlFindings.ThirdParties.Unclassified
+	if domain.Company != "" {
+		list = &urlFindings.ThirdParties.Known
+	}
+	for i := range *list {
+		existing := &(*list)[i]
+		if existing.Domain == domain.Domain {
+			existing.Hosts = union(existing.Hosts, domain.Hosts)
+			existing.Kinds = union(existing.Kinds, domain.Kinds)
+			existing.Requests += domain.Requests
+			return false
+		}
+	}
+	*list = append(*list, domain)
+	return true
+}
+
+// SetScore records the privacy score of a URL
+func (f *Findings) SetScore(url string, score Score) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.urlFindings(url).Score = &score
+}
+
+// union returns the sorted union of two sorted lists
+func union(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			out = append(out, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// urlFindings returns the entry for a URL, creating it if needed.
+// Callers must hold f.mu.
+func (f *Findings) urlFindings(url string) *URLFindings {
+	if urlFindings := f.find(url); urlFindings != nil {
+		return urlFindings
+	}
+	f.index[url] = len(f.Items)
+	f.Items = append(f.Items, URLFindings{URL: url, Findings: []Finding{}})
+	return &f.Items[len(f.Items)-1]
+}
+
+// find returns the entry for a URL, or nil. Callers must hold f.mu.
+func (f *Findings) find(url string) *URLFindings {
+	if i, ok := f.index[url]; ok {
+		return &f.Items[i]
+	}
+	return nil
+}
+
+// Get returns a copy of everything recorded for a URL. It reports false if
+// nothing was recorded.
+func (f *Findings) Get(url string) (URLFindings, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	urlFindings := f.find(url)
+	if urlFindings == nil {
+		return URLFindings{}, false
+	}
+	item := *urlFindings
+	item.Findings = append([]Finding(nil), item.Findings...)
+	return item, true
+}
+
+// Scores returns the score of every scored URL
+func (f *Findings) Scores() []URLFindings {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var scored []URLFindings
+	for _, item := range f.Items {
+		if item.Score != nil {
+			scored = append(scored, URLFindings{URL: item.URL, InputURL: item.InputURL, Tags: item.Tags, Score: item.Score})
+		}
+	}
+	return scored
+}
+
+// ForURL returns a copy of the findings recorded for a URL
+func (f *Findings) ForURL(url string) []Finding {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if urlFindings := f.find(url); urlFindings != nil {
+		return append([]Finding(nil), urlFindings.Findings...)
+	}
+	return nil
+}
+
+// Describe returns the description, risk level and impact given to
+// findings of a pattern type
+func Describe(category, patternType string) (description, risk, impact string) {
+	return getDescription(category, patternType), getRiskLevel(category), getImpact(category)
+}
+
 // getDescription returns a description based on category and pattern type
 func getDescription(category, patternType string) string {
 	descriptions := map[string]map[string]string{
@@ -117,6 +444,11 @@ func getDescription(category, patternType string) string {
 			"Magento":     "Magento e-commerce system elements and features",
 			"Wix":         "Wix website builder platform components and tools",
 			"Squarespace": "Squarespace website platform elements and functionality",
+			"Webflow":     "Webflow website builder components and hosted assets",
+			"BigCommerce": "BigCommerce e-commerce platform storefront components",
+			"PrestaShop":  "PrestaShop e-commerce system modules and configuration",
+			"TYPO3":       "TYPO3 content management system extensions and configuration",
+			"HubSpot CMS": "HubSpot CMS hosted site components and files",
 		},
 		"CloudStorage": {
 			"AWS S3 Bucket":        "Amazon Web Services S3 storage bucket configuration and access",
@@ -148,8 +480,9 @@ func getDescription(category, patternType string) string {
 			"Tidio":    "Tidio live chat and chatbot platform",
 		},
 		"HiddenIframe": {
-			"Hidden Iframe":         "Hidden iframe using CSS display or visibility properties",
-			"Zero Size Iframe":      "Zero-sized iframe with width or height set to 0",
+			"Hidden Iframe":         "Hidden iframe using CSS display, visibility, opacity or the hidden attribute",
+			"Off-screen Iframe":     "Iframe positioned outside the visible page",
+			"Zero Size Iframe":      "Zero-sized iframe with width or height set to 0 or 1 pixel",
 			"Dynamic Hidden Iframe": "Dynamically created hidden iframe using JavaScript",
 		},
 		"Tracking": {
@@ -180,6 +513,60 @@ func getDescription(category, patternType string) string {
 			"VWO":             "Visual Website Optimizer A/B testing platform",
 			"Google Optimize": "Google Optimize A/B testing and personalization tool",
 		},
+		"TrackerID": {
+			"Google Analytics ID":   "Google Analytics property or measurement ID tied to a single account",
+			"Google Tag Manager ID": "Google Tag Manager container ID tied to a single account",
+			"Facebook Pixel ID":     "Facebook pixel ID tied to a single advertiser account",
+			"AdSense Publisher ID":  "Google AdSense publisher ID tied to a single payee account",
+		},
+		"Secrets": {
+			"AWS Access Key ID":         "Amazon Web Services access key ID for programmatic account access",
+			"AWS Secret Access Key":     "Amazon Web Services secret access key paired with an access key ID",
+			"GCP API Key":               "Google Cloud Platform API key for Google APIs and services",
+			"GCP Service Account Key":   "Google Cloud service account JSON key file contents",
+			"Azure Storage Account Key": "Microsoft Azure storage account shared key from a connection string",
+			"Stripe Secret Key":         "Stripe secret or restricted API key for payment operations",
+			"Twilio API Key":            "Twilio API key SID used for messaging and voice APIs",
+			"Slack Token":               "Slack bot, user or app token for workspace API access",
+			"Slack Webhook":             "Slack incoming webhook URL that can post messages to a channel",
+			"Private Key":               "PEM-encoded private key material",
+			"JWT":                       "JSON Web Token carrying signed authentication claims",
+			"Generic High Entropy":      "High-entropy value assigned to a secret, token or password field",
+		},
+		"ConsentCompliance": {
+			"Tracker Without CMP":    "Tracker loaded on a page without any consent management platform",
+			"Tracker Before CMP":     "Tracker placed ahead of the consent management platform in the page source",
+			"Ungated Tracker":        "Tracker loaded without the markup the consent management platform uses to block it until consent",
+			"Tracker Before Consent": "Tracker requested by the rendered page before its consent banner was accepted",
+		},
+		"Cookies": {
+			"Unclassified Cookie": "Cookie or web storage entry not attributed to a known vendor",
+		},
+		"ThirdParty": {
+			"Known Tracker Domain":            "Third-party domain listed as a tracker",
+			"Unclassified Third-Party Domain": "Third-party domain not on the tracker list, which may be a CDN, an embed or an unlisted tracker",
+		},
+		"Redirects": {
+			"Third-Party Redirect":  "Redirect chain passes through a site other than the requested and final ones",
+			"Cross-Domain Redirect": "Requested URL redirects to a different site",
+			"HTTPS Downgrade":       "Redirect chain moves from HTTPS to plain HTTP",
+		},
+		"Score": {
+			"Privacy Score": "Overall privacy and security score of the page, from 100 down to 0",
+		},
+		"SecurityHeaders": {
+			"Header Score":                    "Overall score of the response's security headers",
+			"Missing Content-Security-Policy": "No Content-Security-Policy restricts where scripts and other resources load from",
+			"Weak Content-Security-Policy":    "Content-Security-Policy allows unsafe script sources",
+			"Missing HSTS":                    "No Strict-Transport-Security header forces HTTPS connections",
+			"Weak HSTS":                       "Strict-Transport-Security is too short-lived or does not cover subdomains",
+			"Missing X-Frame-Options":         "Neither X-Frame-Options nor CSP frame-ancestors prevents framing",
+			"Missing Referrer-Policy":         "No Referrer-Policy controls what URL information leaks to third parties",
+			"Weak Referrer-Policy":            "Referrer-Policy sends full URLs to third parties",
+			"Missing Permissions-Policy":      "No Permissions-Policy restricts browser features for embedded content",
+			"Server Version Disclosure":       "Server or X-Powered-By header discloses software versions",
+			"CSP Whitelisted Tracker":         "Content-Security-Policy explicitly allows a detected tracker's domain",
+		},
 	}
 
 	if categoryDesc, ok := descriptions[category]; ok {
@@ -187,6 +574,12 @@ func getDescription(category, patternType string) string {
 			return desc
 		}
 	}
+	if category == "Cookies" {
+		return "Cookie or web storage entry set by " + patternType
+	}
+	if category == "CMSVersion" {
+		return patternType + " release identified from the page or version files"
+	}
 	return "Generic tracking or advertising component"
 }
 
@@ -205,6 +598,15 @@ func getRiskLevel(category string) string {
 		"SessionRecording":  "Medium",
 		"ErrorTracking":     "Low",
 		"ABTesting":         "Low",
+		"TrackerID":         "Medium",
+		"Secrets":           "High",
+		"ConsentCompliance": "High",
+		"Cookies":           "Low",
+		"SecurityHeaders":   "Low",
+		"CMSVersion":        "Low",
+		"Redirects":         "Medium",
+		"ThirdParty":        "Medium",
+		"Score":             "Low",
 	}
 
 	if risk, ok := risks[category]; ok {
@@ -228,6 +630,15 @@ func getImpact(category string) string {
 		"SessionRecording":  "Records and analyzes user interactions and behavior on the site",
 		"ErrorTracking":     "Collects application errors and debugging information",
 		"ABTesting":         "Enables website experimentation and user experience testing",
+		"TrackerID":         "Links the site to other properties operated by the same account owner",
+		"Secrets":           "Exposes credentials that may grant direct access to accounts, data or infrastructure",
+		"ConsentCompliance": "Sets tracking identifiers without prior consent, which may breach GDPR and ePrivacy requirements",
+		"Cookies":           "Stores identifiers in the browser that can recognise the visitor across page views and sites",
+		"SecurityHeaders":   "Weak browser security policies increase exposure to injection, framing and data leakage",
+		"CMSVersion":        "Outdated or vulnerable CMS releases can be matched to public exploits",
+		"Redirects":         "Redirect hops can set cookies and log visits for sites the user never chose to visit",
+		"ThirdParty":        "Every third-party request discloses the visit, IP address and often the page URL to another company",
+		"Score":             "Summarises how much the page exposes its visitors and itself, for comparison across sites",
 	}
 
 	if impact, ok := impacts[category]; ok {
@@ -253,11 +664,30 @@ func cleanValue(value string) string {
 
 // Add adds a new finding
 func (f *Findings) Add(url, category, patternType, value, location string) {
+	f.AddWithDetails(url, category, patternType, value, location, nil)
+}
+
+// AddWithDetails adds a new finding with extra implementation details
+func (f *Findings) AddWithDetails(url, category, patternType, value, location string, details map[string]string) {
+	f.AddFinding(url, Finding{
+		Category:       category,
+		PatternType:    patternType,
+		Value:          value,
+		Location:       location,
+		Implementation: details,
+	})
+}
+
+// AddFinding adds a finding built by the caller. Description, risk level and
+// impact are filled in from the category when left empty.
+func (f *Findings) AddFinding(url string, finding Finding) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	category, patternType := finding.Category, finding.PatternType
+
 	// Clean and process the value
-	cleanedValue := cleanValue(value)
+	cleanedValue := cleanValue(finding.Value)
 
 	// Create a unique key for this finding
 	key := fmt.Sprintf("%s:%s:%s:%s", url, category, patternType, cleanedValue)
@@ -268,52 +698,42 @@ func (f *Findings) Add(url, category, patternType, value, location string) {
 	}
 	f.uniqueEntries[key] = true
 
-	// Create implementation details if it's an iframe
 	var implementation map[string]string
-	if strings.Contains(strings.ToLower(patternType), "iframe") {
+	if len(finding.Implementation) > 0 {
 		implementation = make(map[string]string)
-		if strings.Contains(cleanedValue, "display:none") || strings.Contains(cleanedValue, "visibility:hidden") {
-			implementation["visibility"] = "hidden"
-		}
-		if strings.Contains(cleanedValue, "height=\"0\"") || strings.Contains(cleanedValue, "width=\"0\"") {
-			implementation["dimensions"] = "zero-sized"
-		}
-		if strings.Contains(cleanedValue, "googletagmanager") {
-			implementation["type"] = "Google Tag Manager container"
+		for k, v := range finding.Implementation {
+			implementation[k] = v
 		}
 	}
 
-	finding := Finding{
-		Category:       category,
-		PatternType:    patternType,
-		Value:          cleanedValue,
-		Location:       location,
-		Description:    getDescription(category, patternType),
-		RiskLevel:      getRiskLevel(category),
-		Impact:         getImpact(category),
-		Implementation: implementation,
-	}
-
-	// Find existing URL findings or create new
-	var urlFindings *URLFindings
-	for i := range f.Items {
-		if f.Items[i].URL == url {
-			urlFindings = &f.Items[i]
-			break
-		}
+	finding.Value = cleanedValue
+	finding.Implementation = implementation
+	if finding.Description == "" {
+		finding.Description = getDescription(category, patternType)
+	}
+	if finding.RiskLevel == "" {
+		finding.RiskLevel = getRiskLevel(category)
+	}
+	if finding.Impact == "" {
+		finding.Impact = getImpact(category)
+	}
+	// Only text pattern matches can be mistaken; findings from parsed
+	// markup, headers and cookies are certain
+	if finding.Confidence == "" {
+		finding.Confidence = "high"
 	}
 
-	if urlFindings == nil {
-		f.Items = append(f.Items, URLFindings{
-			URL:      url,
-			Findings: []Finding{finding},
-		})
-	} else {
-		urlFindings.Findings = append(urlFindings.Findings, finding)
+	urlFindings := f.urlFindings(url)
+	if finding.InputURL == "" {
+		finding.InputURL = urlFindings.InputURL
+	}
+	if finding.Tags == nil {
+		finding.Tags = urlFindings.Tags
 	}
+	urlFindings.Findings = append(urlFindings.Findings, finding)
 
 	// Write to JSON file if enabled, but only if we haven't written this finding before
-	if f.encoder != nil && !f.writtenKeys[key] {
+	if f.encoder != nil && !f.writtenKeys[key] && (finding.Suppressed == "" || f.ShowSuppressed) {
 		f.encoder.Encode(finding)
 		f.writtenKeys[key] = true
 	}
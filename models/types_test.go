@@ -1,7 +1,11 @@
 package models
 
 import (
+	"encoding/json"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -129,3 +133,108 @@ func TestStatistics(t *testing.T) {
 		t.Errorf("Expected ScannedURLs count of 2, got %d", stats.ScannedURLs)
 	}
 }
+
+func TestThirdPartyInventory(t *testing.T) {
+	findings := NewFindings()
+	url := "https://example.com"
+
+	if !findings.AddThirdParty(url, ThirdPartyDomain{Domain: "cdnhost.net", Hosts: []string{"img.cdnhost.net"}, Kinds: []string{"img"}, Requests: 2}) {
+		t.Error("Expected a new unclassified domain")
+	}
+	if findings.AddThirdParty(url, ThirdPartyDomain{Domain: "cdnhost.net", Hosts: []string{"a.cdnhost.net", "img.cdnhost.net"}, Kinds: []string{"script"}, Requests: 1}) {
+		t.Error("Expected the repeated domain to be merged")
+	}
+	findings.AddThirdParty(url, ThirdPartyDomain{Domain: "doubleclick.net", Hosts: []string{"doubleclick.net"}, Kinds: []string{"iframe"}, Requests: 1, Company: "Google"})
+
+	inventory := findings.Items[0].ThirdParties
+	if len(inventory.Known) != 1 || len(inventory.Unclassified) != 1 {
+		t.Fatalf("Expected 1 known and 1 unclassified domain, got %d and %d", len(inventory.Known), len(inventory.Unclassified))
+	}
+	cdn := inventory.Unclassified[0]
+	if cdn.Requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", cdn.Requests)
+	}
+	if strings.Join(cdn.Hosts, ",") != "a.cdnhost.net,img.cdnhost.net" || strings.Join(cdn.Kinds, ",") != "img,script" {
+		t.Errorf("Unexpected merged hosts %v or kinds %v", cdn.Hosts, cdn.Kinds)
+	}
+}
+
+func TestTags(t *testing.T) {
+	findings := NewFindings()
+	tags := map[string]string{"asset_id": "A-17"}
+	findings.SetTags("https://example.com", tags)
+	tags["asset_id"] = "changed"
+	findings.SetRedirects("https://www.example.com/", "https://example.com", nil)
+	findings.Add("https://www.example.com/", "CMS", "WordPress", "wp-content", "https://www.example.com/")
+	findings.Add("https://other.com", "CMS", "WordPress", "wp-content", "https://other.com")
+
+	item, _ := findings.Get("https://www.example.com/")
+	if item.Tags["asset_id"] != "A-17" || item.Findings[0].Tags["asset_id"] != "A-17" {
+		t.Errorf("Expected the redirect target and its finding to keep the input tags, got %v and %v", item.Tags, item.Findings[0].Tags)
+	}
+	if other := findings.ForURL("https://other.com"); other[0].Tags != nil {
+		t.Errorf("Expected no tags for an untagged URL, got %v", other[0].Tags)
+	}
+}
+
+func TestURLRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	findings := NewFindings()
+	if err := findings.InitJSONFile(path); err != nil {
+		t.Fatal(err)
+	}
+	url := "https://example.com/"
+	findings.SetTags("http://example.com", map[string]string{"asset_id": "A-1"})
+	findings.SetRedirects(url, "http://example.com", []Redirect{{URL: "http://example.com", Status: 301, Location: url, Cookies: []string{"sid"}}})
+	findings.Add(url, "CMS", "WordPress", "wp-content", url)
+	findings.AddThirdParty(url, ThirdPartyDomain{Domain: "doubleclick.net", Hosts: []string{"doubleclick.net"}, Kinds: []string{"iframe"}, Requests: 2, Company: "Google"})
+	findings.AddThirdParty(url, ThirdPartyDomain{Domain: "cdnhost.net", Hosts: []string{"img.cdnhost.net"}, Kinds: []string{"img"}, Requests: 1})
+	findings.AddCookie(url, Cookie{Name: "_ga", Domain: "example.com", Source: "script", Vendor: "Google Analytics"})
+	findings.AddCookie(url, Cookie{Name: "IDE", Domain: "doubleclick.net", Source: "header", ThirdParty: true})
+	findings.SetScore(url, Score{Score: 95, Grade: "A"})
+	findings.Finish(url, &ContentInfo{Encoding: "gzip", Charset: "iso-8859-1", CharsetSource: "header"})
+	findings.Finish(url, nil)
+	findings.Finish("https://empty.example/", &ContentInfo{Charset: "utf-8"})
+	findings.CloseJSONFile()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	var finding Finding
+	var record URLFindings
+	if err := decoder.Decode(&finding); err != nil || finding.PatternType != "WordPress" {
+		t.Fatalf("Expected the finding first, got %+v (%v)", finding, err)
+	}
+	if err := decoder.Decode(&record); err != nil {
+		t.Fatal(err)
+	}
+	if record.URL != url || record.InputURL != "http://example.com" || record.Tags["asset_id"] != "A-1" {
+		t.Errorf("Unexpected URL record %+v", record)
+	}
+	if len(record.Redirects) != 1 || record.Redirects[0].Status != 301 || record.Redirects[0].Location != url || strings.Join(record.Redirects[0].Cookies, ",") != "sid" {
+		t.Errorf("Expected the redirect chain in the record, got %+v", record.Redirects)
+	}
+	if inventory := record.ThirdParties; inventory == nil || len(inventory.Known) != 1 || inventory.Known[0].Requests != 2 || len(inventory.Unclassified) != 1 || inventory.Unclassified[0].Hosts[0] != "img.cdnhost.net" {
+		t.Errorf("Expected the third-party inventory in the record, got %+v", record.ThirdParties)
+	}
+	if len(record.Cookies) != 2 || record.Cookies[0].Vendor != "Google Analytics" || record.FirstPartyCookies != 1 || record.ThirdPartyCookies != 1 {
+		t.Errorf("Expected the cookie inventory in the record, got %+v", record.Cookies)
+	}
+	if record.Content == nil || record.Content.Charset != "iso-8859-1" || record.Content.Encoding != "gzip" {
+		t.Errorf("Expected the content info in the record, got %+v", record.Content)
+	}
+	if record.Score == nil || record.Score.Grade != "A" || len(record.Findings) != 0 {
+		t.Errorf("Expected the score and no findings in the record, got %+v", record)
+	}
+	if err := decoder.Decode(&record); err != io.EOF {
+		t.Errorf("Expected one record per URL with something recorded, got another: %+v (%v)", record, err)
+	}
+
+	kept, _ := findings.Get(url)
+	if kept.Redirects != nil || kept.Score == nil || kept.Tags == nil || len(kept.Findings) != 1 {
+		t.Errorf("Expected only the findings, input URL, tags and score to be kept, got %+v", kept)
+	}
+}
@@ -0,0 +1,123 @@
+package patterns
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Confidence levels of a finding
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// ConfidenceRank orders confidence levels from 1 (low) to 3 (high). Unknown
+// levels rank 0.
+func ConfidenceRank(level string) int {
+	switch strings.ToLower(level) {
+	case ConfidenceLow:
+		return 1
+	case ConfidenceMedium:
+		return 2
+	case ConfidenceHigh:
+		return 3
+	}
+	return 0
+}
+
+// excludeContext is how far around a match an exclusion is looked for
+const excludeContext = 64
+
+// Controls are the compiled false-positive controls of a PatternType
+type Controls struct {
+	Exclude    *regexp.Regexp
+	Requires   *regexp.Regexp
+	Weak       *regexp.Regexp
+	MinMatches int
+	Domains    []string
+}
+
+// Controls compiles the false-positive controls of a pattern
+func (pt PatternType) Controls() (Controls, error) {
+	c := Controls{MinMatches: pt.MinMatches, Domains: Domains(pt)}
+	var err error
+	if pt.Exclude != "" {
+		if c.Exclude, err = regexp.Compile(pt.Exclude); err != nil {
+			return c, err
+		}
+	}
+	if pt.Requires != "" {
+		if c.Requires, err = regexp.Compile(pt.Requires); err != nil {
+			return c, err
+		}
+	}
+	if pt.Weak != "" {
+		if c.Weak, err = regexp.Compile(`^(?:` + pt.Weak + `)$`); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// Apply drops the body matches of a pattern in content that its controls
+// rule out and rates the rest. Matches naming one of the pattern's domains
+// are high confidence; weak matches are low confidence unless the pattern
+// also matched something specific in content; other matches are medium.
+func (c Controls) Apply(content string, indexes [][]int) ([][]int, []string) {
+	kept := indexes[:0:0]
+	for _, loc := range indexes {
+		if c.Exclude == nil || !c.excluded(content, loc) {
+			kept = append(kept, loc)
+		}
+	}
+	if len(kept) == 0 || len(kept) < c.MinMatches || (c.Requires != nil && !c.Requires.MatchString(content)) {
+		return nil, nil
+	}
+
+	weak := make([]bool, len(kept))
+	specific := false
+	for i, loc := range kept {
+		weak[i] = c.Weak != nil && c.Weak.MatchString(content[loc[0]:loc[1]])
+		specific = specific || !weak[i]
+	}
+	confidence := make([]string, len(kept))
+	for i, loc := range kept {
+		switch {
+		case c.hasDomain(content[loc[0]:loc[1]]):
+			confidence[i] = ConfidenceHigh
+		case weak[i] && !specific:
+			confidence[i] = ConfidenceLow
+		default:
+			confidence[i] = ConfidenceMedium
+		}
+	}
+	return kept, confidence
+}
+
+// excluded reports whether an exclusion match overlaps the match at loc
+func (c Controls) excluded(content string, loc []int) bool {
+	start, end := loc[0]-excludeContext, loc[1]+excludeContext
+	if start < 0 {
+		start = 0
+	}
+	if end > len(content) {
+		end = len(content)
+	}
+	for _, ex := range c.Exclude.FindAllStringIndex(content[start:end], -1) {
+		if start+ex[0] < loc[1] && start+ex[1] > loc[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Controls) hasDomain(match string) bool {
+	match = strings.ToLower(match)
+	for _, domain := range c.Domains {
+		if strings.Contains(match, domain) {
+			return true
+		}
+	}
+	return false
+}
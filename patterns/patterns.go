@@ -1,10 +1,54 @@
 package patterns
 
-// PatternType defines a pattern to search for
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// PatternType defines a pattern to search for. Pattern is matched against
+// the response body; Targets match other parts of the response.
 type PatternType struct {
 	Category string
 	Name     string
 	Pattern  string
+	Targets  []Target
+	// Tags group patterns across categories, e.g. "google" or
+	// "session-replay", for selecting them with -patterns. Every pattern also has
+	// the tags of its category.
+	Tags []string
+
+	// Exclude drops body matches that a match of it overlaps, within a
+	// short distance around the match, e.g. "image." for a "mage\." pattern
+	Exclude string
+	// Requires must also match somewhere in a document for any body match
+	// to be reported
+	Requires string
+	// MinMatches is the number of body matches a document needs before any
+	// is reported
+	MinMatches int
+	// Weak matches the alternatives of Pattern too generic to report with
+	// more than low confidence unless something specific matched too
+	Weak string
+}
+
+// Match targets other than the response body
+const (
+	TargetHeader = "header"
+	TargetCookie = "cookie"
+	TargetMeta   = "meta"
+	TargetScript = "script"
+	TargetURL    = "url"
+)
+
+// Target matches a regex against one part of a response. Key names the
+// header or meta tag for header and meta targets. An empty Pattern matches
+// any value, so a target can test for a header's presence alone.
+type Target struct {
+	Type    string
+	Key     string
+	Pattern string
 }
 
 // API Specification patterns
@@ -18,6 +62,8 @@ var apiSpecPatterns = []PatternType{
 		Category: "APISpec",
 		Name:     "GraphQL",
 		Pattern:  `(?i)/graphql|/graphiql|graphql\.schema|schema\.graphql|\.graphqls|graphiql\.min\.(css|js)|/playground|graphql-playground|altair-graphql|graphql\.config`,
+		Requires: `(?i)graph[iq]l`,
+		Weak:     `(?i)/playground`,
 	},
 	{
 		Category: "APISpec",
@@ -42,41 +88,140 @@ var cmsPatterns = []PatternType{
 		Category: "CMS",
 		Name:     "WordPress",
 		Pattern:  `(?i)wp-content|wp-includes|wp-admin|wp-config\.php|wordpress\.com|wordpress\.org|wp_|wordpress_|/wp-json/|wp\.customize|wp\.blocks`,
+		Targets: []Target{
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^WordPress`},
+			{Type: TargetHeader, Key: "Link", Pattern: `/wp-json/`},
+			{Type: TargetHeader, Key: "X-Pingback", Pattern: `/xmlrpc\.php`},
+			{Type: TargetCookie, Pattern: `^(?:wordpress_|wp-settings-)`},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Drupal",
 		Pattern:  `(?i)drupal\.org|drupal\.settings|drupal\.behaviors|/sites/default/files/|/node/\d+|/admin/content|/sites/all/themes/|/sites/all/modules/`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "X-Drupal-Cache"},
+			{Type: TargetHeader, Key: "X-Drupal-Dynamic-Cache"},
+			{Type: TargetHeader, Key: "X-Generator", Pattern: `(?i)^Drupal`},
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^Drupal`},
+			{Type: TargetCookie, Pattern: `^SS?ESS[0-9a-f]{32}$`},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Joomla",
 		Pattern:  `(?i)com_content|com_users|com_admin|joomla!|/administrator/|mosConfig_|joomla\.org|joomla\.javascript|/components/com_|/modules/mod_`,
+		Targets: []Target{
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^Joomla`},
+			{Type: TargetHeader, Key: "X-Content-Encoded-By", Pattern: `(?i)^Joomla`},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Ghost",
 		Pattern:  `(?i)ghost\.io|ghost-admin|ghost\.|ghost_root_url|ghost\-admin|ghost\.settings|/ghost/api/|@tryghost/`,
+		Exclude:  `(?i)[a-z0-9_]ghost\.|ghost\.(?:png|jpe?g|gif|svg|webp|css|js)\b`,
+		Weak:     `(?i)ghost\.`,
+		Targets: []Target{
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^Ghost`},
+			{Type: TargetHeader, Key: "X-Ghost-Cache-Status"},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Shopify",
+		Tags:     []string{"ecommerce"},
 		Pattern:  `(?i)shopify\.com|myshopify\.com|shopify\.section|shopify\.theme|shopify\.assets|\.myshopify\.|shopify\.payment|shopify-buy`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "X-Shopify-Stage"},
+			{Type: TargetHeader, Key: "X-ShopId"},
+			{Type: TargetHeader, Key: "Powered-By", Pattern: `(?i)^Shopify`},
+			{Type: TargetCookie, Pattern: `^(?:_shopify_[a-z]+|cart_sig|secure_customer_sig)$`},
+			{Type: TargetScript, Pattern: `(?i)//cdn\.shopify\.com/`},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Magento",
+		Tags:     []string{"ecommerce"},
 		Pattern:  `(?i)magento|mage\.|/skin/frontend/|/app/design/frontend/|var magento|mage/cookies\.js|Mage\.Cookies|/checkout/cart/`,
+		Exclude:  `(?i)[a-z0-9_]mage\.`,
+		// A lone mention of Magento is usually prose, not a Magento store
+		MinMatches: 2,
+		Weak:       `(?i)magento|mage\.|/checkout/cart/`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "X-Magento-Cache-Debug"},
+			{Type: TargetHeader, Key: "X-Magento-Tags"},
+			{Type: TargetHeader, Key: "X-Magento-Vary"},
+			{Type: TargetCookie, Pattern: `^(?:X-Magento-Vary|mage-cache-storage|mage-cache-sessid|mage-messages)$`},
+			{Type: TargetScript, Pattern: `(?i)/static/version\d+/frontend/|/mage/requirejs/`},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Wix",
 		Pattern:  `(?i)wix\.com|wixsite\.com|wix-code|wix-api|wix-dashboard|wix-locations|wix-events|wix-stores|wix-bookings`,
+		Targets: []Target{
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^Wix\.com`},
+			{Type: TargetHeader, Key: "X-Wix-Request-Id"},
+			{Type: TargetScript, Pattern: `(?i)//static\.parastorage\.com/`},
+		},
 	},
 	{
 		Category: "CMS",
 		Name:     "Squarespace",
 		Pattern:  `(?i)squarespace\.com|sqsp\.com|squarespace-cdn\.com|squarespace\.config|squarespace\.bootstrap|static\.squarespace|static1\.squarespace`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "Server", Pattern: `(?i)^Squarespace`},
+			{Type: TargetCookie, Pattern: `^(?:crumb|ss_cvr|ss_cvt)$`},
+		},
+	},
+	{
+		Category: "CMS",
+		Name:     "Webflow",
+		Pattern:  `(?i)data-wf-page=|data-wf-site=|assets\.website-files\.com|webflow\.js`,
+		Targets: []Target{
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^Webflow`},
+		},
+	},
+	{
+		Category: "CMS",
+		Name:     "BigCommerce",
+		Tags:     []string{"ecommerce"},
+		Pattern:  `(?i)cdn\d*\.bigcommerce\.com|bigcommerce\.com/s-|BCData\s*=`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "X-BC-Storefront-Version"},
+			{Type: TargetCookie, Pattern: `^(?:SHOP_SESSION_TOKEN|fornax_anonymousId)$`},
+		},
+	},
+	{
+		Category: "CMS",
+		Name:     "PrestaShop",
+		Tags:     []string{"ecommerce"},
+		Pattern:  `(?i)var prestashop\s*=|/modules/ps_[a-z]+/|prestashop\.com`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "Powered-By", Pattern: `(?i)^PrestaShop`},
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^PrestaShop`},
+			{Type: TargetCookie, Pattern: `^PrestaShop-[0-9a-f]{32}$`},
+		},
+	},
+	{
+		Category: "CMS",
+		Name:     "TYPO3",
+		Pattern:  `(?i)/typo3conf/|/typo3temp/|typo3\.org`,
+		Targets: []Target{
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^TYPO3`},
+			{Type: TargetCookie, Pattern: `^(?:fe_typo_user|be_typo_user)$`},
+		},
+	},
+	{
+		Category: "CMS",
+		Name:     "HubSpot CMS",
+		Pattern:  `(?i)hs-sites\.com|hubspotusercontent|/hs-fs/hubfs/`,
+		Targets: []Target{
+			{Type: TargetHeader, Key: "X-HS-Hub-Id"},
+			{Type: TargetMeta, Key: "generator", Pattern: `(?i)^HubSpot`},
+		},
 	},
 }
 
@@ -85,16 +230,19 @@ var cloudStoragePatterns = []PatternType{
 	{
 		Category: "CloudStorage",
 		Name:     "AWS S3 Bucket",
-		Pattern:  `(?i)(?:https?://)?(?:[a-zA-Z0-9-]+\.)?s3[.-](?:[a-zA-Z0-9-]+\.)?amazonaws\.com|(?:https?://)?s3://[a-zA-Z0-9-]+|"bucket":\s*"[a-zA-Z0-9-]+"|AWS_BUCKET|S3_BUCKET`,
+		Tags:     []string{"amazon"},
+		Pattern:  `(?i)(?:https?://)?(?:[a-zA-Z0-9-]+\.)?s3[.-](?:[a-zA-Z0-9-]+\.)?amazonaws\.com(?:/[a-z0-9][a-z0-9.-]{2,62}/?)?|(?:https?://)?s3://[a-zA-Z0-9-]+|"bucket":\s*"[a-zA-Z0-9-]+"|AWS_BUCKET|S3_BUCKET`,
 	},
 	{
 		Category: "CloudStorage",
 		Name:     "Azure Blob Storage",
-		Pattern:  `(?i)(?:https?://)?[a-zA-Z0-9-]+\.blob\.core\.windows\.net|DefaultEndpointsProtocol=https;AccountName=[^;]+;AccountKey=[^;]+|AZURE_STORAGE_CONNECTION_STRING|AZURE_STORAGE_ACCOUNT`,
+		Tags:     []string{"microsoft"},
+		Pattern:  `(?i)(?:https?://)?[a-zA-Z0-9-]+\.blob\.core\.windows\.net(?:/[a-z0-9$][a-z0-9-]{2,62})?|DefaultEndpointsProtocol=https;AccountName=[^;]+;AccountKey=[^;]+|AZURE_STORAGE_CONNECTION_STRING|AZURE_STORAGE_ACCOUNT`,
 	},
 	{
 		Category: "CloudStorage",
 		Name:     "Google Cloud Storage",
+		Tags:     []string{"google"},
 		Pattern:  `(?i)(?:https?://)?storage\.cloud\.google\.com/[a-zA-Z0-9-]+|(?:https?://)?storage\.googleapis\.com/[a-zA-Z0-9-]+|"type":\s*"service_account"|GOOGLE_CLOUD_BUCKET|GCS_BUCKET`,
 	},
 }
@@ -104,12 +252,16 @@ var trackingPixelPatterns = []PatternType{
 	{
 		Category: "TrackingPixel",
 		Name:     "Facebook Pixel",
+		Tags:     []string{"meta"},
 		Pattern:  `(?i)facebook\.com/tr|facebook\.net/signals|connect\.facebook\.net|fbevents\.js|_fbq\.push|fbq\(['"]track['"]`,
 	},
 	{
 		Category: "TrackingPixel",
 		Name:     "Google Analytics",
+		Tags:     []string{"google"},
 		Pattern:  `(?i)google-analytics\.com|analytics\.js|gtag|ga\.js|googletagmanager\.com|google_analytics|_ga\.push|ga\(['"]send['"]`,
+		Exclude:  `(?i)[a-z0-9_-](?:analytics|ga)\.js|[a-z0-9_]gtag|gtag[a-z0-9_]`,
+		Weak:     `(?i)analytics\.js|gtag|ga\.js`,
 	},
 	{
 		Category: "TrackingPixel",
@@ -138,11 +290,13 @@ var adNetworkPatterns = []PatternType{
 	{
 		Category: "AdNetwork",
 		Name:     "Google AdSense",
+		Tags:     []string{"google"},
 		Pattern:  `(?i)pagead2\.googlesyndication\.com|adsbygoogle|google_ad_client|googleads|adsense\.js`,
 	},
 	{
 		Category: "AdNetwork",
 		Name:     "Amazon Ads",
+		Tags:     []string{"amazon"},
 		Pattern:  `(?i)amazon-adsystem\.com|amzn_ads|amzn\.to/ads|amazon-ads-api`,
 	},
 	{
@@ -203,16 +357,6 @@ var aiChatPatterns = []PatternType{
 
 // Hidden iframe patterns
 var hiddenIframePatterns = []PatternType{
-	{
-		Category: "HiddenIframe",
-		Name:     "Hidden Iframe",
-		Pattern:  `(?i)<iframe[^>]*(?:style=["'][^"']*(?:display:\s*none|visibility:\s*hidden|opacity:\s*0)[^"']*["'])[^>]*>`,
-	},
-	{
-		Category: "HiddenIframe",
-		Name:     "Zero Size Iframe",
-		Pattern:  `(?i)<iframe[^>]*(?:width=["']0["']|height=["']0["']|width=["']1["']|height=["']1["'])[^>]*>`,
-	},
 	{
 		Category: "HiddenIframe",
 		Name:     "Dynamic Hidden Iframe",
@@ -225,21 +369,27 @@ var trackingPatterns = []PatternType{
 	{
 		Category: "Tracking",
 		Name:     "Hotjar",
+		Tags:     []string{"session-replay"},
 		Pattern:  `(?i)static\.hotjar\.com|hotjar-|hj\.|hotjar\.com|window\.hjSiteSettings|_hjSettings`,
+		Exclude:  `(?i)[a-z0-9_$]hj\.`,
+		Weak:     `(?i)hotjar-|hj\.`,
 	},
 	{
 		Category: "Tracking",
 		Name:     "Mouseflow",
+		Tags:     []string{"session-replay"},
 		Pattern:  `(?i)mouseflow\.com/projects|_mfq\.push|mouseflow\.init|mouseflowId`,
 	},
 	{
 		Category: "Tracking",
 		Name:     "FullStory",
+		Tags:     []string{"session-replay"},
 		Pattern:  `(?i)fullstory\.com/s/fs\.js|window\['_fs_host'\]|FS\.identify|_fs_loaded`,
 	},
 	{
 		Category: "Tracking",
 		Name:     "Lucky Orange",
+		Tags:     []string{"session-replay"},
 		Pattern:  `(?i)luckyorange\.com|window\.__lo_site_id|_loq\.push`,
 	},
 	{
@@ -288,6 +438,7 @@ var sessionRecordingPatterns = []PatternType{
 	{
 		Category: "SessionRecording",
 		Name:     "Clarity",
+		Tags:     []string{"microsoft"},
 		Pattern:  `(?i)clarity\.ms/tag|microsoft\.com/clarity|clarity\.identify`,
 	},
 }
@@ -326,10 +477,110 @@ var abTestingPatterns = []PatternType{
 	{
 		Category: "ABTesting",
 		Name:     "Google Optimize",
+		Tags:     []string{"google"},
 		Pattern:  `(?i)optimize\.google\.com|gtag\('config', 'OPT-|google_optimize`,
 	},
 }
 
+// Tracker account identifier patterns. The first capture group, when present,
+// holds the bare identifier used to correlate sites run by the same owner.
+var trackerIDPatterns = []PatternType{
+	{
+		Category: "TrackerID",
+		Name:     "Google Analytics ID",
+		Tags:     []string{"google"},
+		Pattern:  `\b(UA-\d{4,10}-\d{1,4}|G-[A-Z0-9]{8,12})\b`,
+	},
+	{
+		Category: "TrackerID",
+		Name:     "Google Tag Manager ID",
+		Tags:     []string{"google"},
+		Pattern:  `\b(GTM-[A-Z0-9]{4,9})\b`,
+	},
+	{
+		Category: "TrackerID",
+		Name:     "Facebook Pixel ID",
+		Tags:     []string{"meta"},
+		Pattern:  `(?i)fbq\(\s*['"]init['"]\s*,\s*['"]?(\d{15,16})|facebook\.com/tr\?id=(\d{15,16})`,
+	},
+	{
+		Category: "TrackerID",
+		Name:     "AdSense Publisher ID",
+		Tags:     []string{"google"},
+		Pattern:  `\b(ca-pub-\d{10,16})\b`,
+	},
+}
+
+// Secret and credential patterns. Matches are masked in all outputs unless
+// secrets are explicitly revealed.
+var secretPatterns = []PatternType{
+	{
+		Category: "Secrets",
+		Name:     "AWS Access Key ID",
+		Tags:     []string{"amazon"},
+		Pattern:  `\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "AWS Secret Access Key",
+		Tags:     []string{"amazon"},
+		Pattern:  `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "GCP API Key",
+		Tags:     []string{"google"},
+		Pattern:  `\bAIza[0-9A-Za-z_-]{35}\b`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "GCP Service Account Key",
+		Tags:     []string{"google"},
+		Pattern:  `"private_key_id":\s*"[a-f0-9]{40}"`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Azure Storage Account Key",
+		Tags:     []string{"microsoft"},
+		Pattern:  `AccountKey=[A-Za-z0-9+/]{86}==`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Stripe Secret Key",
+		Pattern:  `\b(?:sk|rk)_(?:live|test)_[0-9a-zA-Z]{24,99}\b`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Twilio API Key",
+		Pattern:  `\bSK[0-9a-f]{32}\b`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Slack Token",
+		Pattern:  `\bxox[abposr]-[0-9A-Za-z-]{10,72}\b`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Slack Webhook",
+		Pattern:  `https://hooks\.slack\.com/services/T[A-Z0-9]{8,12}/B[A-Z0-9]{8,12}/[A-Za-z0-9]{24}`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Private Key",
+		Pattern:  `-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----(?:\s|\\n)+[A-Za-z0-9+/=]{40,}`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "JWT",
+		Pattern:  `\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,
+	},
+	{
+		Category: "Secrets",
+		Name:     "Generic High Entropy",
+		Pattern:  `(?i)(?:secret|token|api_?key|access_?key|passw(?:or)?d|client_?secret)["']?\s*[:=]\s*["'][A-Za-z0-9/+_=.-]{20,}["']`,
+	},
+}
+
 // init combines all pattern slices into AllPatternTypes
 func init() {
 	AllPatternTypes = make([]PatternType, 0)
@@ -345,7 +596,37 @@ func init() {
 	AllPatternTypes = append(AllPatternTypes, sessionRecordingPatterns...)
 	AllPatternTypes = append(AllPatternTypes, errorTrackingPatterns...)
 	AllPatternTypes = append(AllPatternTypes, abTestingPatterns...)
+	AllPatternTypes = append(AllPatternTypes, trackerIDPatterns...)
+	AllPatternTypes = append(AllPatternTypes, secretPatterns...)
 }
 
 // AllPatternTypes contains all patterns to search for
 var AllPatternTypes []PatternType
+
+// domainLiteral matches domain-like literals left after unescaping a pattern
+var domainLiteral = regexp.MustCompile(`^[a-z0-9-]+(?:\.[a-z0-9-]+)+$`)
+
+// Domains returns the literal domain names a pattern matches, such as
+// static.hotjar.com for Hotjar. Literals that are not under a public
+// suffix, like analytics.js, are skipped.
+func Domains(pt PatternType) []string {
+	source := strings.ToLower(strings.ReplaceAll(pt.Pattern, `\.`, "."))
+	tokens := strings.FieldsFunc(source, func(r rune) bool {
+		return strings.ContainsRune(`|()[]{}?*+^$\/'"=:,;<> `, r)
+	})
+
+	seen := make(map[string]bool)
+	var domains []string
+	for _, token := range tokens {
+		token = strings.Trim(token, ".-")
+		if !domainLiteral.MatchString(token) || seen[token] {
+			continue
+		}
+		if suffix, icann := publicsuffix.PublicSuffix(token); !icann || suffix == token {
+			continue
+		}
+		seen[token] = true
+		domains = append(domains, token)
+	}
+	return domains
+}
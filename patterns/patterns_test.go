@@ -2,6 +2,7 @@ package patterns
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -124,24 +125,14 @@ func TestHiddenIframePatterns(t *testing.T) {
 		content     string
 		want        bool
 	}{
-		{
-			patternName: "Hidden Iframe",
-			content:     `<iframe style="display:none" src="tracker.html"></iframe>`,
-			want:        true,
-		},
-		{
-			patternName: "Zero Size Iframe",
-			content:     `<iframe width="0" height="0" src="tracker.html"></iframe>`,
-			want:        true,
-		},
 		{
 			patternName: "Dynamic Hidden Iframe",
 			content:     `createElement('iframe').style.display = 'none';`,
 			want:        true,
 		},
 		{
-			patternName: "Hidden Iframe",
-			content:     `<iframe src="video.html"></iframe>`,
+			patternName: "Dynamic Hidden Iframe",
+			content:     `createElement('iframe').style.width = '100%';`,
 			want:        false,
 		},
 	}
@@ -208,3 +199,107 @@ func TestPatternValidity(t *testing.T) {
 		}
 	}
 }
+
+func TestDomains(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{`(static\.hotjar\.com|hjSiteSettings)`, []string{"static.hotjar.com"}},
+		{`(google-analytics\.com/analytics\.js|gtag\()`, []string{"google-analytics.com"}},
+		{`(swagger-ui\.css|api-docs)`, nil},
+	}
+	for _, tt := range tests {
+		got := Domains(PatternType{Pattern: tt.pattern})
+		if len(got) != len(tt.want) {
+			t.Errorf("Domains(%q) = %v, want %v", tt.pattern, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Domains(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	types := map[string]bool{TargetHeader: true, TargetCookie: true, TargetMeta: true, TargetScript: true, TargetURL: true}
+	for _, pt := range AllPatternTypes {
+		for _, target := range pt.Targets {
+			if !types[target.Type] {
+				t.Errorf("%s: unknown target type %q", pt.Name, target.Type)
+			}
+			if (target.Type == TargetHeader || target.Type == TargetMeta) && target.Key == "" {
+				t.Errorf("%s: %s target needs a key", pt.Name, target.Type)
+			}
+			if _, err := regexp.Compile(target.Pattern); err != nil {
+				t.Errorf("%s: invalid %s target pattern: %v", pt.Name, target.Type, err)
+			}
+		}
+	}
+}
+
+func TestControls(t *testing.T) {
+	byName := make(map[string]PatternType)
+	for _, pt := range AllPatternTypes {
+		byName[pt.Name] = pt
+	}
+
+	tests := []struct {
+		pattern string
+		content string
+		want    []string // matched text and confidence
+	}{
+		{"Hotjar", `var nhj.x = 1; $hj.y`, nil},
+		{"Hotjar", `hj.q = []`, []string{"hj. low"}},
+		{"Hotjar", `hj.q = []; src="https://static.hotjar.com/c/hotjar-1.js"`, []string{"hj. medium", "static.hotjar.com high", "hotjar- medium"}},
+		{"Google Analytics", `<script src="/js/omega.js"></script><script src="/myanalytics.js"></script>`, nil},
+		{"Google Analytics", `function gtag(){}`, []string{"gtag low"}},
+		{"Google Analytics", `gtag('js'); ga('send', 'pageview')`, []string{"gtag medium", "ga('send' medium"}},
+		{"Magento", `<img src="/image.png"> We moved off Magento.`, nil},
+		{"Magento", `Powered by Magento. Magento rocks`, []string{"Magento low", "Magento low"}},
+		{"Magento", `var magento = {}; Mage.Cookies.path = '/'`, []string{"var magento medium", "Mage. medium"}},
+		{"Ghost", `<img src="ghost.png"> myghost.theme`, nil},
+		{"Ghost", `ghost.url.api()`, []string{"ghost. low"}},
+		{"GraphQL", `See /playground for demos`, nil},
+		{"GraphQL", `fetch('/graphql'); /playground`, []string{"/graphql medium", "/playground medium"}},
+	}
+	for _, tt := range tests {
+		pt := byName[tt.pattern]
+		controls, err := pt.Controls()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.pattern, err)
+		}
+		indexes, confidence := controls.Apply(tt.content, regexp.MustCompile(pt.Pattern).FindAllStringIndex(tt.content, -1))
+		var got []string
+		for i, loc := range indexes {
+			got = append(got, tt.content[loc[0]:loc[1]]+" "+confidence[i])
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s on %q: got %v, want %v", tt.pattern, tt.content, got, tt.want)
+		}
+	}
+
+	if ConfidenceRank("High") != 3 || ConfidenceRank("low") != 1 || ConfidenceRank("certain") != 0 {
+		t.Error("unexpected confidence ranks")
+	}
+}
+
+func TestTags(t *testing.T) {
+	for _, pt := range AllPatternTypes {
+		if len(categoryTags[pt.Category]) == 0 {
+			t.Errorf("category %s has no tags", pt.Category)
+		}
+		for _, tag := range pt.AllTags() {
+			if tag == "" || tag != strings.ToLower(tag) || strings.ContainsAny(tag, ", ") {
+				t.Errorf("%s: tag %q should be a lowercase word", pt.Name, tag)
+			}
+		}
+	}
+
+	hotjar := PatternType{Category: "Tracking", Name: "Hotjar", Tags: []string{"session-replay"}}
+	if !hotjar.HasTag("Session-Replay") || !hotjar.HasTag("analytics") || hotjar.HasTag("ads") {
+		t.Errorf("unexpected tags %v", hotjar.AllTags())
+	}
+}
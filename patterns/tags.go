@@ -0,0 +1,36 @@
+package patterns
+
+import "strings"
+
+// categoryTags are the tags every pattern of a category has
+var categoryTags = map[string][]string{
+	"APISpec":           {"api"},
+	"CMS":               {"cms"},
+	"CloudStorage":      {"cloud"},
+	"TrackingPixel":     {"tracking", "privacy"},
+	"AdNetwork":         {"ads", "privacy"},
+	"AIChat":            {"chat"},
+	"HiddenIframe":      {"privacy"},
+	"Tracking":          {"tracking", "analytics", "privacy"},
+	"ConsentManagement": {"consent"},
+	"SessionRecording":  {"session-replay", "tracking", "privacy"},
+	"ErrorTracking":     {"monitoring"},
+	"ABTesting":         {"analytics"},
+	"TrackerID":         {"tracking", "privacy"},
+	"Secrets":           {"credentials"},
+}
+
+// AllTags returns the tags of a pattern followed by those of its category
+func (pt PatternType) AllTags() []string {
+	return append(append([]string(nil), pt.Tags...), categoryTags[pt.Category]...)
+}
+
+// HasTag reports whether a pattern or its category has tag, ignoring case
+func (pt PatternType) HasTag(tag string) bool {
+	for _, t := range pt.AllTags() {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
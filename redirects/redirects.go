@@ -0,0 +1,142 @@
+package redirects
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gregcmartin/spectre/cookies"
+	"github.com/gregcmartin/spectre/models"
+)
+
+// DefaultMax matches the net/http client's redirect limit
+const DefaultMax = 10
+
+// Pattern types of redirect findings
+const (
+	ThirdParty  = "Third-Party Redirect"
+	CrossDomain = "Cross-Domain Redirect"
+	Downgrade   = "HTTPS Downgrade"
+)
+
+// Types lists the pattern types of redirect findings
+var Types = []string{ThirdParty, CrossDomain, Downgrade}
+
+// Chain records the redirects an http.Client follows. Set CheckRedirect
+// as the client's CheckRedirect; a Chain is used for one request.
+type Chain struct {
+	Max  int // redirects followed before stopping
+	Hops []models.Redirect
+	// Cookies are set by the redirect responses, which the client
+	// otherwise follows without exposing
+	Cookies   []models.Cookie
+	Truncated bool
+}
+
+// CheckRedirect records the redirect response that led to req. Past the
+// limit it stops and sets Truncated, and the client returns the last
+// redirect response in place of the final page.
+func (c *Chain) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if resp := req.Response; resp != nil {
+		set := cookies.FromResponse(resp)
+		hop := models.Redirect{
+			URL:      resp.Request.URL.String(),
+			Status:   resp.StatusCode,
+			Location: resp.Header.Get("Location"),
+		}
+		for _, cookie := range set {
+			hop.Cookies = append(hop.Cookies, cookie.Name)
+		}
+		c.Hops = append(c.Hops, hop)
+		c.Cookies = append(c.Cookies, set...)
+	}
+
+	if len(via) > c.Max {
+		// The response is kept, so its hop is not a redirect followed
+		c.Hops = c.Hops[:len(c.Hops)-1]
+		c.Truncated = true
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// Flag is a redirect worth reporting
+type Flag struct {
+	Type    string
+	Hop     models.Redirect
+	Details map[string]string
+}
+
+// Analyze marks the hops that pass through a site other than the input and
+// final ones, and flags those hops, a final site that differs from the
+// input, and any HTTPS to HTTP downgrade. vendor names the tracker behind a
+// host, if any.
+func Analyze(input, final string, hops []models.Redirect, vendor func(host string) string) []Flag {
+	inputSite, finalSite := site(input), site(final)
+	var flags []Flag
+	for i := range hops {
+		hop := &hops[i]
+		next := hop.Location
+		if u, err := url.Parse(hop.URL); err == nil {
+			if loc, err := u.Parse(hop.Location); err == nil {
+				next = loc.String()
+				if u.Scheme == "https" && loc.Scheme == "http" {
+					flags = append(flags, Flag{Type: Downgrade, Hop: *hop, Details: map[string]string{
+						"from": hop.URL,
+						"to":   next,
+					}})
+				}
+			}
+		}
+
+		hopSite := site(hop.URL)
+		if hopSite == "" || hopSite == inputSite || hopSite == finalSite {
+			continue
+		}
+		hop.ThirdParty = true
+		details := map[string]string{
+			"hop":    hop.URL,
+			"status": strconv.Itoa(hop.Status),
+			"next":   next,
+		}
+		if v := vendor(host(hop.URL)); v != "" {
+			details["tracker"] = v
+		}
+		flags = append(flags, Flag{Type: ThirdParty, Hop: *hop, Details: details})
+	}
+
+	if inputSite != "" && finalSite != "" && inputSite != finalSite {
+		flags = append(flags, Flag{Type: CrossDomain, Details: map[string]string{
+			"input": input,
+			"final": final,
+			"chain": strings.Join(append(hopURLs(hops), final), " -> "),
+		}})
+	}
+	return flags
+}
+
+func hopURLs(hops []models.Redirect) []string {
+	urls := make([]string, 0, len(hops))
+	for _, hop := range hops {
+		urls = append(urls, hop.URL)
+	}
+	return urls
+}
+
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// site returns the registrable domain of a URL
+func site(rawURL string) string {
+	h := host(rawURL)
+	if h == "" {
+		return ""
+	}
+	return cookies.RegistrableDomain(h)
+}
@@ -0,0 +1,135 @@
+package redirects
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gregcmartin/spectre/models"
+)
+
+func TestChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "click_id", Value: "1"})
+		http.Redirect(w, r, "/hop", http.StatusFound)
+	})
+	mux.HandleFunc("/hop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		max       int
+		hops      int
+		final     string
+		truncated bool
+	}{
+		{DefaultMax, 2, "/final", false},
+		{1, 1, "/hop", true},
+		{0, 0, "/start", true},
+	}
+	for _, tt := range tests {
+		chain := &Chain{Max: tt.max}
+		client := &http.Client{CheckRedirect: chain.CheckRedirect}
+		resp, err := client.Get(server.URL + "/start")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if len(chain.Hops) != tt.hops || chain.Truncated != tt.truncated {
+			t.Errorf("max %d: got %d hops, truncated %v; want %d, %v", tt.max, len(chain.Hops), chain.Truncated, tt.hops, tt.truncated)
+		}
+		if got := resp.Request.URL.Path; got != tt.final {
+			t.Errorf("max %d: final path %s, want %s", tt.max, got, tt.final)
+		}
+		if tt.hops > 0 {
+			first := chain.Hops[0]
+			if first.Status != http.StatusFound || first.Location != "/hop" || len(first.Cookies) != 1 || first.Cookies[0] != "click_id" {
+				t.Errorf("max %d: unexpected first hop %+v", tt.max, first)
+			}
+			if len(chain.Cookies) != 1 {
+				t.Errorf("max %d: got %d hop cookies, want 1", tt.max, len(chain.Cookies))
+			}
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	vendor := func(host string) string {
+		if host == "click.tracker.net" {
+			return "Example Tracker (Tracking)"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		final      string
+		hops       []models.Redirect
+		want       []string
+		thirdParty []bool
+	}{
+		{
+			name:  "https upgrade on the same site",
+			input: "http://example.com/",
+			final: "https://www.example.com/",
+			hops: []models.Redirect{
+				{URL: "http://example.com/", Status: 301, Location: "https://www.example.com/"},
+			},
+			thirdParty: []bool{false},
+		},
+		{
+			name:  "tracking hop",
+			input: "https://news.example.com/story",
+			final: "https://example.com/landing",
+			hops: []models.Redirect{
+				{URL: "https://news.example.com/story", Status: 302, Location: "https://click.tracker.net/c?u=1"},
+				{URL: "https://click.tracker.net/c?u=1", Status: 302, Location: "https://example.com/landing"},
+			},
+			want:       []string{ThirdParty},
+			thirdParty: []bool{false, true},
+		},
+		{
+			name:  "cross-site downgrade",
+			input: "https://example.com/",
+			final: "http://example.org/",
+			hops: []models.Redirect{
+				{URL: "https://example.com/", Status: 302, Location: "http://example.org/"},
+			},
+			want:       []string{Downgrade, CrossDomain},
+			thirdParty: []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := Analyze(tt.input, tt.final, tt.hops, vendor)
+			var got []string
+			for _, f := range flags {
+				got = append(got, f.Type)
+				if f.Type == ThirdParty && f.Details["tracker"] != "Example Tracker (Tracking)" {
+					t.Errorf("third-party hop tracker = %q", f.Details["tracker"])
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got flags %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got flags %v, want %v", got, tt.want)
+				}
+			}
+			for i, hop := range tt.hops {
+				if hop.ThirdParty != tt.thirdParty[i] {
+					t.Errorf("hop %d third party = %v, want %v", i, hop.ThirdParty, tt.thirdParty[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// Package render loads pages in a headless Chrome over the DevTools
+// protocol and records the network requests they send. Consent banners are
+// never accepted, so every request a page sends is sent before consent.
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Defaults for a page load
+const (
+	DefaultTimeout = 30 * time.Second
+	// DefaultSettle is how long requests are recorded after the load event,
+	// for tags that fire on a timer or once the page is idle
+	DefaultSettle = 3 * time.Second
+)
+
+// chromeNames are the executables looked up when no browser is given
+var chromeNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "headless-shell", "chrome"}
+
+// Request is a network request sent by a rendered page
+type Request struct {
+	URL  string
+	Type string // DevTools resource type, e.g. Script, Image or XHR
+}
+
+// command is a DevTools protocol command
+type command struct {
+	ID        int         `json:"id"`
+	Method    string      `json:"method"`
+	Params    interface{} `json:"params,omitempty"`
+	SessionID string      `json:"sessionId,omitempty"`
+}
+
+// event is a DevTools protocol response, which has an ID, or an event,
+// which has a method
+type event struct {
+	ID        int             `json:"id,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Browser is a headless Chrome. Pages are rendered one at a time, each in
+// a new tab.
+type Browser struct {
+	Timeout time.Duration // per page; zero uses DefaultTimeout
+	Settle  time.Duration // zero uses DefaultSettle
+
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	messages chan event
+	readErr  error
+	nextID   int
+	cmd      *exec.Cmd
+	dir      string // profile directory of a launched browser
+}
+
+// Start launches a headless Chrome, found on the PATH when path is empty.
+// A ws:// path connects to a browser that is already running instead.
+func Start(path string) (*Browser, error) {
+	if strings.HasPrefix(path, "ws://") || strings.HasPrefix(path, "wss://") {
+		return Dial(path)
+	}
+	if path == "" {
+		for _, name := range chromeNames {
+			if found, err := exec.LookPath(name); err == nil {
+				path = found
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no Chrome or Chromium found, set its path with -chrome")
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "spectre-chrome")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--user-data-dir=" + dir,
+		"--remote-debugging-port=0",
+		"about:blank",
+	}
+	if os.Geteuid() == 0 {
+		args = append([]string{"--no-sandbox"}, args...)
+	}
+	cmd := exec.Command(path, args...)
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	endpoint, err := devToolsEndpoint(dir, 20*time.Second)
+	if err == nil {
+		var b *Browser
+		if b, err = Dial(endpoint); err == nil {
+			b.cmd, b.dir = cmd, dir
+			return b, nil
+		}
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+	os.RemoveAll(dir)
+	return nil, err
+}
+
+// devToolsEndpoint waits for Chrome to write the port and browser path of
+// its DevTools endpoint to its profile directory
+func devToolsEndpoint(dir string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if f, err := os.Open(filepath.Join(dir, "DevToolsActivePort")); err == nil {
+			var lines []string
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				lines = append(lines, strings.TrimSpace(scanner.Text()))
+			}
+			f.Close()
+			if len(lines) >= 2 && lines[0] != "" && lines[1] != "" {
+				return "ws://127.0.0.1:" + lines[0] + lines[1], nil
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return "", fmt.Errorf("Chrome did not open its DevTools endpoint")
+}
+
+// Dial connects to the browser DevTools endpoint of a running Chrome
+func Dial(endpoint string) (*Browser, error) {
+	conn, err := websocket.Dial(endpoint, "", "http://127.0.0.1/")
+	if err != nil {
+		return nil, err
+	}
+	b := &Browser{conn: conn, messages: make(chan event, 256)}
+	go b.read()
+	return b, nil
+}
+
+// read passes received messages to the page being rendered until the
+// connection closes
+func (b *Browser) read() {
+	for {
+		var e event
+		if err := websocket.JSON.Receive(b.conn, &e); err != nil {
+			b.readErr = err
+			close(b.messages)
+			return
+		}
+		b.messages <- e
+	}
+}
+
+// Close closes the connection, and stops the browser if Start launched it
+func (b *Browser) Close() error {
+	err := b.conn.Close()
+	if b.cmd != nil {
+		b.cmd.Process.Kill()
+		b.cmd.Wait()
+		os.RemoveAll(b.dir)
+	}
+	return err
+}
+
+// Requests loads a page in a new tab without accepting any consent banner
+// and returns the requests it sends until its load event and the settle
+// time after it, or until the timeout
+func (b *Browser) Requests(pageURL string) ([]Request, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	timeout, settle := b.Timeout, b.Settle
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if settle <= 0 {
+		settle = DefaultSettle
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var requests []Request
+	var session string
+	loaded := false
+	handle := func(e event) {
+		if e.SessionID != session || session == "" {
+			return
+		}
+		switch e.Method {
+		case "Network.requestWillBeSent":
+			var params struct {
+				Request struct {
+					URL string `json:"url"`
+				} `json:"request"`
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(e.Params, &params) == nil && !strings.HasPrefix(params.Request.URL, "data:") {
+				requests = append(requests, Request{URL: params.Request.URL, Type: params.Type})
+			}
+		case "Page.loadEventFired":
+			loaded = true
+		}
+	}
+	call := func(method, sessionID string, params interface{}, result interface{}) error {
+		return b.call(method, sessionID, params, result, handle, deadline.C)
+	}
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := call("Target.createTarget", "", map[string]string{"url": "about:blank"}, &target); err != nil {
+		return nil, err
+	}
+	defer b.call("Target.closeTarget", "", map[string]string{"targetId": target.TargetID}, nil, nil, time.After(5*time.Second))
+
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := call("Target.attachToTarget", "", map[string]interface{}{"targetId": target.TargetID, "flatten": true}, &attached); err != nil {
+		return nil, err
+	}
+	session = attached.SessionID
+	for _, method := range []string{"Network.enable", "Page.enable"} {
+		if err := call(method, session, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	var navigated struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := call("Page.navigate", session, map[string]string{"url": pageURL}, &navigated); err != nil {
+		return nil, err
+	}
+	if navigated.ErrorText != "" {
+		return nil, fmt.Errorf("loading %s: %s", pageURL, navigated.ErrorText)
+	}
+
+	// Record requests until the load event, then for the settle time
+	var settled <-chan time.Time
+	for {
+		if loaded && settled == nil {
+			settled = time.After(settle)
+		}
+		select {
+		case e, ok := <-b.messages:
+			if !ok {
+				return requests, b.readErr
+			}
+			handle(e)
+		case <-settled:
+			return requests, nil
+		case <-deadline.C:
+			return requests, nil
+		}
+	}
+}
+
+// call sends a command and waits for its response, passing the events
+// received meanwhile to handle
+func (b *Browser) call(method, sessionID string, params, result interface{}, handle func(event), deadline <-chan time.Time) error {
+	b.nextID++
+	id := b.nextID
+	if err := websocket.JSON.Send(b.conn, command{ID: id, Method: method, Params: params, SessionID: sessionID}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case e, ok := <-b.messages:
+			if !ok {
+				return b.readErr
+			}
+			if e.ID != id {
+				if e.Method != "" && handle != nil {
+					handle(e)
+				}
+				continue
+			}
+			if e.Error != nil {
+				return fmt.Errorf("%s: %s", method, e.Error.Message)
+			}
+			if result != nil && len(e.Result) > 0 {
+				return json.Unmarshal(e.Result, result)
+			}
+			return nil
+		case <-deadline:
+			return fmt.Errorf("%s: timed out", method)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// devTools is a stand-in for Chrome's DevTools endpoint that loads every
+// page with the same requests, or fails to load it with errorText
+func devTools(t *testing.T, errorText string) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		send := func(v interface{}) {
+			if err := websocket.JSON.Send(conn, v); err != nil {
+				t.Error(err)
+			}
+		}
+		request := func(session, url string) {
+			send(map[string]interface{}{
+				"method":    "Network.requestWillBeSent",
+				"sessionId": session,
+				"params":    map[string]interface{}{"request": map[string]string{"url": url}, "type": "Script"},
+			})
+		}
+		for {
+			var cmd struct {
+				ID        int             `json:"id"`
+				Method    string          `json:"method"`
+				Params    json.RawMessage `json:"params"`
+				SessionID string          `json:"sessionId"`
+			}
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			result := map[string]interface{}{}
+			switch cmd.Method {
+			case "Target.createTarget":
+				result["targetId"] = "T1"
+			case "Target.attachToTarget":
+				result["sessionId"] = "S1"
+			case "Page.navigate":
+				if errorText != "" {
+					result["errorText"] = errorText
+					break
+				}
+				var params struct {
+					URL string `json:"url"`
+				}
+				json.Unmarshal(cmd.Params, &params)
+				request("S1", params.URL)
+				request("S2", "https://other-tab.example.org/x.js")
+				request("S1", "data:image/gif;base64,R0lGOD")
+				request("S1", "https://www.googletagmanager.com/gtag/js?id=G-1")
+			}
+			send(map[string]interface{}{"id": cmd.ID, "result": result})
+			if cmd.Method == "Page.navigate" && errorText == "" {
+				send(map[string]interface{}{"method": "Page.loadEventFired", "sessionId": "S1", "params": map[string]float64{"timestamp": 1}})
+				request("S1", "https://static.hotjar.com/c/hotjar-1.js")
+			}
+		}
+	}))
+}
+
+func TestRequests(t *testing.T) {
+	server := devTools(t, "")
+	defer server.Close()
+
+	b, err := Start("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.Timeout, b.Settle = 5*time.Second, 100*time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		requests, err := b.Requests("https://www.example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range requests {
+			got = append(got, r.URL)
+		}
+		want := []string{"https://www.example.com/", "https://www.googletagmanager.com/gtag/js?id=G-1", "https://static.hotjar.com/c/hotjar-1.js"}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("load %d: got requests %v, want %v", i, got, want)
+		}
+		if len(requests) > 0 && requests[0].Type != "Script" {
+			t.Errorf("load %d: got type %q", i, requests[0].Type)
+		}
+	}
+}
+
+func TestRequestsNavigationError(t *testing.T) {
+	server := devTools(t, "net::ERR_NAME_NOT_RESOLVED")
+	defer server.Close()
+
+	b, err := Dial("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if _, err := b.Requests("https://missing.example.com/"); err == nil || !strings.Contains(err.Error(), "ERR_NAME_NOT_RESOLVED") {
+		t.Errorf("Expected the navigation error, got %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gregcmartin/spectre/decode"
+	"github.com/gregcmartin/spectre/deobfuscate"
+	"github.com/gregcmartin/spectre/models"
+	"github.com/gregcmartin/spectre/patterns"
+	"github.com/gregcmartin/spectre/rules"
+	"github.com/gregcmartin/spectre/secrets"
+	"github.com/gregcmartin/spectre/selection"
+)
+
+const rulesUsage = `Usage: spectre rules <command> [arguments]
+
+Commands:
+  list [-c categories] [-patterns names] [-v]
+                                      list categories, patterns and their metadata
+  test <pattern|regex> <file|url>     show a pattern's matches in a document with context
+  lint [regex...]                     check patterns, and any regexes given, for risky constructs
+  explain <finding|pattern> [value]   show which rule produced a finding and why
+
+A pattern is named as Category/Pattern Name or by its name alone. A finding
+is a JSON object from the results file, or - to read one from stdin.
+`
+
+// runRules implements the `spectre rules` subcommand
+func runRules(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, rulesUsage)
+		return 2
+	}
+	switch args[0] {
+	case "list":
+		return runRulesList(args[1:])
+	case "test":
+		return runRulesTest(args[1:])
+	case "lint":
+		return runRulesLint(args[1:])
+	case "explain":
+		return runRulesExplain(args[1:])
+	}
+	fmt.Fprint(os.Stderr, rulesUsage)
+	return 2
+}
+
+func runRulesList(args []string) int {
+	fs := flag.NewFlagSet("rules list", flag.ExitOnError)
+	category := fs.String("c", "all", "comma-separated categories to list; prefix with - to skip")
+	names := fs.String("patterns", "", "comma-separated pattern names or tags to list; prefix with - to skip")
+	verbose := fs.Bool("v", false, "show each pattern's regex, targets, tags and description")
+	fs.Parse(args)
+	sel, err := selection.Parse(*category, *names, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m %v\n", err)
+		return 2
+	}
+
+	fmt.Printf("%-18s %-28s %-6s %-20s %s\n", "CATEGORY", "PATTERN", "RISK", "TARGETS", "CONTROLS")
+	for _, pt := range patterns.AllPatternTypes {
+		if !sel.Pattern(pt) {
+			continue
+		}
+		description, risk, _ := models.Describe(pt.Category, pt.Name)
+		var targets []string
+		for _, t := range pt.Targets {
+			if !contains(targets, t.Type) {
+				targets = append(targets, t.Type)
+			}
+		}
+		if pt.Pattern != "" {
+			targets = append([]string{"body"}, targets...)
+		}
+		fmt.Printf("%-18s %-28s %-6s %-20s %s\n", pt.Category, pt.Name, risk, strings.Join(targets, ","), strings.Join(controlNames(pt), ","))
+
+		if *verbose {
+			if pt.Pattern != "" {
+				fmt.Printf("    regex: %s\n", pt.Pattern)
+			}
+			for _, t := range pt.Targets {
+				fmt.Printf("    %s: %s\n", targetName(t), t.Pattern)
+			}
+			if domains := patterns.Domains(pt); len(domains) > 0 {
+				fmt.Printf("    domains: %s\n", strings.Join(domains, ", "))
+			}
+			fmt.Printf("    tags: %s\n", strings.Join(pt.AllTags(), ", "))
+			fmt.Printf("    %s\n", description)
+		}
+	}
+	return 0
+}
+
+// controlNames lists the false-positive controls a pattern sets
+func controlNames(pt patterns.PatternType) []string {
+	var names []string
+	if pt.Exclude != "" {
+		names = append(names, "exclude")
+	}
+	if pt.Requires != "" {
+		names = append(names, "requires")
+	}
+	if pt.MinMatches > 0 {
+		names = append(names, fmt.Sprintf("min=%d", pt.MinMatches))
+	}
+	if pt.Weak != "" {
+		names = append(names, "weak")
+	}
+	return names
+}
+
+func targetName(t patterns.Target) string {
+	if t.Key != "" {
+		return t.Type + " " + t.Key
+	}
+	return t.Type
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func runRulesTest(args []string) int {
+	fs := flag.NewFlagSet("rules test", flag.ExitOnError)
+	width := fs.Int("w", 40, "characters of context to show around each match")
+	ua := fs.String("ua", "Spectre", "User-Agent for URLs")
+	reveal := fs.Bool("reveal-secrets", false, "show matched secrets unmasked")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: spectre rules test [options] <pattern|regex> <file|url>\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	pts := rules.Find(patterns.AllPatternTypes, fs.Arg(0))
+	if len(pts) == 0 {
+		pts = []patterns.PatternType{{Category: "Test", Name: fs.Arg(0), Pattern: fs.Arg(0)}}
+	}
+	content, err := readDocument(fs.Arg(1), *ua)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error reading %s: %v\n", fs.Arg(1), err)
+		return 1
+	}
+	decoded := deobfuscate.Normalize(content)
+
+	reported := false
+	for _, pt := range pts {
+		if pt.Pattern == "" {
+			fmt.Printf("\033[34m[*]\033[37m %s/%s matches headers, cookies, meta tags or scripts only\n", pt.Category, pt.Name)
+			continue
+		}
+		matches, err := rules.Test(pt, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Invalid pattern %s: %v\n", pt.Name, err)
+			return 1
+		}
+		var all, decodedMatches []rules.Match
+		if decoded != content {
+			all, _ = rules.Test(pt, decoded)
+			for _, m := range all {
+				if !strings.Contains(content, decoded[m.Start:m.End]) {
+					decodedMatches = append(decodedMatches, m)
+				}
+			}
+		}
+		fmt.Printf("\033[34m[*]\033[37m %s/%s: %d matches in %s\n", pt.Category, pt.Name, len(matches)+len(decodedMatches), fs.Arg(1))
+
+		// Secrets are masked as the scanner masks them, in the context of
+		// other matches too
+		shown, shownDecoded := content, decoded
+		if !*reveal {
+			mask := secrets.MaskEmbedded
+			if pt.Category == "Secrets" {
+				mask = secrets.MaskMatch
+			}
+			shown, shownDecoded = maskMatches(content, matches, mask), maskMatches(decoded, all, mask)
+		}
+		reported = printMatches(shown, matches, "", *width) || reported
+		reported = printMatches(shownDecoded, decodedMatches, " (decoded)", *width) || reported
+	}
+	if !reported {
+		return 1
+	}
+	return 0
+}
+
+// maskMatches returns content with each match masked. Masks keep the
+// length of a match, so match offsets still hold.
+func maskMatches(content string, matches []rules.Match, mask func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(content[last:m.Start])
+		b.WriteString(mask(content[m.Start:m.End]))
+		last = m.End
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// printMatches prints matches with context and reports whether any
+// survived the pattern's controls
+func printMatches(content string, matches []rules.Match, note string, width int) bool {
+	kept := false
+	for _, m := range matches {
+		before, match, after := rules.Snippet(content, m.Start, m.End, width)
+		if m.Dropped != "" {
+			fmt.Printf("\033[33m[!]\033[37m line %d%s dropped, %s: %s\033[33m%s\033[37m%s\n", m.Line, note, m.Dropped, before, match, after)
+			continue
+		}
+		kept = true
+		fmt.Printf("\033[32m[+]\033[37m line %d%s %s confidence: %s\033[32m%s\033[37m%s\n", m.Line, note, m.Confidence, before, match, after)
+	}
+	return kept
+}
+
+// readDocument fetches a URL, undoing its content encoding, or reads a
+// local file
+func readDocument(source, ua string) (string, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		file, err := os.Open(source)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		text, _, _ := decode.Transcode(file, "")
+		data, err := io.ReadAll(text)
+		return string(data), err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   10 * time.Second,
+	}
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Accept-Encoding", decode.AcceptEncoding)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _, err := decode.Body(resp.Body, resp.Header.Get("Content-Encoding"), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+func runRulesLint(args []string) int {
+	pts := patterns.AllPatternTypes
+	for i, arg := range args {
+		pts = append(pts[:len(pts):len(pts)], patterns.PatternType{Category: "Test", Name: fmt.Sprintf("regex %d", i+1), Pattern: arg})
+	}
+	issues := rules.Lint(pts)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		fmt.Fprintf(os.Stderr, "\033[33m[!]\033[37m %d issues\n", len(issues))
+		return 1
+	}
+	return 0
+}
+
+func runRulesExplain(args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprint(os.Stderr, rulesUsage)
+		return 2
+	}
+
+	var finding models.Finding
+	arg := strings.TrimSpace(args[0])
+	switch {
+	case arg == "-" || strings.HasPrefix(arg, "{"):
+		var r io.Reader = strings.NewReader(arg)
+		if arg == "-" {
+			r = os.Stdin
+		}
+		if err := json.NewDecoder(r).Decode(&finding); err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error reading finding: %v\n", err)
+			return 1
+		}
+	default:
+		found := rules.Find(patterns.AllPatternTypes, arg)
+		if len(found) == 0 {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m No pattern named %s\n", arg)
+			return 1
+		}
+		finding = models.Finding{Category: found[0].Category, PatternType: found[0].Name}
+		if len(args) == 2 {
+			finding.Value = args[1]
+		}
+	}
+
+	description, risk, _ := models.Describe(finding.Category, finding.PatternType)
+	found := rules.Find(patterns.AllPatternTypes, finding.Category+"/"+finding.PatternType)
+	if len(found) == 0 {
+		fmt.Printf("%s (%s) findings come from scanner analysis, not a detection pattern\n", finding.Category, finding.PatternType)
+		fmt.Printf("Description: %s\n", description)
+		return 1
+	}
+	pt := found[0]
+	e := rules.Explain(pt, finding.Value, finding.Implementation["target"], finding.Implementation["key"], finding.Confidence)
+
+	fmt.Printf("Rule:        %s/%s\n", pt.Category, pt.Name)
+	fmt.Printf("Description: %s\n", description)
+	fmt.Printf("Risk:        %s\n", risk)
+	if pt.Pattern != "" {
+		fmt.Printf("Regex:       %s\n", pt.Pattern)
+	}
+	if finding.Value != "" {
+		fmt.Printf("Value:       %s\n", finding.Value)
+		if finding.Implementation["masked"] == "true" {
+			fmt.Println("Matched:     unknown, the value is masked; rescan with -reveal-secrets")
+		} else if len(e.Targets) > 0 {
+			for _, t := range e.Targets {
+				fmt.Printf("Matched:     %s target %s\n", targetName(t), t.Pattern)
+			}
+		} else if len(e.Branches) > 0 {
+			for _, branch := range e.Branches {
+				fmt.Printf("Matched:     alternative %s\n", branch)
+			}
+		} else {
+			fmt.Println("Matched:     no part of the rule matches the value")
+		}
+	}
+	if finding.Confidence != "" && e.Reason != "" {
+		fmt.Printf("Confidence:  %s, %s\n", finding.Confidence, e.Reason)
+	}
+	if pt.Exclude != "" {
+		fmt.Printf("Exclude:     %s\n", pt.Exclude)
+	}
+	if pt.Requires != "" {
+		fmt.Printf("Requires:    %s\n", pt.Requires)
+	}
+	if pt.MinMatches > 0 {
+		fmt.Printf("Min matches: %d\n", pt.MinMatches)
+	}
+	if pt.Weak != "" {
+		fmt.Printf("Weak:        %s\n", pt.Weak)
+	}
+	if finding.Suppressed != "" {
+		fmt.Printf("Suppressed:  %s\n", finding.Suppressed)
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+// Explanation describes which part of a pattern produced a finding
+type Explanation struct {
+	Pattern patterns.PatternType
+	// Targets are the non-body targets that match a finding from one
+	Targets []patterns.Target
+	// Branches are the alternatives of the body pattern matching the value
+	Branches []string
+	Reason   string // why the finding has its confidence
+}
+
+// Explain works out why pt reported value. target and key are the
+// finding's "target" and "key" details, empty for body matches, and
+// confidence is the confidence it was reported with.
+func Explain(pt patterns.PatternType, value, target, key, confidence string) Explanation {
+	e := Explanation{Pattern: pt}
+	if target != "" {
+		e.Targets = matchingTargets(pt, value, target, key)
+		e.Reason = fmt.Sprintf("matches in the %s are always high confidence", target)
+		return e
+	}
+
+	for _, branch := range Branches(pt.Pattern) {
+		if re, err := regexp.Compile(branch); err == nil && re.MatchString(value) {
+			e.Branches = append(e.Branches, branch)
+		}
+	}
+	if len(e.Branches) == 0 {
+		// A value given without its finding's details may come from any
+		// target that tests more than a header's presence
+		for _, t := range pt.Targets {
+			if t.Pattern != "" && regexp.MustCompile(t.Pattern).MatchString(value) {
+				e.Targets = append(e.Targets, t)
+			}
+		}
+	}
+	controls, err := pt.Controls()
+	if err != nil {
+		return e
+	}
+	switch strings.ToLower(confidence) {
+	case patterns.ConfidenceHigh:
+		e.Reason = "the match names a domain of the pattern: " + strings.Join(controls.Domains, ", ")
+	case patterns.ConfidenceLow:
+		e.Reason = fmt.Sprintf("the match is a weak alternative (%s) and nothing more specific to the vendor matched in the document", pt.Weak)
+	case patterns.ConfidenceMedium:
+		e.Reason = "the match is in the body but does not name a domain of the pattern"
+	}
+	return e
+}
+
+// matchingTargets returns the targets of pt of the given type and key
+// whose pattern matches value
+func matchingTargets(pt patterns.PatternType, value, target, key string) []patterns.Target {
+	var found []patterns.Target
+	for _, t := range pt.Targets {
+		if t.Type != target || !strings.EqualFold(t.Key, key) {
+			continue
+		}
+		if re, err := regexp.Compile(t.Pattern); err == nil && re.MatchString(value) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
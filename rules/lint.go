@@ -0,0 +1,149 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/gregcmartin/spectre/matcher"
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+// Lint checks
+const (
+	CheckCatastrophic = "catastrophic"
+	CheckBroad        = "broad"
+	CheckShortToken   = "short-token"
+	CheckDuplicate    = "duplicate"
+)
+
+// minToken is the length below which an unanchored literal alternative
+// is likely to occur in unrelated text
+const minToken = 5
+
+// Issue is a problem Lint found in a pattern
+type Issue struct {
+	Pattern string // Category/Pattern Name
+	Check   string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Pattern, i.Check, i.Message)
+}
+
+// Lint checks the body patterns of pts for nested repetition, patterns
+// that match too much, short unanchored literals without an exclusion,
+// and literal alternatives that more than one pattern matches
+func Lint(pts []patterns.PatternType) []Issue {
+	var issues []Issue
+	owners := make(map[string][]string) // literal alternative -> patterns
+	var literals []string
+	for _, pt := range pts {
+		if pt.Pattern == "" {
+			continue
+		}
+		key := pt.Category + "/" + pt.Name
+		re, err := syntax.Parse(pt.Pattern, syntax.Perl)
+		if err != nil {
+			issues = append(issues, Issue{key, CheckBroad, fmt.Sprintf("does not compile: %v", err)})
+			continue
+		}
+		for _, msg := range nested(re, false) {
+			issues = append(issues, Issue{key, CheckCatastrophic, msg})
+		}
+		issues = append(issues, broad(key, pt.Pattern, re)...)
+
+		for _, branch := range Branches(pt.Pattern) {
+			lit, ok := literal(branch)
+			if !ok {
+				continue
+			}
+			if len(lit) < minToken && pt.Exclude == "" {
+				issues = append(issues, Issue{key, CheckShortToken,
+					fmt.Sprintf("alternative %q is unanchored; add context, \\b or an Exclude", lit)})
+			}
+			if len(owners[lit]) == 0 {
+				literals = append(literals, lit)
+			}
+			if !contains(owners[lit], key) {
+				owners[lit] = append(owners[lit], key)
+			}
+		}
+	}
+
+	// A literal alternative of one pattern contained in another pattern's
+	// literal alternative matches everything the longer one does
+	for _, short := range literals {
+		for _, long := range literals {
+			if !strings.Contains(long, short) {
+				continue
+			}
+			for _, a := range owners[short] {
+				for _, b := range owners[long] {
+					if a == b {
+						continue
+					}
+					msg := fmt.Sprintf("alternative %q is also matched by %s", long, b)
+					if long != short {
+						msg = fmt.Sprintf("alternative %q also matches %s's %q", short, b, long)
+					}
+					issues = append(issues, Issue{a, CheckDuplicate, msg})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// nested reports unbounded repetitions inside other unbounded repetitions,
+// such as (a+)+. Go's engine runs them in linear time, but they are slow
+// on large pages and backtrack catastrophically in other engines.
+func nested(re *syntax.Regexp, inRepeat bool) []string {
+	var found []string
+	repeat := unbounded(re)
+	if repeat && inRepeat {
+		found = append(found, fmt.Sprintf("nested unbounded repetition %s", re))
+	}
+	for _, sub := range re.Sub {
+		found = append(found, nested(sub, inRepeat || repeat)...)
+	}
+	return found
+}
+
+func unbounded(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max == -1)
+}
+
+// broad reports patterns that match the empty string, contain unbounded
+// wildcards, or lack literals the prefilter can skip documents on
+func broad(key, pattern string, re *syntax.Regexp) []Issue {
+	var issues []Issue
+	if regexp.MustCompile(pattern).MatchString("") {
+		issues = append(issues, Issue{key, CheckBroad, "matches the empty string, so every document matches"})
+	}
+	var walk func(re *syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		if unbounded(re) && (re.Sub[0].Op == syntax.OpAnyChar || re.Sub[0].Op == syntax.OpAnyCharNotNL) {
+			issues = append(issues, Issue{key, CheckBroad, fmt.Sprintf("unbounded wildcard %s can run to the end of the line", re)})
+		}
+		for _, sub := range re.Sub {
+			walk(sub)
+		}
+	}
+	walk(re)
+	if _, ok := matcher.Literals(pattern); !ok {
+		issues = append(issues, Issue{key, CheckBroad, "no literal occurs in every match, so the prefilter cannot skip any document"})
+	}
+	return issues
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+// Package rules helps write and review detection patterns: it looks
+// patterns up by name, shows how they match a document, lints them and
+// explains the findings they produce.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/gregcmartin/spectre/matcher"
+	"github.com/gregcmartin/spectre/patterns"
+	"github.com/gregcmartin/spectre/secrets"
+)
+
+// Find returns the patterns named by name, either Category/Pattern Name or
+// a pattern name alone, ignoring case
+func Find(pts []patterns.PatternType, name string) []patterns.PatternType {
+	var found []patterns.PatternType
+	for _, pt := range pts {
+		if strings.EqualFold(name, pt.Category+"/"+pt.Name) || strings.EqualFold(name, pt.Name) {
+			found = append(found, pt)
+		}
+	}
+	return found
+}
+
+// Match is one body match of a pattern
+type Match struct {
+	Start, End int
+	Line       int
+	Confidence string // empty when the controls dropped the match
+	Dropped    string // why the controls dropped the match
+}
+
+// Test returns every body match of pt in content, including those its
+// controls drop and generic secrets too low in entropy to be reported
+func Test(pt patterns.PatternType, content string) ([]Match, error) {
+	re, err := regexp.Compile(pt.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	controls, err := pt.Controls()
+	if err != nil {
+		return nil, err
+	}
+	all := re.FindAllStringIndex(content, -1)
+	if len(all) == 0 {
+		return nil, nil
+	}
+	kept, confidence := controls.Apply(content, all)
+	unexcluded, _ := patterns.Controls{Exclude: controls.Exclude}.Apply(content, all)
+
+	lines := matcher.NewLines(content)
+	matches := make([]Match, len(all))
+	for i, loc := range all {
+		m := Match{Start: loc[0], End: loc[1], Line: lines.Line(loc[0])}
+		entropy, low := lowEntropy(pt, content[loc[0]:loc[1]])
+		switch {
+		case indexOf(kept, loc) >= 0 && low:
+			m.Dropped = fmt.Sprintf("entropy %.2f is below %.1f", entropy, secrets.MinEntropy)
+		case indexOf(kept, loc) >= 0:
+			m.Confidence = confidence[indexOf(kept, loc)]
+		case indexOf(unexcluded, loc) < 0:
+			m.Dropped = fmt.Sprintf("excluded by %s", pt.Exclude)
+		case controls.Requires != nil && !controls.Requires.MatchString(content):
+			m.Dropped = fmt.Sprintf("requires %s elsewhere in the document", pt.Requires)
+		default:
+			m.Dropped = fmt.Sprintf("fewer than %d matches", pt.MinMatches)
+		}
+		matches[i] = m
+	}
+	return matches, nil
+}
+
+// lowEntropy returns the entropy of a generic secret match and reports
+// whether the scanner drops it as too low to be a credential
+func lowEntropy(pt patterns.PatternType, match string) (float64, bool) {
+	if pt.Category != "Secrets" || pt.Name != secrets.GenericPattern {
+		return 0, false
+	}
+	entropy := secrets.Entropy(secrets.Token(strings.TrimSpace(match)))
+	return entropy, entropy < secrets.MinEntropy
+}
+
+func indexOf(indexes [][]int, loc []int) int {
+	for i, l := range indexes {
+		if l[0] == loc[0] && l[1] == loc[1] {
+			return i
+		}
+	}
+	return -1
+}
+
+// Snippet returns the match at start:end with up to width bytes of context
+// on either side, with line breaks shown as spaces
+func Snippet(content string, start, end, width int) (before, match, after string) {
+	from, to := start-width, end+width
+	if from < 0 {
+		from = 0
+	}
+	if to > len(content) {
+		to = len(content)
+	}
+	flat := strings.NewReplacer("\r", " ", "\n", " ", "\t", " ")
+	return flat.Replace(content[from:start]), flat.Replace(content[start:end]), flat.Replace(content[end:to])
+}
+
+// leadingFlags matches a flag group such as (?i) at the start of a pattern
+var leadingFlags = regexp.MustCompile(`^\(\?[a-zA-Z]+\)`)
+
+// Branches splits a pattern into its top-level alternatives. A group
+// around the whole pattern is unwrapped, and a leading flag group such as
+// (?i) is kept on every alternative.
+func Branches(pattern string) []string {
+	flags := leadingFlags.FindString(pattern)
+	pattern = pattern[len(flags):]
+	for {
+		inner, ok := unwrap(pattern)
+		if !ok {
+			break
+		}
+		pattern = inner
+	}
+	var branches []string
+	for _, branch := range split(pattern) {
+		branches = append(branches, flags+branch)
+	}
+	return branches
+}
+
+// unwrap strips a group enclosing all of pattern
+func unwrap(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, "(") || closing(pattern) != len(pattern)-1 {
+		return pattern, false
+	}
+	inner := pattern[1 : len(pattern)-1]
+	switch {
+	case strings.HasPrefix(inner, "?:"):
+		inner = inner[2:]
+	case strings.HasPrefix(inner, "?P<"):
+		inner = inner[strings.Index(inner, ">")+1:]
+	case strings.HasPrefix(inner, "?"):
+		return pattern, false
+	}
+	return inner, true
+}
+
+// closing returns the offset of the parenthesis closing the one that
+// opens pattern, or -1
+func closing(pattern string) int {
+	end, depth := -1, 0
+	scanTopLevel(pattern, func(i int, c byte) bool {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				end = i
+				return false
+			}
+		}
+		return true
+	})
+	return end
+}
+
+// split splits pattern at alternation bars outside groups
+func split(pattern string) []string {
+	var parts []string
+	depth, start := 0, 0
+	scanTopLevel(pattern, func(i int, c byte) bool {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '|':
+			if depth == 0 {
+				parts = append(parts, pattern[start:i])
+				start = i + 1
+			}
+		}
+		return true
+	})
+	return append(parts, pattern[start:])
+}
+
+// scanTopLevel calls fn for each byte of pattern outside escapes and
+// character classes until fn returns false
+func scanTopLevel(pattern string, fn func(i int, c byte) bool) {
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '\\':
+			i++
+		case '[':
+			i = classEnd(pattern, i)
+		default:
+			if !fn(i, c) {
+				return
+			}
+		}
+	}
+}
+
+// classEnd returns the offset of the bracket closing the character class
+// opened at start
+func classEnd(pattern string, start int) int {
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			if strings.HasPrefix(pattern[i:], "[:") {
+				if end := strings.Index(pattern[i:], ":]"); end > 0 {
+					i += end + 1
+				}
+			}
+		case ']':
+			return i
+		}
+	}
+	return len(pattern)
+}
+
+// literal returns the text a regex matches when it matches only one
+// string, lowercased if it ignores case
+func literal(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	for re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+	if re.Op != syntax.OpLiteral {
+		return "", false
+	}
+	if re.Flags&syntax.FoldCase != 0 {
+		return strings.ToLower(string(re.Rune)), true
+	}
+	return string(re.Rune), true
+}
@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+func TestBranches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{`(?i)static\.hotjar\.com|hj\.`, []string{`(?i)static\.hotjar\.com`, `(?i)hj\.`}},
+		{`(a|b)`, []string{`a`, `b`}},
+		{`(?:a(b|c)|[|(]d)`, []string{`a(b|c)`, `[|(]d`}},
+		{`(a)|(b)`, []string{`(a)`, `(b)`}},
+		{`\(x\|y\)`, []string{`\(x\|y\)`}},
+		{`[]|]x|y`, []string{`[]|]x`, `y`}},
+	}
+	for _, tt := range tests {
+		got := Branches(tt.pattern)
+		if strings.Join(got, " ; ") != strings.Join(tt.want, " ; ") {
+			t.Errorf("Branches(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	pts := []patterns.PatternType{{Category: "Tracking", Name: "Hotjar"}, {Category: "CMS", Name: "Ghost"}}
+	if got := Find(pts, "tracking/hotjar"); len(got) != 1 || got[0].Name != "Hotjar" {
+		t.Errorf("Find by key: got %v", got)
+	}
+	if got := Find(pts, "ghost"); len(got) != 1 || got[0].Category != "CMS" {
+		t.Errorf("Find by name: got %v", got)
+	}
+	if got := Find(pts, "CMS/Hotjar"); len(got) != 0 {
+		t.Errorf("Find with wrong category: got %v", got)
+	}
+}
+
+func TestLint(t *testing.T) {
+	pts := []patterns.PatternType{
+		{Category: "A", Name: "Nested", Pattern: `(ab+c)+xyz`},
+		{Category: "A", Name: "Empty", Pattern: `x*`},
+		{Category: "A", Name: "Wildcard", Pattern: `token=.*`},
+		{Category: "A", Name: "Short", Pattern: `(?i)example\.com|ex\.`},
+		{Category: "A", Name: "Excluded", Pattern: `ex\.|excluded\.js`, Exclude: `[a-z]ex\.`},
+		{Category: "B", Name: "Overlap", Pattern: `cdn\.example\.com/ex\.js`},
+		{Category: "B", Name: "Anchored", Pattern: `\bzq\(|header-only-pattern`},
+		{Category: "B", Name: "Targets", Targets: []patterns.Target{{Type: patterns.TargetCookie, Pattern: `.*`}}},
+	}
+	var got []string
+	for _, issue := range Lint(pts) {
+		got = append(got, issue.Pattern+" "+issue.Check)
+	}
+	want := []string{
+		"A/Nested catastrophic",
+		"A/Empty broad",
+		"A/Empty broad",
+		"A/Wildcard broad",
+		"A/Short short-token",
+		"A/Short duplicate",    // example.com in B/Overlap
+		"A/Short duplicate",    // ex. in A/Excluded
+		"A/Excluded duplicate", // ex. in A/Short
+		"A/Short duplicate",    // ex. in B/Overlap
+		"A/Excluded duplicate", // ex. in B/Overlap
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got issues\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTest(t *testing.T) {
+	magento := patterns.PatternType{Category: "CMS", Name: "Magento", Pattern: `(?i)mage\.|magento`, Exclude: `(?i)[a-z0-9_]mage\.`, MinMatches: 2}
+	matches, err := Test(magento, "<img src=\"/image.png\">\nWe left Magento")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(matches))
+	}
+	if !strings.HasPrefix(matches[0].Dropped, "excluded") || matches[0].Line != 1 {
+		t.Errorf("image.: got %+v", matches[0])
+	}
+	if !strings.HasPrefix(matches[1].Dropped, "fewer than 2") || matches[1].Line != 2 {
+		t.Errorf("Magento: got %+v", matches[1])
+	}
+
+	graphql := patterns.PatternType{Category: "APISpec", Name: "GraphQL", Pattern: `/playground`, Requires: `graphql`}
+	matches, _ = Test(graphql, "see /playground")
+	if len(matches) != 1 || !strings.HasPrefix(matches[0].Dropped, "requires") {
+		t.Errorf("GraphQL: got %+v", matches)
+	}
+
+	hotjar := patterns.PatternType{Category: "Tracking", Name: "Hotjar", Pattern: `static\.hotjar\.com|hj\.`, Weak: `hj\.`}
+	matches, _ = Test(hotjar, "hj.q; static.hotjar.com")
+	if len(matches) != 2 || matches[0].Confidence != patterns.ConfidenceMedium || matches[1].Confidence != patterns.ConfidenceHigh {
+		t.Errorf("Hotjar: got %+v", matches)
+	}
+
+	generic := patterns.PatternType{Category: "Secrets", Name: "Generic High Entropy", Pattern: `token = "[A-Za-z0-9]{20,}"`}
+	matches, _ = Test(generic, `token = "aaaaaaaaaaaaaaaaaaaaaaaa"; token = "k3Jd9XqP2mZ7vL4tR8wN1bYc"`)
+	if len(matches) != 2 || !strings.HasPrefix(matches[0].Dropped, "entropy") || matches[1].Confidence == "" {
+		t.Errorf("Generic High Entropy: got %+v", matches)
+	}
+
+	before, match, after := Snippet("abc\ndefgh", 4, 6, 2)
+	if before != "c " || match != "de" || after != "fg" {
+		t.Errorf("Snippet: got %q %q %q", before, match, after)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	pt := patterns.PatternType{
+		Category: "Tracking",
+		Name:     "Hotjar",
+		Pattern:  `(?i)static\.hotjar\.com|hj\.`,
+		Weak:     `hj\.`,
+		Targets:  []patterns.Target{{Type: patterns.TargetCookie, Pattern: `^_hjSession`}, {Type: patterns.TargetHeader, Key: "X-Hotjar"}},
+	}
+
+	e := Explain(pt, "STATIC.hotjar.com", "", "", "high")
+	if len(e.Branches) != 1 || e.Branches[0] != `(?i)static\.hotjar\.com` || !strings.Contains(e.Reason, "static.hotjar.com") {
+		t.Errorf("body match: got %+v", e)
+	}
+	e = Explain(pt, "hj.", "", "", "low")
+	if len(e.Branches) != 1 || !strings.Contains(e.Reason, "weak") {
+		t.Errorf("weak match: got %+v", e)
+	}
+	e = Explain(pt, "_hjSession_1", "cookie", "", "high")
+	if len(e.Targets) != 1 || e.Targets[0].Type != patterns.TargetCookie || len(e.Branches) != 0 {
+		t.Errorf("cookie match: got %+v", e)
+	}
+	e = Explain(pt, "_hjSession_1", "", "", "")
+	if len(e.Targets) != 1 || e.Targets[0].Type != patterns.TargetCookie {
+		t.Errorf("value without target: got %+v", e)
+	}
+}
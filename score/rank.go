@@ -0,0 +1,102 @@
+package score
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/gregcmartin/spectre/models"
+)
+
+// Site is the score recorded for one scanned URL
+type Site struct {
+	URL      string `json:"url"`
+	InputURL string `json:"input_url,omitempty"`
+	Score    int    `json:"score"`
+	Grade    string `json:"grade"`
+	Findings int    `json:"findings"` // scored findings
+
+	Tags map[string]string `json:"tags,omitempty"` // of the scanned input
+}
+
+// ReadResults collects the score findings of a Spectre result file, either
+// a stream of JSON findings as written by -o or a single JSON array
+func ReadResults(r io.Reader) ([]Site, error) {
+	br := bufio.NewReader(r)
+	var sites []Site
+	add := func(f models.Finding) {
+		if f.Category != Category {
+			return
+		}
+		score, err := strconv.Atoi(f.Implementation["score"])
+		if err != nil {
+			return
+		}
+		findings, _ := strconv.Atoi(f.Implementation["findings"])
+		sites = append(sites, Site{
+			URL:      f.Location,
+			InputURL: f.InputURL,
+			Score:    score,
+			Grade:    f.Implementation["grade"],
+			Findings: findings,
+			Tags:     f.Tags,
+		})
+	}
+
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(br)
+	if first == '[' {
+		var all []models.Finding
+		if err := decoder.Decode(&all); err != nil {
+			return nil, err
+		}
+		for _, f := range all {
+			add(f)
+		}
+		return sites, nil
+	}
+	for {
+		var f models.Finding
+		err := decoder.Decode(&f)
+		if err == io.EOF {
+			return sites, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(f)
+	}
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// Sort orders sites from the lowest score to the highest, or the reverse
+// when best is set. Ties are broken by URL.
+func Sort(sites []Site, best bool) {
+	sort.SliceStable(sites, func(i, j int) bool {
+		a, b := sites[i], sites[j]
+		if a.Score != b.Score {
+			return (a.Score < b.Score) != best
+		}
+		return a.URL < b.URL
+	})
+}
@@ -0,0 +1,176 @@
+package score
+
+import (
+	_ "embed"
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/gregcmartin/spectre/buckets"
+	"github.com/gregcmartin/spectre/headers"
+	"github.com/gregcmartin/spectre/models"
+)
+
+//go:embed model.json
+var defaultModel []byte
+
+// Category is the finding category scores are reported under. Findings in
+// it, and suppressed findings, are not scored.
+const Category = "Score"
+
+// Bonus deductions, applied on top of the weights of the findings involved
+const (
+	HiddenIframe         = "Hidden Iframe"
+	UnconsentedRecording = "Session Recording Without Consent"
+	ExposedBucket        = "Exposed Bucket"
+)
+
+// Model weighs findings into a score. Each finding costs the weight of its
+// identifier type (TrackerID findings only), else its pattern type, else its
+// category, else Default. A category's total is limited to its cap.
+type Model struct {
+	Default     float64            `json:"default"`
+	Categories  map[string]float64 `json:"categories"`
+	Patterns    map[string]float64 `json:"patterns"`
+	Identifiers map[string]float64 `json:"identifiers"`
+	Caps        map[string]float64 `json:"caps"`
+	Bonuses     Bonuses            `json:"bonuses"`
+}
+
+// Bonuses are extra deductions for the most serious combinations
+type Bonuses struct {
+	// HiddenIframe is deducted once when a page has any hidden iframe
+	HiddenIframe float64 `json:"hidden_iframe"`
+	// UnconsentedRecording is deducted once when session recording runs on a
+	// page without a consent manager, or is reported as not held until consent
+	UnconsentedRecording float64 `json:"unconsented_recording"`
+	// ExposedBucket is deducted for each publicly listable or readable bucket
+	ExposedBucket float64 `json:"exposed_bucket"`
+}
+
+// Default returns the bundled model
+func Default() *Model {
+	m, err := Parse(defaultModel)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Load reads a model file. Weights it does not set keep their defaults.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := Default()
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Parse reads a model in JSON form
+func Parse(data []byte) (*Model, error) {
+	m := &Model{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// weight returns the points a finding costs
+func (m *Model) weight(f models.Finding) float64 {
+	if f.Category == "TrackerID" {
+		if w, ok := m.Identifiers[f.PatternType]; ok {
+			return w
+		}
+	}
+	if w, ok := m.Patterns[f.PatternType]; ok {
+		return w
+	}
+	if w, ok := m.Categories[f.Category]; ok {
+		return w
+	}
+	return m.Default
+}
+
+// Score rates everything recorded for a URL
+func (m *Model) Score(u models.URLFindings) models.Score {
+	points := make(map[string]float64)
+	var hiddenIframe, recording, cmp, recordingViolation bool
+	exposed := 0
+	for _, f := range u.Findings {
+		if f.Category == Category || f.Suppressed != "" {
+			continue
+		}
+		points[f.Category] += m.weight(f)
+		switch f.Category {
+		case "HiddenIframe":
+			hiddenIframe = true
+		case "SessionRecording":
+			recording = true
+		case "ConsentManagement":
+			cmp = true
+		case "ConsentCompliance":
+			if f.Implementation["tracker_category"] == "SessionRecording" {
+				recordingViolation = true
+			}
+		case "CloudStorage":
+			switch f.Implementation["exposure"] {
+			case buckets.StatusPublicListable, buckets.StatusPublicReadable:
+				exposed++
+			}
+		}
+	}
+	for category, p := range points {
+		if limit, ok := m.Caps[category]; ok && p > limit {
+			points[category] = limit
+		}
+	}
+	if hiddenIframe {
+		points[HiddenIframe] = m.Bonuses.HiddenIframe
+	}
+	if recording && (!cmp || recordingViolation) {
+		points[UnconsentedRecording] = m.Bonuses.UnconsentedRecording
+	}
+	if exposed > 0 {
+		points[ExposedBucket] = m.Bonuses.ExposedBucket * float64(exposed)
+	}
+
+	result := models.Score{}
+	total := 0.0
+	for reason, p := range points {
+		if p <= 0 {
+			continue
+		}
+		total += p
+		result.Deductions = append(result.Deductions, models.Deduction{Reason: reason, Points: p})
+	}
+	sort.Slice(result.Deductions, func(i, j int) bool {
+		a, b := result.Deductions[i], result.Deductions[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Reason < b.Reason
+	})
+	result.Score = 100 - int(math.Round(total))
+	if result.Score < 0 {
+		result.Score = 0
+	}
+	// Grades use the same scale as the security header score
+	result.Grade = headers.Grade(result.Score)
+	return result
+}
+
+// RiskLevel returns the risk level reported for a grade
+func RiskLevel(grade string) string {
+	switch grade {
+	case "A", "B":
+		return "Low"
+	case "C", "D":
+		return "Medium"
+	}
+	return "High"
+}
@@ -0,0 +1,171 @@
+package score
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/models"
+)
+
+func finding(category, patternType string, details map[string]string) models.Finding {
+	return models.Finding{Category: category, PatternType: patternType, Implementation: details}
+}
+
+func TestScore(t *testing.T) {
+	m := &Model{
+		Default:     3,
+		Categories:  map[string]float64{"Tracking": 5, "TrackerID": 2, "ConsentManagement": 0, "CloudStorage": 4, "HiddenIframe": 6},
+		Patterns:    map[string]float64{"Header Score": 0, "Google Tag Manager ID": 9},
+		Identifiers: map[string]float64{"Facebook Pixel ID": 4},
+		Caps:        map[string]float64{"Tracking": 12},
+		Bonuses:     Bonuses{HiddenIframe: 10, UnconsentedRecording: 15, ExposedBucket: 20},
+	}
+
+	tests := []struct {
+		name     string
+		findings []models.Finding
+		score    int
+		grade    string
+		reasons  string
+	}{
+		{"empty", nil, 100, "A", ""},
+		{"ignores score findings", []models.Finding{finding(Category, "Privacy Score", nil)}, 100, "A", ""},
+		{"ignores suppressed findings", []models.Finding{{Category: "Tracking", PatternType: "Hotjar", Suppressed: "approved"}}, 100, "A", ""},
+		{"zero weights", []models.Finding{finding("SecurityHeaders", "Header Score", nil), finding("ConsentManagement", "OneTrust", nil)}, 100, "A", ""},
+		{"default weight", []models.Finding{finding("Unknown", "Thing", nil)}, 97, "A", "Unknown"},
+		{
+			"identifier before pattern before category",
+			[]models.Finding{finding("TrackerID", "Facebook Pixel ID", nil), finding("TrackerID", "Google Tag Manager ID", nil), finding("TrackerID", "AdSense Publisher ID", nil)},
+			85, "B", "TrackerID",
+		},
+		{
+			"category cap",
+			[]models.Finding{finding("Tracking", "A", nil), finding("Tracking", "B", nil), finding("Tracking", "C", nil)},
+			88, "B", "Tracking",
+		},
+		{
+			"hidden iframe bonus once",
+			[]models.Finding{finding("HiddenIframe", "zero-size", nil), finding("HiddenIframe", "off-screen", nil)},
+			78, "B", "HiddenIframe,Hidden Iframe",
+		},
+		{
+			"session recording without cmp",
+			[]models.Finding{finding("SessionRecording", "Hotjar", nil)},
+			82, "B", "Session Recording Without Consent,SessionRecording",
+		},
+		{
+			"session recording behind cmp",
+			[]models.Finding{finding("SessionRecording", "Hotjar", nil), finding("ConsentManagement", "OneTrust", nil)},
+			97, "A", "SessionRecording",
+		},
+		{
+			"session recording before consent",
+			[]models.Finding{
+				finding("SessionRecording", "Hotjar", nil),
+				finding("ConsentManagement", "OneTrust", nil),
+				finding("ConsentCompliance", "Tracker Before Consent", map[string]string{"tracker_category": "SessionRecording"}),
+			},
+			79, "B", "Session Recording Without Consent,ConsentCompliance,SessionRecording",
+		},
+		{
+			"exposed buckets",
+			[]models.Finding{
+				finding("CloudStorage", "AWS S3 Bucket", map[string]string{"exposure": "public-listable"}),
+				finding("CloudStorage", "GCS Bucket", map[string]string{"exposure": "public-readable"}),
+				finding("CloudStorage", "Azure Blob", map[string]string{"exposure": "private"}),
+			},
+			48, "D", "Exposed Bucket,CloudStorage",
+		},
+		{
+			"floor at zero",
+			[]models.Finding{
+				finding("CloudStorage", "AWS S3 Bucket", map[string]string{"exposure": "public-listable"}),
+				finding("CloudStorage", "AWS S3 Bucket", map[string]string{"exposure": "public-listable"}),
+				finding("CloudStorage", "AWS S3 Bucket", map[string]string{"exposure": "public-listable"}),
+				finding("CloudStorage", "AWS S3 Bucket", map[string]string{"exposure": "public-listable"}),
+				finding("CloudStorage", "AWS S3 Bucket", map[string]string{"exposure": "public-listable"}),
+			},
+			0, "F", "Exposed Bucket,CloudStorage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Score(models.URLFindings{Findings: tt.findings})
+			var reasons []string
+			for _, d := range got.Deductions {
+				reasons = append(reasons, d.Reason)
+			}
+			if got.Score != tt.score || got.Grade != tt.grade || strings.Join(reasons, ",") != tt.reasons {
+				t.Errorf("got %d %s %v, want %d %s %s", got.Score, got.Grade, reasons, tt.score, tt.grade, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(path, []byte(`{"categories": {"Tracking": 50}, "bonuses": {"hidden_iframe": 1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := Default()
+	if m.Categories["Tracking"] != 50 || m.Bonuses.HiddenIframe != 1 {
+		t.Errorf("overrides not applied: %v %v", m.Categories["Tracking"], m.Bonuses.HiddenIframe)
+	}
+	if m.Categories["AdNetwork"] != def.Categories["AdNetwork"] || m.Bonuses.ExposedBucket != def.Bonuses.ExposedBucket {
+		t.Error("weights not in the file should keep their defaults")
+	}
+}
+
+func TestRanking(t *testing.T) {
+	results := `{"category": "Tracking", "pattern_type": "Hotjar", "location": "https://a.example/#L3"}
+{"category": "Score", "pattern_type": "Privacy Score", "location": "https://a.example/", "implementation": {"score": "70", "grade": "C", "findings": "4"}}
+{"category": "Score", "pattern_type": "Privacy Score", "location": "https://c.example/", "implementation": {"score": "95", "grade": "A"}}
+{"category": "Score", "pattern_type": "Privacy Score", "location": "https://b.example/", "input_url": "http://b.example/", "implementation": {"score": "70", "grade": "C"}}
+{"url": "https://b.example/", "input_url": "http://b.example/", "first_party_cookies": 0, "third_party_cookies": 0, "score": {"score": 70, "grade": "C"}}`
+
+	sites, err := ReadResults(strings.NewReader(results))
+	if err != nil {
+		t.Fatal(err)
+	}
+	array, err := ReadResults(strings.NewReader("[" + strings.ReplaceAll(results, "}\n{", "},{") + "]"))
+	if err != nil || len(array) != len(sites) {
+		t.Fatalf("array form read %d sites, err %v", len(array), err)
+	}
+
+	Sort(sites, false)
+	var got []string
+	for _, s := range sites {
+		got = append(got, s.URL)
+	}
+	if want := "https://a.example/,https://b.example/,https://c.example/"; strings.Join(got, ",") != want {
+		t.Errorf("lowest first: got %v", got)
+	}
+	if sites[0].Findings != 4 || sites[1].InputURL != "http://b.example/" {
+		t.Errorf("site fields not read: %+v", sites[:2])
+	}
+
+	Sort(sites, true)
+	if sites[0].URL != "https://c.example/" || sites[1].URL != "https://a.example/" {
+		t.Errorf("best first: got %v", sites)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	m := Default()
+	if m.Default <= 0 || len(m.Categories) == 0 || m.Bonuses.UnconsentedRecording <= 0 {
+		t.Errorf("bundled model incomplete: %+v", m)
+	}
+	if got := RiskLevel("A"); got != "Low" {
+		t.Errorf("RiskLevel(A) = %s", got)
+	}
+	if got := RiskLevel("F"); got != "High" {
+		t.Errorf("RiskLevel(F) = %s", got)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gregcmartin/spectre/score"
+)
+
+// runScores implements the `spectre scores` subcommand
+func runScores(args []string) int {
+	fs := flag.NewFlagSet("scores", flag.ExitOnError)
+	output := fs.String("o", "", "write the ranking to file instead of stdout")
+	asJSON := fs.Bool("json", false, "write the ranking as JSON")
+	best := fs.Bool("best", false, "rank the highest scores first")
+	limit := fs.Int("n", 0, "number of sites to list (0 for all)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: spectre scores [options] <results.json|results.json.zip>...\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var sites []score.Site
+	for _, path := range fs.Args() {
+		err := readResultFile(path, func(r io.Reader) error {
+			found, err := score.ReadResults(r)
+			sites = append(sites, found...)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error reading %s: %v\n", path, err)
+			return 1
+		}
+	}
+	score.Sort(sites, *best)
+	if *limit > 0 && len(sites) > *limit {
+		sites = sites[:*limit]
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error writing ranking: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if sites == nil {
+			sites = []score.Site{}
+		}
+		if err := encoder.Encode(sites); err != nil {
+			fmt.Fprintf(os.Stderr, "\033[31m[-]\033[37m Error writing ranking: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	for _, site := range sites {
+		fmt.Fprintf(out, "%3d %-2s %s\n", site.Score, site.Grade, site.URL)
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// MinEntropy is the Shannon entropy in bits per character a generic
+// credential-like value must reach to be reported
+const MinEntropy = 3.5
+
+// GenericPattern is the pattern name whose matches are filtered by entropy
+const GenericPattern = "Generic High Entropy"
+
+// tokenPattern matches runs of characters used in keys and tokens
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_.-]{8,}`)
+
+// embeddedKeyPattern matches credentials embedded in matches from other
+// categories, such as Azure connection strings reported under CloudStorage
+var embeddedKeyPattern = regexp.MustCompile(`(AccountKey=)([^;"'\s]+)`)
+
+// verificationHints describes how to confirm a leaked credential is live
+var verificationHints = map[string]string{
+	"AWS Access Key ID":         "Run `aws sts get-caller-identity` with the key pair; a valid key returns the account ID and ARN",
+	"AWS Secret Access Key":     "Pair with the matching access key ID and run `aws sts get-caller-identity`",
+	"GCP API Key":               "Request https://maps.googleapis.com/maps/api/geocode/json?address=test&key=<key>; REQUEST_DENIED means restricted or invalid",
+	"GCP Service Account Key":   "Run `gcloud auth activate-service-account --key-file=<file>`; success means the key is active",
+	"Azure Storage Account Key": "List containers with `az storage container list --account-name <name> --account-key <key>`",
+	"Stripe Secret Key":         "Request https://api.stripe.com/v1/charges with the key as basic auth user; 401 means revoked",
+	"Twilio API Key":            "Request https://api.twilio.com/2010-04-01/Accounts.json with SID and secret as basic auth",
+	"Slack Token":               "POST the token to https://slack.com/api/auth.test; \"ok\": true means it is live",
+	"Slack Webhook":             "POST an empty JSON body to the webhook; \"invalid_payload\" means it is live, 404 means revoked",
+	"Private Key":               "Derive the public key with `openssl pkey -pubout` and compare it against deployed certificates",
+	"JWT":                       "Decode the payload to check `exp` and issuer, then replay it against the issuing API",
+	GenericPattern:              "Identify the owning service from surrounding code and test the value against its API",
+}
+
+// Entropy returns the Shannon entropy of s in bits per character
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Token returns the longest key-like run of characters in a match, which is
+// the part of the match that carries the secret
+func Token(match string) string {
+	longest := ""
+	for _, candidate := range tokenPattern.FindAllString(match, -1) {
+		if len(candidate) > len(longest) {
+			longest = candidate
+		}
+	}
+	return longest
+}
+
+// Mask hides the middle of a secret, keeping a few characters on each end
+// so findings can still be told apart
+func Mask(s string) string {
+	if len(s) <= 12 {
+		if len(s) <= 2 {
+			return strings.Repeat("*", len(s))
+		}
+		return s[:2] + strings.Repeat("*", len(s)-2)
+	}
+	return s[:4] + strings.Repeat("*", len(s)-8) + s[len(s)-4:]
+}
+
+// MaskMatch masks the secret-carrying token inside a full pattern match
+func MaskMatch(match string) string {
+	token := Token(match)
+	if token == "" {
+		return match
+	}
+	return strings.Replace(match, token, Mask(token), 1)
+}
+
+// MaskEmbedded masks credentials embedded in a match from a non-secret category
+func MaskEmbedded(match string) string {
+	return embeddedKeyPattern.ReplaceAllStringFunc(match, func(m string) string {
+		parts := embeddedKeyPattern.FindStringSubmatch(m)
+		return parts[1] + Mask(parts[2])
+	})
+}
+
+// Hint returns the verification hint for a secret pattern
+func Hint(patternName string) string {
+	if hint, ok := verificationHints[patternName]; ok {
+		return hint
+	}
+	return verificationHints[GenericPattern]
+}
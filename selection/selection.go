@@ -0,0 +1,291 @@
+// Package selection decides which categories and patterns a scan reports,
+// from lists of categories, pattern names or tags, and risk levels.
+package selection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gregcmartin/spectre/compliance"
+	"github.com/gregcmartin/spectre/cookies"
+	"github.com/gregcmartin/spectre/headers"
+	"github.com/gregcmartin/spectre/iframes"
+	"github.com/gregcmartin/spectre/patterns"
+	"github.com/gregcmartin/spectre/redirects"
+	"github.com/gregcmartin/spectre/thirdparty"
+)
+
+// Analysis lists the categories reported by scanner analysis rather than
+// by patterns
+var Analysis = []string{"Cookies", "SecurityHeaders", "ConsentCompliance", "CMSVersion", "Redirects", "ThirdParty", "Score"}
+
+// AnalysisTypes are the pattern types of findings reported by scanner
+// analysis rather than by patterns, so they can be selected by name too
+var AnalysisTypes = analysisTypes()
+
+func analysisTypes() []patterns.PatternType {
+	var types []patterns.PatternType
+	add := func(category string, names ...string) {
+		for _, name := range names {
+			types = append(types, patterns.PatternType{Category: category, Name: name})
+		}
+	}
+	add("HiddenIframe", iframes.Kinds...)
+	add("ThirdParty", thirdparty.PatternTypes...)
+	add("Redirects", redirects.Types...)
+	add("Cookies", cookies.PatternTypes()...)
+	add("SecurityHeaders", headers.PatternTypes...)
+	for _, status := range []string{compliance.StatusNoCMP, compliance.StatusBeforeCMP, compliance.StatusUngated, compliance.StatusFired} {
+		add("ConsentCompliance", compliance.PatternTypes[status])
+	}
+	return types
+}
+
+// Risks are the risk levels a selection can name
+var Risks = []string{"low", "medium", "high", "critical"}
+
+// Selection is a parsed selection. A nil Selection selects everything.
+type Selection struct {
+	categories map[string]bool // lowercased; nil selects every category
+	named      map[string]bool // categories named explicitly, lowercased
+	skip       map[string]bool // excluded categories, lowercased
+	names      []string        // pattern names or tags to report
+	skipNames  []string        // pattern names or tags to skip
+	risks      map[string]bool // lowercased; nil selects every risk level
+}
+
+// Parse reads comma-separated lists of categories, pattern names or tags,
+// and risk levels. An item prefixed with - is removed from the selection,
+// and "all" selects every category, so "all,-CMS" selects every category
+// but CMS. A list of exclusions alone starts from everything. Unknown
+// items are an error.
+func Parse(categories, names, risks string) (*Selection, error) {
+	s := &Selection{named: make(map[string]bool), skip: make(map[string]bool)}
+	known := knownCategories()
+
+	include, exclude := split(categories)
+	all := len(include) == 0
+	for _, item := range include {
+		if strings.EqualFold(item, "all") {
+			all = true
+			continue
+		}
+		if !known[strings.ToLower(item)] {
+			return nil, fmt.Errorf("unknown category %q, use one of: %s", item, strings.Join(Categories(), ", "))
+		}
+		s.named[strings.ToLower(item)] = true
+	}
+	for _, item := range exclude {
+		if !known[strings.ToLower(item)] {
+			return nil, fmt.Errorf("unknown category %q, use one of: %s", item, strings.Join(Categories(), ", "))
+		}
+		s.skip[strings.ToLower(item)] = true
+	}
+	// CMS versions come from CMS detections
+	if s.named["cms"] {
+		s.named["cmsversion"] = true
+	}
+	if !all {
+		s.categories = s.named
+	}
+
+	s.names, s.skipNames = split(names)
+	for _, item := range append(append([]string(nil), s.names...), s.skipNames...) {
+		if !knownName(item) {
+			return nil, fmt.Errorf("unknown pattern or tag %q, see spectre rules list -v", item)
+		}
+	}
+
+	levels, skipLevels := split(risks)
+	if len(levels) == 0 && len(skipLevels) > 0 {
+		levels = Risks
+	}
+	for _, item := range append(append([]string(nil), levels...), skipLevels...) {
+		if !contains(Risks, strings.ToLower(item)) {
+			return nil, fmt.Errorf("unknown risk level %q, use low, medium, high or critical", item)
+		}
+	}
+	if len(levels) > 0 {
+		s.risks = make(map[string]bool)
+		for _, level := range levels {
+			s.risks[strings.ToLower(level)] = true
+		}
+		for _, level := range skipLevels {
+			delete(s.risks, strings.ToLower(level))
+		}
+	}
+	return s, nil
+}
+
+// split separates the items of a comma-separated list from those prefixed
+// with -
+func split(list string) (include, exclude []string) {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case strings.HasPrefix(item, "-"):
+			exclude = append(exclude, strings.TrimSpace(item[1:]))
+		default:
+			include = append(include, item)
+		}
+	}
+	return include, exclude
+}
+
+// Categories returns every category a selection can name
+func Categories() []string {
+	var categories []string
+	seen := make(map[string]bool)
+	for _, pt := range patterns.AllPatternTypes {
+		if !seen[pt.Category] {
+			seen[pt.Category] = true
+			categories = append(categories, pt.Category)
+		}
+	}
+	for _, category := range Analysis {
+		if !seen[category] {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+func knownCategories() map[string]bool {
+	known := make(map[string]bool)
+	for _, category := range Categories() {
+		known[strings.ToLower(category)] = true
+	}
+	return known
+}
+
+func knownName(item string) bool {
+	for _, pt := range append(append([]patterns.PatternType(nil), patterns.AllPatternTypes...), AnalysisTypes...) {
+		if matchesName(pt, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// analysisType returns the analysis pattern type of a finding
+func analysisType(category, name string) (patterns.PatternType, bool) {
+	for _, pt := range AnalysisTypes {
+		if pt.Category == category && pt.Name == name {
+			return pt, true
+		}
+	}
+	return patterns.PatternType{}, false
+}
+
+// matchesName reports whether item is pt's name, Category/Name or one of
+// its tags
+func matchesName(pt patterns.PatternType, item string) bool {
+	return strings.EqualFold(item, pt.Name) || strings.EqualFold(item, pt.Category+"/"+pt.Name) || pt.HasTag(item)
+}
+
+// Category reports whether findings of a category are selected. When
+// pattern names or tags are selected, only their patterns, the categories
+// named explicitly and the analysis categories with a selected type are.
+func (s *Selection) Category(category string) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.names) > 0 {
+		return s.named[strings.ToLower(category)] && !s.skip[strings.ToLower(category)] || s.analysisNamed(category)
+	}
+	return s.categorySelected(strings.ToLower(category))
+}
+
+// analysisNamed reports whether a selected name matches an analysis type
+// of a selected category
+func (s *Selection) analysisNamed(category string) bool {
+	if !s.categorySelected(strings.ToLower(category)) {
+		return false
+	}
+	for _, pt := range AnalysisTypes {
+		if pt.Category != category {
+			continue
+		}
+		for _, item := range s.names {
+			if matchesName(pt, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Selection) categorySelected(category string) bool {
+	return (s.categories == nil || s.categories[category]) && !s.skip[category]
+}
+
+// Pattern reports whether a pattern is selected by its category, name and
+// tags
+func (s *Selection) Pattern(pt patterns.PatternType) bool {
+	if s == nil {
+		return true
+	}
+	if !s.categorySelected(strings.ToLower(pt.Category)) {
+		return false
+	}
+	for _, item := range s.skipNames {
+		if matchesName(pt, item) {
+			return false
+		}
+	}
+	if len(s.names) == 0 {
+		return true
+	}
+	for _, item := range s.names {
+		if matchesName(pt, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finding reports whether a finding of scanner analysis is selected by its
+// pattern type, once its category is. Analysis categories named
+// explicitly are reported whole, and findings of patterns, which Pattern
+// selects, are always kept.
+func (s *Selection) Finding(category, patternType string) bool {
+	pt, ok := analysisType(category, patternType)
+	if s == nil || !ok {
+		return true
+	}
+	for _, item := range s.skipNames {
+		if matchesName(pt, item) {
+			return false
+		}
+	}
+	if len(s.names) == 0 || s.named[strings.ToLower(category)] {
+		return true
+	}
+	for _, item := range s.names {
+		if matchesName(pt, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Risk reports whether findings at a risk level are selected. Findings of
+// unknown risk are kept.
+func (s *Selection) Risk(level string) bool {
+	if s == nil || s.risks == nil || !contains(Risks, strings.ToLower(level)) {
+		return true
+	}
+	return s.risks[strings.ToLower(level)]
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package selection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+func TestParse(t *testing.T) {
+	hotjar := patterns.PatternType{Category: "Tracking", Name: "Hotjar", Tags: []string{"session-replay"}}
+	mixpanel := patterns.PatternType{Category: "Tracking", Name: "Mixpanel"}
+	ga := patterns.PatternType{Category: "TrackingPixel", Name: "Google Analytics", Tags: []string{"google"}}
+	wordpress := patterns.PatternType{Category: "CMS", Name: "WordPress"}
+
+	tests := []struct {
+		name       string
+		categories string
+		names      string
+		risks      string
+		patterns   map[string]bool // pattern name -> selected
+		selected   map[string]bool
+		findings   map[string]bool // category/pattern type -> selected
+		risky      map[string]bool
+	}{
+		{
+			name:       "all",
+			categories: "all",
+			patterns:   map[string]bool{"Hotjar": true, "WordPress": true},
+			selected:   map[string]bool{"Cookies": true, "Score": true},
+		},
+		{
+			name:       "list",
+			categories: "Tracking, cms",
+			patterns:   map[string]bool{"Hotjar": true, "WordPress": true, "Google Analytics": false},
+			selected:   map[string]bool{"CMSVersion": true, "Cookies": false},
+		},
+		{
+			name:       "exclusions",
+			categories: "all,-CMS,-Score",
+			patterns:   map[string]bool{"Hotjar": true, "WordPress": false},
+			selected:   map[string]bool{"Cookies": true, "Score": false},
+		},
+		{
+			name:       "exclusions alone",
+			categories: "-Tracking",
+			patterns:   map[string]bool{"Hotjar": false, "Google Analytics": true},
+			selected:   map[string]bool{"Cookies": true},
+		},
+		{
+			name:       "names and tags",
+			categories: "all,Cookies",
+			names:      "session-replay,trackingpixel/google analytics",
+			patterns:   map[string]bool{"Hotjar": true, "Mixpanel": false, "Google Analytics": true, "WordPress": false},
+			selected:   map[string]bool{"Cookies": true, "ThirdParty": false},
+		},
+		{
+			name:       "name exclusions",
+			categories: "Tracking",
+			names:      "-Hotjar",
+			patterns:   map[string]bool{"Hotjar": false, "Mixpanel": true, "Google Analytics": false},
+		},
+		{
+			name:     "analysis names",
+			names:    "Hidden Iframe,redirects/https downgrade",
+			selected: map[string]bool{"HiddenIframe": true, "Redirects": true, "Cookies": false},
+			findings: map[string]bool{
+				"HiddenIframe/Hidden Iframe":      true,
+				"HiddenIframe/Zero Size Iframe":   false,
+				"Redirects/HTTPS Downgrade":       true,
+				"Redirects/Cross-Domain Redirect": false,
+				"Tracking/Hotjar":                 true,
+			},
+		},
+		{
+			name:     "analysis name exclusions",
+			names:    "-Unclassified Cookie,-Header Score",
+			selected: map[string]bool{"Cookies": true, "SecurityHeaders": true},
+			findings: map[string]bool{
+				"Cookies/Unclassified Cookie":  false,
+				"Cookies/Google Analytics":     true,
+				"SecurityHeaders/Header Score": false,
+				"SecurityHeaders/Missing HSTS": true,
+			},
+		},
+		{
+			name:       "named analysis category",
+			categories: "Cookies",
+			names:      "Missing HSTS",
+			selected:   map[string]bool{"Cookies": true, "SecurityHeaders": false},
+			findings:   map[string]bool{"Cookies/Unclassified Cookie": true},
+		},
+		{
+			name:  "risks",
+			risks: "High,medium",
+			risky: map[string]bool{"High": true, "Medium": true, "Low": false, "Unknown": true},
+		},
+		{
+			name:  "critical",
+			risks: "critical",
+			risky: map[string]bool{"Critical": true, "high": false, "low": false},
+		},
+		{
+			name:  "low leaves critical out",
+			risks: "low",
+			risky: map[string]bool{"low": true, "critical": false},
+		},
+		{
+			name:  "risk exclusions",
+			risks: "-low",
+			risky: map[string]bool{"high": true, "medium": true, "low": false},
+		},
+	}
+	all := map[string]patterns.PatternType{"Hotjar": hotjar, "Mixpanel": mixpanel, "Google Analytics": ga, "WordPress": wordpress}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.categories, tt.names, tt.risks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.patterns {
+				if got := s.Pattern(all[name]); got != want {
+					t.Errorf("Pattern(%s) = %v, want %v", name, got, want)
+				}
+			}
+			for category, want := range tt.selected {
+				if got := s.Category(category); got != want {
+					t.Errorf("Category(%s) = %v, want %v", category, got, want)
+				}
+			}
+			for finding, want := range tt.findings {
+				parts := strings.SplitN(finding, "/", 2)
+				if got := s.Finding(parts[0], parts[1]); got != want {
+					t.Errorf("Finding(%s) = %v, want %v", finding, got, want)
+				}
+			}
+			for level, want := range tt.risky {
+				if got := s.Risk(level); got != want {
+					t.Errorf("Risk(%s) = %v, want %v", level, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		categories, names, risks string
+	}{
+		{"Trackng", "", ""},
+		{"all,-Bogus", "", ""},
+		{"all", "Hotjr", ""},
+		{"all", "-no-such-tag", ""},
+		{"all", "", "severe"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.categories, tt.names, tt.risks); err == nil {
+			t.Errorf("Parse(%q, %q, %q): got no error", tt.categories, tt.names, tt.risks)
+		}
+	}
+
+	var s *Selection
+	if !s.Category("CMS") || !s.Pattern(patterns.PatternType{Category: "CMS"}) || !s.Risk("low") {
+		t.Error("nil selection should select everything")
+	}
+}
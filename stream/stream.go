@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Window defaults. The overlap must exceed the longest match so matches
+// spanning a chunk boundary are found whole in one window.
+const (
+	DefaultChunkSize = 1 << 20
+	DefaultOverlap   = 4 << 10
+)
+
+// Window is one part of a document. Windows overlap: only matches that
+// start before Limit belong to the window, and the rest of Content starts
+// the next window.
+type Window struct {
+	Content   string
+	FirstLine int  // document line that Content starts on
+	Limit     int  // len(Content) for the last window
+	Whole     bool // the window holds the entire document
+}
+
+// Scan reads r in windows of at most chunkSize plus overlap bytes and calls
+// fn for each, so memory stays bounded however large the document is.
+// Zero sizes use the defaults. It returns the number of bytes read.
+func Scan(r io.Reader, chunkSize, overlap int, fn func(Window)) (int64, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if overlap <= 0 {
+		overlap = DefaultOverlap
+	}
+	if overlap > chunkSize/2 {
+		overlap = chunkSize / 2
+	}
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, overlap+chunkSize)
+	var total int64
+	carry, firstLine, first := 0, 1, true
+	for {
+		n, err := io.ReadFull(br, buf[carry:carry+chunkSize])
+		total += int64(n)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return total, err
+		}
+		// A full chunk is the last when nothing follows it, so a document
+		// of exactly chunkSize bytes is still whole
+		if err == nil {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+
+		content := string(buf[:carry+n])
+		if last {
+			if content != "" {
+				fn(Window{Content: content, FirstLine: firstLine, Limit: len(content), Whole: first})
+			}
+			return total, nil
+		}
+
+		cut := tailStart(content, overlap)
+		fn(Window{Content: content, FirstLine: firstLine, Limit: cut})
+		firstLine += strings.Count(content[:cut], "\n")
+		carry = copy(buf, buf[cut:len(content)])
+		first = false
+	}
+}
+
+// tailStart returns where the overlap carried into the next window begins:
+// after the first line break in the last overlap bytes, or failing that
+// after whitespace, so the next window does not start mid-token
+func tailStart(content string, overlap int) int {
+	start := len(content) - overlap
+	if i := strings.IndexByte(content[start:], '\n'); i >= 0 {
+		return start + i + 1
+	}
+	if i := strings.IndexAny(content[start:], " \t\r"); i >= 0 {
+		return start + i + 1
+	}
+	return start
+}
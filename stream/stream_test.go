@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	var doc strings.Builder
+	for i := 0; i < 2000; i++ {
+		doc.WriteString("line of text with some words\n")
+	}
+	content := doc.String()
+
+	tests := []struct {
+		name      string
+		content   string
+		chunkSize int
+		overlap   int
+		windows   int
+	}{
+		{"fits in one chunk", "short\ndocument", 1024, 64, 1},
+		{"empty", "", 1024, 64, 0},
+		{"exactly one chunk", content[:4096], 4096, 256, 1},
+		{"multiple chunks", content, 4096, 256, 15},
+		{"overlap clamped", content, 4096, 1 << 20, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var windows []Window
+			total, err := Scan(strings.NewReader(tt.content), tt.chunkSize, tt.overlap, func(w Window) {
+				windows = append(windows, w)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != int64(len(tt.content)) {
+				t.Errorf("read %d bytes, want %d", total, len(tt.content))
+			}
+			if len(windows) != tt.windows {
+				t.Fatalf("got %d windows, want %d", len(windows), tt.windows)
+			}
+
+			// The owned parts of the windows rebuild the document, and each
+			// window starts on the line its owned part starts on
+			var owned strings.Builder
+			for i, w := range windows {
+				if w.Whole != (len(windows) == 1) {
+					t.Errorf("window %d: Whole = %v", i, w.Whole)
+				}
+				if want := strings.Count(owned.String(), "\n") + 1; w.FirstLine != want {
+					t.Errorf("window %d: FirstLine = %d, want %d", i, w.FirstLine, want)
+				}
+				if len(w.Content) > tt.chunkSize+tt.overlap {
+					t.Errorf("window %d: %d bytes exceeds chunk and overlap", i, len(w.Content))
+				}
+				if i < len(windows)-1 && !strings.HasPrefix(windows[i+1].Content, w.Content[w.Limit:]) {
+					t.Errorf("window %d: tail not carried into next window", i)
+				}
+				owned.WriteString(w.Content[:w.Limit])
+			}
+			if owned.String() != tt.content {
+				t.Errorf("owned window parts do not rebuild the document")
+			}
+		})
+	}
+}
+
+func TestTailStart(t *testing.T) {
+	tests := []struct {
+		content string
+		overlap int
+		want    int
+	}{
+		{"aaaa\nbbbb\ncccc", 8, 10},
+		{"aaaa bbbb cccc", 8, 10},
+		{"aaaabbbbcccc", 8, 4},
+	}
+	for _, tt := range tests {
+		if got := tailStart(tt.content, tt.overlap); got != tt.want {
+			t.Errorf("tailStart(%q, %d) = %d, want %d", tt.content, tt.overlap, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package suppress
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule suppresses findings that match every field it sets
+type Rule struct {
+	// Host is a glob matched against the host of the scanned URL, or the
+	// path of a local file, e.g. "*.example.com"
+	Host     string `yaml:"host"`
+	Category string `yaml:"category"`
+	// Pattern is the pattern type, e.g. "Sentry" or "Google Analytics ID"
+	Pattern string `yaml:"pattern"`
+	// Identifier is a tracker ID or other token that must occur in the value
+	Identifier string `yaml:"identifier"`
+	// Value is a regular expression matched against the value
+	Value string `yaml:"value"`
+	// Expires is the last day, as YYYY-MM-DD, the rule applies
+	Expires       string `yaml:"expires"`
+	Justification string `yaml:"justification"`
+
+	value   *regexp.Regexp
+	expires time.Time // first moment the rule no longer applies
+}
+
+// List is a parsed suppression file
+type List struct {
+	Rules []*Rule `yaml:"suppressions"`
+}
+
+// Load reads a suppression file
+func Load(filename string) (*List, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads suppressions in YAML form. Every rule needs a justification
+// and at least one field to match on.
+func Parse(data []byte) (*List, error) {
+	l := &List{}
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, err
+	}
+	for i, r := range l.Rules {
+		if r == nil {
+			return nil, fmt.Errorf("suppression %d: empty entry", i+1)
+		}
+		if r.Host == "" && r.Category == "" && r.Pattern == "" && r.Identifier == "" && r.Value == "" {
+			return nil, fmt.Errorf("suppression %d: needs a host, category, pattern, identifier or value", i+1)
+		}
+		if strings.TrimSpace(r.Justification) == "" {
+			return nil, fmt.Errorf("suppression %d: missing justification", i+1)
+		}
+		if _, err := path.Match(strings.ToLower(r.Host), ""); err != nil {
+			return nil, fmt.Errorf("suppression %d: host %q: %v", i+1, r.Host, err)
+		}
+		if r.Value != "" {
+			re, err := regexp.Compile(r.Value)
+			if err != nil {
+				return nil, fmt.Errorf("suppression %d: value: %v", i+1, err)
+			}
+			r.value = re
+		}
+		if r.Expires != "" {
+			day, err := time.Parse("2006-01-02", r.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("suppression %d: expires %q is not a YYYY-MM-DD date", i+1, r.Expires)
+			}
+			r.expires = day.AddDate(0, 0, 1)
+		}
+	}
+	return l, nil
+}
+
+// Expired reports whether the rule no longer applies at now
+func (r *Rule) Expired(now time.Time) bool {
+	return !r.expires.IsZero() && !now.Before(r.expires)
+}
+
+// Expired returns the rules that no longer apply at now
+func (l *List) Expired(now time.Time) []*Rule {
+	var expired []*Rule
+	for _, r := range l.Rules {
+		if r.Expired(now) {
+			expired = append(expired, r)
+		}
+	}
+	return expired
+}
+
+// Match returns the first unexpired rule matching a finding for urlStr, or
+// nil. Categories and pattern types are compared case-insensitively.
+func (l *List) Match(urlStr, category, patternType, value string, now time.Time) *Rule {
+	if l == nil {
+		return nil
+	}
+	host := strings.ToLower(Host(urlStr))
+	for _, r := range l.Rules {
+		if r.Expired(now) {
+			continue
+		}
+		if r.Host != "" {
+			if ok, _ := path.Match(strings.ToLower(r.Host), host); !ok {
+				continue
+			}
+		}
+		if r.Category != "" && !strings.EqualFold(r.Category, category) {
+			continue
+		}
+		if r.Pattern != "" && !strings.EqualFold(r.Pattern, patternType) {
+			continue
+		}
+		if r.Identifier != "" && !containsToken(value, r.Identifier) {
+			continue
+		}
+		if r.value != nil && !r.value.MatchString(value) {
+			continue
+		}
+		return r
+	}
+	return nil
+}
+
+// Host returns the lowercased host of a URL, or a local path unchanged
+func Host(urlStr string) string {
+	if !strings.Contains(urlStr, "://") {
+		return urlStr
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// containsToken reports whether token occurs in s without a letter, digit
+// or '-' on either side, so UA-1234-1 does not match UA-1234-12
+func containsToken(s, token string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], token)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(token)
+		if (start == 0 || !tokenByte(s[start-1])) && (end == len(s) || !tokenByte(s[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func tokenByte(c byte) bool {
+	return c == '-' || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,99 @@
+package suppress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const file = `suppressions:
+  - host: "*.example.com"
+    pattern: sentry
+    justification: Our own error tracking
+  - category: ConsentManagement
+    pattern: OneTrust
+    justification: Our CMP
+    expires: 2026-03-31
+  - identifier: UA-1234-1
+    justification: Our analytics property
+  - host: shop.example.org
+    value: '^https://cdn\.example\.org/'
+    justification: Own CDN
+  - host: "dist/*.js"
+    category: Secrets
+    justification: Test fixtures
+`
+
+func TestMatch(t *testing.T) {
+	list, err := Parse([]byte(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		url      string
+		category string
+		pattern  string
+		value    string
+		want     string
+	}{
+		{"host glob and pattern", "https://www.example.com/", "ErrorTracking", "Sentry", "browser.sentry-cdn.com", "Our own error tracking"},
+		{"host glob needs a subdomain", "https://example.com/", "ErrorTracking", "Sentry", "browser.sentry-cdn.com", ""},
+		{"other pattern", "https://www.example.com/", "ErrorTracking", "Bugsnag", "bugsnag", ""},
+		{"last day of expiry", "https://any.site/", "ConsentManagement", "OneTrust", "otSDKStub.js", "Our CMP"},
+		{"identifier token", "https://any.site/", "TrackerID", "Google Analytics ID", "ga('create', 'UA-1234-1')", "Our analytics property"},
+		{"identifier prefix", "https://any.site/", "TrackerID", "Google Analytics ID", "UA-1234-12", ""},
+		{"value regex", "https://shop.example.org/cart", "ThirdParty", "Unclassified Third-Party Domain", "https://cdn.example.org/app.js", "Own CDN"},
+		{"value regex other host", "https://shop.example.com/", "ThirdParty", "Unclassified Third-Party Domain", "https://cdn.example.org/app.js", ""},
+		{"local path", "dist/app.js", "Secrets", "AWS Access Key ID", "AKIA...", "Test fixtures"},
+		{"local path in subdirectory", "dist/vendor/app.js", "Secrets", "AWS Access Key ID", "AKIA...", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if r := list.Match(tt.url, tt.category, tt.pattern, tt.value, now); r != nil {
+				got = r.Justification
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	later := now.Add(2 * time.Hour)
+	if r := list.Match("https://any.site/", "ConsentManagement", "OneTrust", "otSDKStub.js", later); r != nil {
+		t.Errorf("expired rule matched: %q", r.Justification)
+	}
+	if expired := list.Expired(later); len(expired) != 1 || expired[0].Justification != "Our CMP" {
+		t.Errorf("Expired = %v", expired)
+	}
+	var none *List
+	if none.Match("https://any.site/", "Tracking", "Hotjar", "", now) != nil {
+		t.Error("nil list matched")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"no justification", "suppressions:\n  - pattern: Sentry\n", "missing justification"},
+		{"no matcher", "suppressions:\n  - justification: everything\n", "needs a host"},
+		{"bad regex", "suppressions:\n  - value: '('\n    justification: x\n", "value"},
+		{"bad date", "suppressions:\n  - pattern: Sentry\n    expires: next year\n    justification: x\n", "YYYY-MM-DD"},
+		{"bad glob", "suppressions:\n  - host: '[a'\n    justification: x\n", "host"},
+		{"not yaml", "suppressions: [", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package thirdparty
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+//go:embed trackers.json
+var defaultList []byte
+
+// Entry is the company and category behind a tracker domain
+type Entry struct {
+	Company  string
+	Category string
+}
+
+// List maps tracker domains to the companies operating them
+type List struct {
+	domains map[string]Entry
+}
+
+// Default returns the tracker list bundled with Spectre
+func Default() *List {
+	l, err := Parse(defaultList)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Load reads a tracker list in Disconnect services.json or EasyPrivacy
+// filter list format
+func Load(path string) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads a tracker list, taking data starting with { as Disconnect
+// services.json and anything else as an EasyPrivacy filter list
+func Parse(data []byte) (*List, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseDisconnect(trimmed)
+	}
+	return parseFilterList(data), nil
+}
+
+// parseDisconnect reads {"categories": {category: [{company: {url: [domains]}}]}}.
+// Company objects may carry non-list keys such as "performance", which are
+// ignored.
+func parseDisconnect(data []byte) (*List, error) {
+	var services struct {
+		Categories map[string][]map[string]map[string]json.RawMessage `json:"categories"`
+	}
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, fmt.Errorf("parsing Disconnect list: %v", err)
+	}
+
+	l := &List{domains: make(map[string]Entry)}
+	for category, companies := range services.Categories {
+		for _, company := range companies {
+			for name, properties := range company {
+				for _, value := range properties {
+					var domains []string
+					if json.Unmarshal(value, &domains) != nil {
+						continue
+					}
+					for _, d := range domains {
+						l.add(d, Entry{Company: name, Category: category})
+					}
+				}
+			}
+		}
+	}
+	return l, nil
+}
+
+// parseFilterList reads the domain-anchored blocking rules of an Adblock
+// Plus filter list, such as ||tracker.example^. Rules with paths, options
+// other than third-party, or exceptions do not block a whole domain and
+// are skipped.
+func parseFilterList(data []byte) *List {
+	l := &List{domains: make(map[string]Entry)}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		rule := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(rule, "||") {
+			continue
+		}
+		rule = strings.TrimPrefix(rule, "||")
+		if i := strings.IndexByte(rule, '$'); i >= 0 {
+			if rule[i+1:] != "third-party" {
+				continue
+			}
+			rule = rule[:i]
+		}
+		rule = strings.TrimSuffix(rule, "^")
+		if rule == "" || strings.ContainsAny(rule, "/*^|") {
+			continue
+		}
+		l.add(rule, Entry{Company: rule, Category: "EasyPrivacy"})
+	}
+	return l
+}
+
+// trackerCategories are the pattern categories whose domains are trackers
+var trackerCategories = map[string]bool{
+	"TrackingPixel":    true,
+	"AdNetwork":        true,
+	"Tracking":         true,
+	"SessionRecording": true,
+	"ABTesting":        true,
+}
+
+// AddPatterns adds the domains of tracking patterns that the list does not
+// already cover, named after the pattern
+func (l *List) AddPatterns(pts []patterns.PatternType) {
+	for _, pt := range pts {
+		if !trackerCategories[pt.Category] {
+			continue
+		}
+		for _, domain := range patterns.Domains(pt) {
+			if _, ok := l.Lookup(domain); !ok {
+				l.add(domain, Entry{Company: pt.Name, Category: pt.Category})
+			}
+		}
+	}
+}
+
+func (l *List) add(domain string, e Entry) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" {
+		return
+	}
+	if _, ok := l.domains[domain]; !ok {
+		l.domains[domain] = e
+	}
+}
+
+// Lookup finds the tracker entry for a host or any of its parent domains
+func (l *List) Lookup(host string) (Entry, bool) {
+	if l == nil {
+		return Entry{}, false
+	}
+	host = strings.ToLower(host)
+	for {
+		if e, ok := l.domains[host]; ok {
+			return e, true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return Entry{}, false
+		}
+		host = host[i+1:]
+	}
+}
+
+// Len returns the number of domains in the list
+func (l *List) Len() int {
+	return len(l.domains)
+}
@@ -0,0 +1,223 @@
+package thirdparty
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gregcmartin/spectre/cookies"
+	"golang.org/x/net/html"
+)
+
+// Pattern types of ThirdParty findings
+const (
+	KnownTracker = "Known Tracker Domain"
+	Unclassified = "Unclassified Third-Party Domain"
+)
+
+// PatternTypes lists the pattern types of ThirdParty findings
+var PatternTypes = []string{KnownTracker, Unclassified}
+
+// Resource is a resource referenced by a page
+type Resource struct {
+	Line       int
+	Offset     int    // byte offset of the referencing tag
+	Kind       string // script, img, iframe, link rel, form, media, object or embed
+	URL        string // absolute URL
+	Host       string
+	Domain     string // registrable domain of Host
+	ThirdParty bool   // Domain is not the page's site
+}
+
+// resourceAttrs are the attributes that load or send to another URL
+var resourceAttrs = map[string][]string{
+	"script": {"src"},
+	"img":    {"src", "srcset"},
+	"iframe": {"src"},
+	"frame":  {"src"},
+	"link":   {"href"},
+	"form":   {"action"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"source": {"src", "srcset"},
+	"track":  {"src"},
+	"embed":  {"src"},
+	"object": {"data"},
+	"input":  {"src"},
+}
+
+// linkRels are the link relations that make the browser contact a host
+var linkRels = map[string]bool{
+	"stylesheet": true, "preload": true, "modulepreload": true, "prefetch": true,
+	"preconnect": true, "dns-prefetch": true, "prerender": true, "icon": true,
+	"manifest": true, "apple-touch-icon": true,
+}
+
+// Extract finds the resources a page loads or submits to, with relative
+// URLs resolved against pageURL. On a page without a host, such as a local
+// file, relative URLs are skipped and every absolute URL is third-party.
+func Extract(content, pageURL string) []Resource {
+	base, err := url.Parse(pageURL)
+	if err != nil || base.Hostname() == "" {
+		base = nil
+	}
+	pageSite := ""
+	if base != nil {
+		pageSite = cookies.RegistrableDomain(base.Hostname())
+	}
+
+	var found []Resource
+	z := html.NewTokenizer(strings.NewReader(content))
+	line, offset := 1, 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		startLine, startOffset := line, offset
+		line += bytes.Count(raw, []byte("\n"))
+		offset += len(raw)
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		name, hasAttr := z.TagName()
+		tag := string(name)
+		wanted, ok := resourceAttrs[tag]
+		if !ok {
+			continue
+		}
+		attrs := make(map[string]string)
+		for hasAttr {
+			var key, val []byte
+			key, val, hasAttr = z.TagAttr()
+			attrs[string(key)] = string(val)
+		}
+
+		kind := tag
+		switch tag {
+		case "link":
+			rel := linkRel(attrs["rel"])
+			if rel == "" {
+				continue
+			}
+			kind = "link " + rel
+		case "video", "audio", "source", "track":
+			kind = "media"
+		case "frame":
+			kind = "iframe"
+		case "input":
+			if !strings.EqualFold(attrs["type"], "image") {
+				continue
+			}
+			kind = "img"
+		}
+
+		for _, attr := range wanted {
+			value, ok := attrs[attr]
+			if !ok {
+				continue
+			}
+			refs := []string{value}
+			if attr == "srcset" {
+				refs = srcset(value)
+			}
+			for _, ref := range refs {
+				r, ok := resource(ref, base, pageSite)
+				if !ok {
+					continue
+				}
+				r.Line, r.Offset = startLine, startOffset
+				r.Kind = kind
+				found = append(found, r)
+			}
+		}
+	}
+	return found
+}
+
+// linkRel returns the first relation of a link that loads a resource
+func linkRel(rel string) string {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		if linkRels[r] {
+			return r
+		}
+	}
+	return ""
+}
+
+// srcset returns the URLs of a srcset attribute
+func srcset(value string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(value, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+func resource(ref string, base *url.URL, pageSite string) (Resource, bool) {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "//") && base == nil {
+		ref = "https:" + ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return Resource{}, false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return Resource{}, false
+	}
+	host := strings.ToLower(u.Hostname())
+	domain := cookies.RegistrableDomain(host)
+	return Resource{URL: u.String(), Host: host, Domain: domain, ThirdParty: domain != pageSite}, true
+}
+
+// Group collects third-party resources by registrable domain, in order of
+// first use
+func Group(resources []Resource) []Domain {
+	var domains []Domain
+	index := make(map[string]int)
+	for _, r := range resources {
+		if !r.ThirdParty {
+			continue
+		}
+		i, ok := index[r.Domain]
+		if !ok {
+			i = len(domains)
+			index[r.Domain] = i
+			domains = append(domains, Domain{Domain: r.Domain, Line: r.Line})
+		}
+		d := &domains[i]
+		d.Hosts = addSorted(d.Hosts, r.Host)
+		d.Kinds = addSorted(d.Kinds, r.Kind)
+		d.Requests++
+	}
+	return domains
+}
+
+// Domain is a third-party site and how a page uses it
+type Domain struct {
+	Domain   string
+	Line     int // first reference
+	Hosts    []string
+	Kinds    []string
+	Requests int
+}
+
+func addSorted(list []string, value string) []string {
+	i := sort.SearchStrings(list, value)
+	if i < len(list) && list[i] == value {
+		return list
+	}
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = value
+	return list
+}
@@ -0,0 +1,139 @@
+package thirdparty
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gregcmartin/spectre/patterns"
+)
+
+const page = `<html><head>
+<link rel="stylesheet" href="/css/site.css">
+<link rel="preconnect" href="https://fonts.gstatic.com">
+<link rel="canonical" href="https://other.example.org/page">
+<script src="https://www.googletagmanager.com/gtag/js?id=G-1"></script>
+<script src="//cdn.example.com/app.js"></script>
+</head><body>
+<img src="https://px.ads.linkedin.com/collect?pid=1" width="1" height="1">
+<img srcset="https://images.cdnhost.net/a.jpg 1x, https://images.cdnhost.net/a@2x.jpg 2x">
+<iframe src="https://www.youtube.com/embed/abc"></iframe>
+<form action="https://forms.hubspot.com/submit"></form>
+<input type="image" src="https://buttons.cdnhost.net/go.png">
+<a href="https://twitter.com/example">not loaded</a>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	resources := Extract(page, "https://www.example.com/")
+	var got []string
+	for _, r := range resources {
+		party := "first"
+		if r.ThirdParty {
+			party = "third"
+		}
+		got = append(got, r.Kind+" "+r.Domain+" "+party)
+	}
+	want := []string{
+		"link stylesheet example.com first",
+		"link preconnect gstatic.com third",
+		"script googletagmanager.com third",
+		"script example.com first",
+		"img linkedin.com third",
+		"img cdnhost.net third",
+		"img cdnhost.net third",
+		"iframe youtube.com third",
+		"form hubspot.com third",
+		"img cdnhost.net third",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+	if resources[0].URL != "https://www.example.com/css/site.css" {
+		t.Errorf("relative URL resolved to %s", resources[0].URL)
+	}
+	if resources[2].Line != 5 || !strings.HasPrefix(page[resources[2].Offset:], `<script src="https://www.googletagmanager.com`) {
+		t.Errorf("script at line %d, offset %d; want line 5 at its tag", resources[2].Line, resources[2].Offset)
+	}
+
+	// Without a page host, relative URLs are skipped and absolute ones are
+	// all third-party
+	local := Extract(page, "dist/index.html")
+	if len(local) != len(want)-1 {
+		t.Errorf("got %d resources for a local page, want %d", len(local), len(want)-1)
+	}
+	for _, r := range local {
+		if !r.ThirdParty {
+			t.Errorf("local page resource %s is not third-party", r.URL)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	domains := Group(Extract(page, "https://www.example.com/"))
+	if len(domains) != 6 {
+		t.Fatalf("got %d domains, want 6", len(domains))
+	}
+	cdn := domains[3]
+	if cdn.Domain != "cdnhost.net" || cdn.Requests != 3 || cdn.Line != 9 {
+		t.Errorf("unexpected cdnhost.net group %+v", cdn)
+	}
+	if strings.Join(cdn.Hosts, ",") != "buttons.cdnhost.net,images.cdnhost.net" || strings.Join(cdn.Kinds, ",") != "img" {
+		t.Errorf("unexpected cdnhost.net hosts %v or kinds %v", cdn.Hosts, cdn.Kinds)
+	}
+}
+
+func TestParse(t *testing.T) {
+	disconnect := `{"categories": {"Advertising": [
+		{"Example Ads": {"https://ads.example/": ["adserver.test", "pixel.test"], "performance": "true"}}
+	]}}`
+	easyPrivacy := `[Adblock Plus 2.0]
+! Title: EasyPrivacy
+||metrics.test^
+||beacon.test^$third-party
+||scripts.test/track.js
+||images.test^$image
+@@||allowed.test^
+`
+	tests := []struct {
+		name    string
+		list    string
+		host    string
+		found   bool
+		company string
+	}{
+		{"disconnect domain", disconnect, "adserver.test", true, "Example Ads"},
+		{"disconnect subdomain", disconnect, "eu.pixel.test", true, "Example Ads"},
+		{"disconnect unlisted", disconnect, "example.test", false, ""},
+		{"filter rule", easyPrivacy, "cdn.metrics.test", true, "metrics.test"},
+		{"third-party option", easyPrivacy, "beacon.test", true, "beacon.test"},
+		{"path rule", easyPrivacy, "scripts.test", false, ""},
+		{"other option", easyPrivacy, "images.test", false, ""},
+		{"exception", easyPrivacy, "allowed.test", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Parse([]byte(tt.list))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, ok := l.Lookup(tt.host)
+			if ok != tt.found || e.Company != tt.company {
+				t.Errorf("Lookup(%q) = %+v, %v; want %q, %v", tt.host, e, ok, tt.company, tt.found)
+			}
+		})
+	}
+}
+
+func TestDefaultList(t *testing.T) {
+	l := Default()
+	if e, ok := l.Lookup("stats.g.doubleclick.net"); !ok || e.Company != "Google" || e.Category != "Advertising" {
+		t.Errorf("doubleclick.net: got %+v, %v", e, ok)
+	}
+
+	l.AddPatterns(patterns.AllPatternTypes)
+	if _, ok := l.Lookup("static.hotjar.com"); !ok {
+		t.Error("hotjar.com missing after adding patterns")
+	}
+	if _, ok := l.Lookup("cdn.shopify.com"); ok {
+		t.Error("CMS domains should not be listed as trackers")
+	}
+}
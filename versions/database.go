@@ -0,0 +1,109 @@
+package versions
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+//go:embed vulnerabilities.json
+var defaultDatabase []byte
+
+// Database is the local dataset of supported CMS versions and known
+// vulnerable version ranges
+type Database struct {
+	Updated string             `json:"updated"`
+	CMS     map[string]Product `json:"cms"`
+}
+
+// Product lists a CMS's oldest supported release and its vulnerabilities
+type Product struct {
+	Supported       string          `json:"supported"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Vulnerability affects versions from Introduced up to, but not including, Fixed
+type Vulnerability struct {
+	ID         string `json:"id"`
+	Introduced string `json:"introduced"`
+	Fixed      string `json:"fixed"`
+	Severity   string `json:"severity"`
+	Summary    string `json:"summary"`
+}
+
+// Assessment statuses
+const (
+	StatusCurrent    = "current"
+	StatusOutdated   = "outdated"
+	StatusVulnerable = "vulnerable"
+	StatusUnknown    = "unknown"
+)
+
+// Assessment is a detected version checked against the database
+type Assessment struct {
+	Status          string
+	Supported       string
+	Vulnerabilities []Vulnerability
+	RiskLevel       string
+}
+
+var severityRank = map[string]int{"Low": 1, "Medium": 2, "High": 3, "Critical": 4}
+
+// Default returns the database bundled with Spectre
+func Default() *Database {
+	db, err := Parse(defaultDatabase)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Load reads a database file, such as an updated copy of the bundled one
+func Load(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes a database
+func Parse(data []byte) (*Database, error) {
+	var db Database
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("invalid vulnerability database: %v", err)
+	}
+	return &db, nil
+}
+
+// Assess checks a detected version against the database. Vulnerable
+// installs take the highest severity of their vulnerabilities as risk
+// level, installs older than the oldest supported release are Medium, and
+// others Low. Partial versions are only compared as far as they go.
+func (db *Database) Assess(d Detection) Assessment {
+	product, ok := db.CMS[d.CMS]
+	if !ok || len(parts(d.Version)) == 0 {
+		return Assessment{Status: StatusUnknown, RiskLevel: "Low"}
+	}
+	version, compare := d.Version, Compare
+	if d.Partial {
+		compare = ComparePrefix
+	}
+
+	a := Assessment{Status: StatusCurrent, Supported: product.Supported, RiskLevel: "Low"}
+	if product.Supported != "" && compare(version, product.Supported) < 0 {
+		a.Status = StatusOutdated
+		a.RiskLevel = "Medium"
+	}
+	for _, v := range product.Vulnerabilities {
+		if compare(version, v.Introduced) >= 0 && compare(version, v.Fixed) < 0 {
+			a.Vulnerabilities = append(a.Vulnerabilities, v)
+			a.Status = StatusVulnerable
+			if severityRank[v.Severity] > severityRank[a.RiskLevel] {
+				a.RiskLevel = v.Severity
+			}
+		}
+	}
+	return a
+}
@@ -0,0 +1,231 @@
+package versions
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Detection is a CMS version read from a page or probe
+type Detection struct {
+	CMS     string
+	Version string
+	Source  string // where the version was read, e.g. "meta generator"
+	Detail  string // extra context such as a Magento static deploy date
+	// Partial is set when the source gives only the release line, as in
+	// "Magento/2.4" or "Drupal 10"
+	Partial bool
+}
+
+// Probe is a file requested from a site to read its exact CMS version
+type Probe struct {
+	CMS     string
+	Path    string
+	Pattern *regexp.Regexp // first capture group is the version
+	Partial bool           // the file names only the release line
+}
+
+// Probes are the version files requested with -probe-versions
+var Probes = []Probe{
+	{CMS: "Drupal", Path: "/CHANGELOG.txt", Pattern: regexp.MustCompile(`(?m)^Drupal (\d+\.\d+(?:\.\d+)?),`)},
+	{CMS: "Drupal", Path: "/core/CHANGELOG.txt", Pattern: regexp.MustCompile(`(?m)^Drupal (\d+\.\d+(?:\.\d+)?),`)},
+	{CMS: "Joomla", Path: "/administrator/manifests/files/joomla.xml", Pattern: regexp.MustCompile(`<version>(\d+\.\d+(?:\.\d+)?)</version>`)},
+	{CMS: "Magento", Path: "/magento_version", Pattern: regexp.MustCompile(`^Magento/(\d+\.\d+(?:\.\d+)?)`), Partial: true},
+}
+
+var (
+	generatorTag = regexp.MustCompile(`(?i)<meta[^>]+name=["']generator["'][^>]*>`)
+	contentAttr  = regexp.MustCompile(`(?i)content=["']([^"']+)["']`)
+	generators   = []struct {
+		cms     string
+		pattern *regexp.Regexp
+	}{
+		{"WordPress", regexp.MustCompile(`(?i)^WordPress (\d+\.\d+(?:\.\d+)?)`)},
+		{"Drupal", regexp.MustCompile(`(?i)^Drupal (\d+(?:\.\d+)*)`)},
+		{"Joomla", regexp.MustCompile(`(?i)^Joomla!? (\d+\.\d+(?:\.\d+)?)`)},
+		{"Ghost", regexp.MustCompile(`(?i)^Ghost (\d+\.\d+(?:\.\d+)?)`)},
+		{"TYPO3", regexp.MustCompile(`(?i)^TYPO3 (\d+\.\d+(?:\.\d+)?)`)},
+	}
+	// Core assets under wp-includes carry the WordPress version; plugin and
+	// theme assets under wp-content carry their own
+	wpCoreAsset   = regexp.MustCompile(`/wp-includes/[^"'\s?]+\?ver=(\d+\.\d+(?:\.\d+)?)\b`)
+	magentoStatic = regexp.MustCompile(`/static/version(\d{10})/`)
+)
+
+// FromContent reads CMS versions from generator meta tags, WordPress core
+// asset query strings and Magento static version paths
+func FromContent(content string) []Detection {
+	var c Collector
+	c.Add(content, len(content))
+	return c.Detections()
+}
+
+// Collector reads CMS versions from a document read in overlapping windows
+type Collector struct {
+	generators []Detection
+	wpCounts   map[string]int
+	magento    *Detection
+}
+
+// Add reads the versions of a window from the matches that start before
+// limit, so matches in the overlap of two windows are read once
+func (c *Collector) Add(content string, limit int) {
+	for _, loc := range generatorTag.FindAllStringIndex(content, -1) {
+		if loc[0] >= limit {
+			break
+		}
+		m := contentAttr.FindStringSubmatch(content[loc[0]:loc[1]])
+		if m == nil {
+			continue
+		}
+		for _, g := range generators {
+			if v := g.pattern.FindStringSubmatch(strings.TrimSpace(m[1])); v != nil {
+				// Drupal 8 and later name only the major version
+				c.addGenerator(Detection{CMS: g.cms, Version: v[1], Source: "meta generator", Partial: len(parts(v[1])) == 1})
+			}
+		}
+	}
+
+	for _, m := range wpCoreAsset.FindAllStringSubmatchIndex(content, -1) {
+		if m[0] >= limit {
+			break
+		}
+		if c.wpCounts == nil {
+			c.wpCounts = make(map[string]int)
+		}
+		c.wpCounts[content[m[2]:m[3]]]++
+	}
+
+	// Magento 2 static paths carry the deploy timestamp, not the release,
+	// so they only date the install
+	if m := magentoStatic.FindStringSubmatchIndex(content); c.magento == nil && m != nil && m[0] < limit {
+		if ts, err := strconv.ParseInt(content[m[2]:m[3]], 10, 64); err == nil {
+			c.magento = &Detection{
+				CMS:     "Magento",
+				Version: "2",
+				Source:  "static version path",
+				Detail:  "static content deployed " + time.Unix(ts, 0).UTC().Format("2006-01-02"),
+				Partial: true,
+			}
+		}
+	}
+}
+
+// addGenerator records a generator version once, however often it is
+// repeated
+func (c *Collector) addGenerator(d Detection) {
+	for _, seen := range c.generators {
+		if seen.CMS == d.CMS && seen.Version == d.Version {
+			return
+		}
+	}
+	c.generators = append(c.generators, d)
+}
+
+// Detections returns the versions read from the windows added so far
+func (c *Collector) Detections() []Detection {
+	var found []Detection
+	seen := make(map[string]bool)
+	add := func(d Detection) {
+		if key := d.CMS + ":" + d.Version; !seen[key] {
+			seen[key] = true
+			found = append(found, d)
+		}
+	}
+	for _, d := range c.generators {
+		add(d)
+	}
+	// Use the most common core asset version, as a stray asset may be
+	// pinned to an older release
+	if v := mostCommon(c.wpCounts); v != "" {
+		add(Detection{CMS: "WordPress", Version: v, Source: "wp-includes ver="})
+	}
+	if c.magento != nil {
+		add(*c.magento)
+	}
+	return found
+}
+
+// FromProbe reads a version from a probe response body
+func FromProbe(p Probe, body string) (Detection, bool) {
+	m := p.Pattern.FindStringSubmatch(body)
+	if m == nil {
+		return Detection{}, false
+	}
+	return Detection{CMS: p.CMS, Version: m[1], Source: p.Path, Partial: p.Partial}, true
+}
+
+func mostCommon(counts map[string]int) string {
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	best := ""
+	for _, k := range keys {
+		if best == "" || counts[k] > counts[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// Compare compares two dotted versions, counting missing components as 0,
+// so "2.4.3" is older than "2.4.3-p2". Patch suffixes such as "-p1" count
+// as a further component.
+func Compare(a, b string) int {
+	pa, pb := parts(a), parts(b)
+	n := len(pa)
+	if len(pb) > n {
+		n = len(pb)
+	}
+	return compare(pa, pb, n)
+}
+
+// ComparePrefix compares a partial version over the components both
+// specify, so "2.4" is neither older nor newer than "2.4.7"
+func ComparePrefix(a, b string) int {
+	pa, pb := parts(a), parts(b)
+	n := len(pa)
+	if len(pb) < n {
+		n = len(pb)
+	}
+	return compare(pa, pb, n)
+}
+
+// compare compares the first n components of two versions, padding them
+// with 0
+func compare(pa, pb []int, n int) int {
+	for i := 0; i < n; i++ {
+		var a, b int
+		if i < len(pa) {
+			a = pa[i]
+		}
+		if i < len(pb) {
+			b = pb[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func parts(version string) []int {
+	var result []int
+	for _, field := range strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '-' || r == 'p'
+	}) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		result = append(result, n)
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package versions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"6.4.2", "6.4.3", -1},
+		{"6.10", "6.9.1", 1},
+		{"7.58", "7.58", 0},
+		{"2.4", "2.4.7", -1},
+		{"2.4.3-p1", "2.4.3-p2", -1},
+		{"2.4.3", "2.4.3-p2", -1},
+		{"2.4.3-p2", "2.4.3", 1},
+		{"6.4", "6.4.0", 0},
+		{"10", "10.2", -1},
+		{"7", "10.2", -1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	prefix := []struct {
+		a, b string
+		want int
+	}{
+		{"2.4", "2.4.7", 0},
+		{"2.4", "2.3.7", 1},
+		{"10", "10.2", 0},
+		{"7", "10.2", -1},
+	}
+	for _, tt := range prefix {
+		if got := ComparePrefix(tt.a, tt.b); got != tt.want {
+			t.Errorf("ComparePrefix(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFromContent(t *testing.T) {
+	content := `<meta name="generator" content="WordPress 6.4.2" />
+<script src="/wp-includes/js/jquery/jquery.min.js?ver=6.4.2"></script>
+<script src="/wp-includes/js/wp-emoji.min.js?ver=6.4.2"></script>
+<script src="/wp-includes/js/old.js?ver=5.0"></script>
+<link rel="stylesheet" href="/wp-content/plugins/forms/style.css?ver=2.1.0">
+<script src="/static/version1696245000/frontend/Magento/luma/en_US/requirejs/require.js"></script>`
+
+	found := FromContent(content)
+	want := []Detection{
+		{CMS: "WordPress", Version: "6.4.2", Source: "meta generator"},
+		{CMS: "Magento", Version: "2", Source: "static version path", Detail: "static content deployed 2023-10-02", Partial: true},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("Expected %d detections, got %+v", len(want), found)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("Detection %d: got %+v, want %+v", i, found[i], want[i])
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	lines := []string{
+		"<html><head>\n",
+		`<script src="/wp-includes/js/old.js?ver=5.0"></script>` + "\n",
+		`<script src="/wp-includes/js/a.js?ver=6.4.2"></script>` + "\n",
+		`<script src="/wp-includes/js/b.js?ver=6.4.2"></script>` + "\n",
+		`<meta name="generator" content="Drupal 10 (https://www.drupal.org)">` + "\n",
+	}
+
+	// Two windows overlapping on the second line, which the first does not own
+	var c Collector
+	c.Add(strings.Join(lines[:2], ""), len(lines[0]))
+	c.Add(strings.Join(lines[1:], ""), len(strings.Join(lines[1:], "")))
+
+	want := []Detection{
+		{CMS: "Drupal", Version: "10", Source: "meta generator", Partial: true},
+		{CMS: "WordPress", Version: "6.4.2", Source: "wp-includes ver="},
+	}
+	found := c.Detections()
+	if len(found) != len(want) {
+		t.Fatalf("Expected %d detections, got %+v", len(want), found)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("Detection %d: got %+v, want %+v", i, found[i], want[i])
+		}
+	}
+}
+
+func TestFromProbe(t *testing.T) {
+	tests := []struct {
+		probe   int
+		body    string
+		version string
+	}{
+		{0, "\nDrupal 7.98, 2023-06-07\n-----------------------\n", "7.98"},
+		{2, `<extension type="file"><name>files_joomla</name><version>4.2.7</version></extension>`, "4.2.7"},
+		{3, "Magento/2.4 (Community)", "2.4"},
+		{3, "<html>Not found</html>", ""},
+	}
+	for _, tt := range tests {
+		d, ok := FromProbe(Probes[tt.probe], tt.body)
+		if ok != (tt.version != "") || d.Version != tt.version {
+			t.Errorf("%s: got %q, %v; want %q", Probes[tt.probe].Path, d.Version, ok, tt.version)
+		}
+	}
+}
+
+func TestAssess(t *testing.T) {
+	db := Default()
+	tests := []struct {
+		cms, version string
+		partial      bool
+		status       string
+		risk         string
+	}{
+		{"Drupal", "7.56", false, StatusVulnerable, "Critical"},
+		{"Drupal", "7.98", false, StatusOutdated, "Medium"},
+		{"Joomla", "4.2.7", false, StatusVulnerable, "High"},
+		{"Joomla", "4.4.9", false, StatusCurrent, "Low"},
+		{"WordPress", "6.6.2", false, StatusCurrent, "Low"},
+		{"Ghost", "5.0", false, StatusUnknown, "Low"},
+		{"Magento", "2.4.3", false, StatusVulnerable, "Critical"},
+		{"Magento", "2.4.3-p2", false, StatusOutdated, "Medium"},
+		{"Magento", "2.4", true, StatusCurrent, "Low"},
+		{"Magento", "2", true, StatusCurrent, "Low"},
+	}
+	for _, tt := range tests {
+		a := db.Assess(Detection{CMS: tt.cms, Version: tt.version, Partial: tt.partial})
+		if a.Status != tt.status || a.RiskLevel != tt.risk {
+			t.Errorf("Assess(%s %s) = %s/%s, want %s/%s", tt.cms, tt.version, a.Status, a.RiskLevel, tt.status, tt.risk)
+		}
+	}
+}